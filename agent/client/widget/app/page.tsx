@@ -1,15 +1,43 @@
 "use client";
 
 import { useEffect, useState } from "react";
-import { DeviceStatus, fetchStatus } from "@/lib/api";
+import { DeviceStatus, Prediction, ScoreFactor, fetchStatus } from "@/lib/api";
 
 type UiState = {
   ssid: string;
   signalPercent: number;
   avgPingMs: number;
   score: number;
+  weakest: string;
+  captive: boolean;
+  portalUrl: string;
+  predicted: string;
+  stayPut: string;
 };
 
+// weakestFactor names what costs the score most, e.g. "latency (58)".
+function weakestFactor(factors: ScoreFactor[] | null | undefined) {
+  let worst: ScoreFactor | null = null;
+  for (const f of factors ?? []) {
+    if (f.score >= 80) continue;
+    if (!worst || f.weight * (100 - f.score) > worst.weight * (100 - worst.score)) {
+      worst = f;
+    }
+  }
+  return worst ? `${worst.name} (${Math.round(worst.score)})` : "";
+}
+
+// predictionText warns what is about to go, e.g. "Signal dropping below
+// 60% in about 25 s".
+function predictionText(p: Prediction | undefined) {
+  if (!p) return "";
+  const secs = Math.max(1, Math.round(p.seconds_to_threshold));
+  if (p.metric === "signal") {
+    return `Signal dropping below ${p.threshold}% in about ${secs} s; moving closer to the access point may help`;
+  }
+  return `Ping rising above ${p.threshold} ms in about ${secs} s`;
+}
+
 function scoreLabel(score: number) {
   if (score >= 80) return "Excellent";
   if (score >= 60) return "Good";
@@ -23,6 +51,11 @@ export default function DashboardWidget() {
     signalPercent: 0,
     avgPingMs: 0,
     score: 0,
+    weakest: "",
+    captive: false,
+    portalUrl: "",
+    predicted: "",
+    stayPut: "",
   });
   const [autoSwitch, setAutoSwitch] = useState(true);
   const [error, setError] = useState<string | null>(null);
@@ -49,6 +82,11 @@ export default function DashboardWidget() {
             signalPercent: d.signal_percent ?? 0,
             avgPingMs: d.avg_ping_ms ?? 0,
             score: d.score ?? 0,
+            weakest: weakestFactor(d.score_breakdown?.factors),
+            captive: d.connectivity === "captive_portal",
+            portalUrl: d.portal_url ?? "",
+            predicted: predictionText(d.prediction),
+            stayPut: d.governor?.stay_put ? d.governor.reason ?? "" : "",
           });
         }
       } catch (e: any) {
@@ -138,6 +176,12 @@ export default function DashboardWidget() {
                   {scoreLabel(data.score)}
                 </span>
               </div>
+              {data.weakest && (
+                <div className="mt-1 text-[11px] text-slate-400">
+                  Held back by{" "}
+                  <span className="text-slate-200">{data.weakest}</span>
+                </div>
+              )}
             </div>
 
             <div className="mt-3 flex items-center justify-between rounded-xl bg-slate-800/70 px-3 py-2">
@@ -166,6 +210,34 @@ export default function DashboardWidget() {
           </div>
         </div>
 
+        {data.captive && (
+          <div className="mt-3 rounded-md border border-sky-500/40 bg-sky-900/30 px-2 py-1.5 text-[11px] text-sky-100">
+            {data.ssid} needs a browser login before it reaches the internet.{" "}
+            {data.portalUrl && (
+              <a
+                href={data.portalUrl}
+                target="_blank"
+                rel="noreferrer"
+                className="font-medium underline"
+              >
+                Open login page
+              </a>
+            )}
+          </div>
+        )}
+
+        {data.predicted && (
+          <div className="mt-3 rounded-md border border-orange-500/40 bg-orange-900/30 px-2 py-1.5 text-[11px] text-orange-100">
+            {data.predicted}.
+          </div>
+        )}
+
+        {data.stayPut && (
+          <div className="mt-3 rounded-md border border-slate-600/60 bg-slate-800/60 px-2 py-1.5 text-[11px] text-slate-300">
+            Auto-switch paused: {data.stayPut}.
+          </div>
+        )}
+
         {error && (
           <div className="mt-3 rounded-md border border-yellow-500/40 bg-yellow-900/30 px-2 py-1.5 text-[11px] text-yellow-100">
             {error}
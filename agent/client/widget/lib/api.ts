@@ -1,3 +1,34 @@
+export type ScoreFactor = {
+  name: string; // "signal" | "latency" | "jitter" | "loss" | "dns" | "download" | "upload"
+  value: number;
+  score: number; // 0-100 on this factor alone
+  weight: number; // share of the total score
+};
+
+// Prediction is set while the signal or RTT trend will cross its
+// threshold soon (a degradation_predicted event).
+export type Prediction = {
+  event: string;
+  metric: string; // "signal" | "latency"
+  current: number;
+  threshold: number;
+  slope_per_min: number;
+  seconds_to_threshold: number;
+  r2: number;
+  since: string;
+};
+
+// Governor is what the failover limits allow right now.
+export type Governor = {
+  switches_last_hour: number;
+  max_switches_per_hour: number;
+  consecutive_failures: number;
+  cooldown_until?: string;
+  stay_put: boolean;
+  reason?: string;
+  open_breakers?: { ssid: string; failures: number; open_until: string }[];
+};
+
 export type DeviceStatus = {
   device_id: string;
   user_id: string;
@@ -8,6 +39,15 @@ export type DeviceStatus = {
   signal_percent: number;
   avg_ping_ms: number;
   score: number;
+  score_breakdown?: {
+    profile: string;
+    score: number;
+    factors: ScoreFactor[] | null;
+  };
+  connectivity?: string; // "online" | "captive_portal" | "offline"
+  prediction?: Prediction;
+  governor?: Governor;
+  portal_url?: string;
 };
 
 export async function fetchStatus(): Promise<DeviceStatus> {
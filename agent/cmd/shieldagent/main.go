@@ -3,44 +3,273 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"log"
+	"net"
 	"net/http"
+	"netshield/agent/internal/capture"
 	agentclient "netshield/agent/internal/client"
+	"netshield/agent/internal/history"
 	"netshield/agent/internal/monitor"
+	"netshield/agent/internal/probe"
+	"netshield/agent/internal/speedtest"
 	"netshield/agent/internal/wifi"
 	agentpb "netshield/agent/proto"
 
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// newWifiManager picks the Wi-Fi backend for the current OS.
+// On Linux, NetworkManager is preferred; headless images without it
+// fall back to the wpa_supplicant control socket.
+func newWifiManager(runner wifi.CommandRunner) wifi.Manager {
+	switch runtime.GOOS {
+	case "linux":
+		if ctrl := os.Getenv("NETSHIELD_WPA_CTRL"); ctrl != "" {
+			return wifi.NewWpaManager(ctrl)
+		}
+		if _, err := exec.LookPath("nmcli"); err == nil {
+			return wifi.LinuxManager{Runner: runner}
+		}
+		if ctrl, err := wifi.FindWpaCtrl(wifi.DefaultWpaCtrlDir); err == nil {
+			log.Println("[agent] nmcli not found, using wpa_supplicant at", ctrl)
+			return wifi.NewWpaManager(ctrl)
+		}
+		return wifi.LinuxManager{Runner: runner}
+	default:
+		return wifi.WindowsManager{Runner: runner}
+	}
+}
+
+// parseEndpoints reads NETSHIELD_ENDPOINTS: comma-separated URLs, each
+// optionally followed by a space and the expected status, e.g.
+// "https://exam.example.edu/health 200, https://telehealth.example.com".
+// Endpoints configured this way are critical.
+func parseEndpoints(s string) []probe.Endpoint {
+	var endpoints []probe.Endpoint
+	for _, entry := range strings.Split(s, ",") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		e := probe.Endpoint{URL: fields[0], Critical: true}
+		if len(fields) > 1 {
+			if status, err := strconv.Atoi(fields[1]); err == nil {
+				e.ExpectStatus = status
+			} else {
+				log.Printf("[agent] NETSHIELD_ENDPOINTS: bad status %q for %s", fields[1], e.URL)
+			}
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints
+}
+
+// parseExamWindows reads NETSHIELD_EXAM_WINDOWS: comma-separated
+// "start/end" pairs in RFC 3339, e.g.
+// "2026-06-02T09:00:00+02:00/2026-06-02T11:00:00+02:00".
+func parseExamWindows(s string) []monitor.Window {
+	var windows []monitor.Window
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		startStr, endStr, _ := strings.Cut(entry, "/")
+		start, err1 := time.Parse(time.RFC3339, startStr)
+		end, err2 := time.Parse(time.RFC3339, endStr)
+		if err1 != nil || err2 != nil || !end.After(start) {
+			log.Printf("[agent] NETSHIELD_EXAM_WINDOWS: bad window %q", entry)
+			continue
+		}
+		windows = append(windows, monitor.Window{Start: start, End: end})
+	}
+	return windows
+}
+
+// historyFile is where network history is kept: NETSHIELD_HISTORY_FILE,
+// else history.json under the user's config directory. Empty keeps it in
+// memory only.
+func historyFile() string {
+	if path := os.Getenv("NETSHIELD_HISTORY_FILE"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Println("[agent] no config directory, network history will not be kept:", err)
+		return ""
+	}
+	return filepath.Join(dir, "netshield", "history.json")
+}
+
+// prober picks the probers for a live run. A recorded run pings through
+// runner only, so --replay sees every probe the capture was taken with.
+func prober(runner wifi.CommandRunner, recording bool) probe.Prober {
+	if recording {
+		return probe.Recorded(runner)
+	}
+	return probe.Default(runner)
+}
+
 func main() {
-	wm := wifi.WindowsManager{}
+	simulate := flag.String("simulate", "", "run against a scripted scenario file instead of the real Wi-Fi adapter")
+	record := flag.String("record", "", "append every netsh/nmcli/ping command and its output to this session file")
+	replay := flag.String("replay", "", "replay a session file recorded with --record instead of touching the adapter")
+	flag.Parse()
+
+	var runner wifi.CommandRunner = wifi.ExecRunner{}
+	if *record != "" {
+		rec, err := capture.NewRecorder(*record, runner)
+		if err != nil {
+			log.Fatalf("[agent] open capture: %v", err)
+		}
+		defer rec.Close()
+		runner = rec
+		log.Println("[agent] recording commands to", *record)
+	}
+
+	wm := newWifiManager(runner)
 
 	cfg := monitor.Config{
-		MinSignalPercent: 60,
-		MaxAvgPingMs:     120,
-		PingHost:         "8.8.8.8",
-		CheckInterval:    10 * time.Second,
+		MinSignalPercent:    60,
+		MaxAvgPingMs:        120,
+		PingHost:            "8.8.8.8",
+		ProbeCount:          5,
+		CheckInterval:       10 * time.Second,
+		SpeedtestInterval:   15 * time.Minute,
+		BufferbloatInterval: time.Hour,
+		DNSNames:            []string{"www.google.com"},
+		DNSServers:          []string{"", "1.1.1.1", "tcp://8.8.8.8"},
 		PreferredProfiles: []string{
 			"esperance",
 			"KIIT-WIFI-DU",
 			"vivo",
 		},
+		Interface:   os.Getenv("NETSHIELD_WIFI_INTERFACE"),
+		Endpoints:   parseEndpoints(os.Getenv("NETSHIELD_ENDPOINTS")),
+		Domain:      os.Getenv("NETSHIELD_DOMAIN"),
+		Codec:       os.Getenv("NETSHIELD_CODEC"),
+		ExamWindows: parseExamWindows(os.Getenv("NETSHIELD_EXAM_WINDOWS")),
+		DeviceID:    os.Getenv("NETSHIELD_DEVICE_ID"),
+		// Switching before the link is bad is opt-in; by default a
+		// predicted degradation is only reported.
+		FailoverOnPrediction: os.Getenv("NETSHIELD_PREDICTIVE_FAILOVER") == "1",
 	}
 
 	m := &monitor.Monitor{
 		Wifi:                wm,
+		Prober:              prober(runner, *record != ""),
+		Captive:             probe.CaptiveDetector{URL: os.Getenv("NETSHIELD_CAPTIVE_URL")},
+		Path:                probe.SystemDiscoverer{Runner: runner},
+		Tracer:              probe.DefaultTracer(runner),
 		Config:              cfg,
 		SwitchAutomatically: true,
 	}
 
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if *replay != "" {
+		entries, err := capture.LoadSession(*replay)
+		if err != nil {
+			log.Fatalf("[agent] load capture: %v", err)
+		}
+		rp := capture.NewReplayer(entries)
+		if m.Wifi, err = capture.NewReplayManager(rp); err != nil {
+			log.Fatalf("[agent] replay: %v", err)
+		}
+		// A recorded run probed with the system ping only; see prober.
+		m.Prober = probe.SystemProber{Runner: rp, OS: rp.OS()}
+		// Replay on a virtual clock starting at the capture, as fast as
+		// the recorded output allows, and stop once it has all been used.
+		m.Clock = monitor.NewVirtualClock(rp.Start())
+		m.Config.DNSNames = nil // lookups and requests were not recorded
+		m.Config.Endpoints = nil
+		m.Captive = nil
+		m.Path = nil // the routing table would be this machine's, not the capture's
+		m.Tracer = nil
+		go func() {
+			for !rp.Exhausted() {
+				time.Sleep(50 * time.Millisecond)
+			}
+			log.Println("[agent] capture fully replayed")
+			cancel()
+		}()
+		log.Printf("[agent] replaying %d recorded commands from %s", len(entries), *replay)
+	}
+
+	if *simulate != "" {
+		scn, err := wifi.LoadScenario(*simulate)
+		if err != nil {
+			log.Fatalf("[agent] load scenario: %v", err)
+		}
+		sim := wifi.NewSimulatedManager(scn, time.Now)
+		m.Wifi = sim
+		m.Prober = probe.Simulated{RTT: sim.PingRTT}
+		m.Captive = probe.SimulatedCaptive{Captive: sim.CaptivePortal}
+		m.Path = nil
+		m.Tracer = nil
+		m.Config.DNSNames = nil
+		m.Config.Endpoints = nil
+		m.Config.PreferredProfiles = nil
+		for _, n := range scn.Networks {
+			m.Config.PreferredProfiles = append(m.Config.PreferredProfiles, n.SSID)
+		}
+		if scn.CheckInterval > 0 {
+			m.Config.CheckInterval = scn.CheckInterval
+		}
+		log.Println("[agent] simulating scenario:", *simulate)
+	}
+
 	serverAddr := os.Getenv("NETSHIELD_SERVER_ADDR")
 	if serverAddr == "" {
 		serverAddr = "localhost:50051"
 	}
 
+	// The throughput test talks to the server's HTTP port; it is not run
+	// against recorded or simulated links.
+	if *replay == "" && *simulate == "" {
+		speedURL := os.Getenv("NETSHIELD_SPEEDTEST_URL")
+		if speedURL == "" {
+			if host, _, err := net.SplitHostPort(serverAddr); err == nil {
+				speedURL = "http://" + net.JoinHostPort(host, "8082")
+			}
+		}
+		m.Speedtest = &speedtest.Tester{BaseURL: speedURL}
+		bloatURL := os.Getenv("NETSHIELD_BUFFERBLOAT_URL")
+		if bloatURL == "" {
+			bloatURL = speedURL
+		}
+		m.Bufferbloat = &speedtest.Tester{BaseURL: bloatURL}
+	}
+
+	// Failover ranks networks on how they behaved before. A simulation
+	// learns in memory only, and a replay would learn another machine's
+	// networks, so it does without.
+	if *replay == "" {
+		path := ""
+		if *simulate == "" {
+			path = historyFile()
+		}
+		h, err := history.Open(path)
+		if err != nil {
+			log.Println("[agent] network history unreadable, starting afresh:", err)
+			h, _ = history.Open("")
+			h.Path = path
+		}
+		if m.Clock != nil {
+			h.Now = m.Clock.Now
+		}
+		m.History = h
+	}
+
 	var client *agentclient.Client
 
 	c, err := agentclient.New(serverAddr)
@@ -56,9 +285,14 @@ func main() {
 
 	m.OnMetric = func(metric *agentpb.NetworkMetric) {
 		log.Printf(
-			"[agent] metric: signal=%d ping=%d",
+			"[agent] metric: signal=%d ping=%d jitter=%d loss=%.0f%% mos=%.2f connectivity=%s fault=%s",
 			metric.SignalPercent,
 			metric.AvgPingMs,
+			metric.JitterMs,
+			metric.PacketLossPct,
+			metric.Mos,
+			metric.Connectivity,
+			metric.FaultLocation,
 		)
 		if client == nil {
 			return
@@ -69,12 +303,27 @@ func main() {
 	}
 
 	go startLocalAPI(m)
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer cancel()
 
 	if err := m.Start(ctx); err != nil && err != context.Canceled {
 		log.Println("[agent] monitor stopped with error:", err)
 	}
+	if m.History != nil {
+		if err := m.History.Save(); err != nil {
+			log.Println("[agent] save network history:", err)
+		}
+	}
+}
+
+// requirePost answers 405 to anything but POST. The link tests load the
+// network for seconds, so they take no CORS headers and cannot be set off
+// by a page's GET.
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
 }
 
 func startLocalAPI(m *monitor.Monitor) {
@@ -91,10 +340,65 @@ func startLocalAPI(m *monitor.Monitor) {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		switchMode := m.SwitchAutomatically
-		m.SwitchAutomatically = !switchMode
+		on := m.ToggleAutoSwitch()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]bool{"SwitchAutomatically": on})
+	})
+	mux.HandleFunc("/speedtest", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+
+		res, err := m.RunSpeedtest(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	})
+	mux.HandleFunc("/bufferbloat", func(w http.ResponseWriter, r *http.Request) {
+		if !requirePost(w, r) {
+			return
+		}
+
+		res, err := m.RunBufferbloat(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	})
+	mux.HandleFunc("/ranking", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// The last failover explains the network it chose. A POST also
+		// ranks a fresh scan, which can make the adapter rescan, so a
+		// page polling with GET never triggers one.
+		report := map[string]*monitor.RankingReport{"last_failover": m.Ranking()}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			now, err := m.RankNow(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			report["now"] = now
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]bool{"SwitchAutomatically": m.SwitchAutomatically})
+		_ = json.NewEncoder(w).Encode(report)
 	})
 	mux.HandleFunc("/current", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
@@ -0,0 +1,201 @@
+// Package capture records the raw output of every netsh/nmcli/ping command
+// the agent runs, and replays a recorded session through the same parsers
+// and monitor so a user's report can be reproduced on any machine.
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"netshield/agent/internal/wifi"
+)
+
+// Entry is one recorded command invocation (one JSON line in the session file).
+type Entry struct {
+	Time   time.Time `json:"ts"`
+	Name   string    `json:"cmd"`
+	Args   []string  `json:"args"`
+	Output string    `json:"output"`
+	Error  string    `json:"error,omitempty"`
+}
+
+func (e Entry) key() string {
+	return e.Name + "\x00" + strings.Join(e.Args, "\x00")
+}
+
+// Recorder wraps a CommandRunner and appends every call to a session file.
+type Recorder struct {
+	Runner wifi.CommandRunner
+
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// NewRecorder creates (or appends to) the session file at path.
+func NewRecorder(path string, runner wifi.CommandRunner) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if runner == nil {
+		runner = wifi.ExecRunner{}
+	}
+	return &Recorder{Runner: runner, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (r *Recorder) Run(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := r.Runner.Run(ctx, name, args...)
+
+	e := Entry{Time: time.Now(), Name: name, Args: args, Output: out}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if werr := r.enc.Encode(e); werr != nil {
+		log.Println("[capture] write failed:", werr)
+	}
+	return out, err
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// LoadSession reads a session file written by Recorder.
+func LoadSession(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// Replayer is a CommandRunner that answers from a recorded session. Each
+// distinct command line replays its recordings in order; once exhausted
+// the last recording keeps being returned.
+type Replayer struct {
+	mu      sync.Mutex
+	queues  map[string][]Entry
+	last    map[string]Entry
+	pastEnd bool
+	Entries []Entry
+}
+
+func NewReplayer(entries []Entry) *Replayer {
+	r := &Replayer{
+		queues:  make(map[string][]Entry),
+		last:    make(map[string]Entry),
+		Entries: entries,
+	}
+	for _, e := range entries {
+		r.queues[e.key()] = append(r.queues[e.key()], e)
+	}
+	return r
+}
+
+// Run answers at once; a done ctx fails it as it would a real command.
+func (r *Replayer) Run(ctx context.Context, name string, args ...string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("%s %v: %w", name, args, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := Entry{Name: name, Args: args}.key()
+	e, ok := r.last[k]
+	if q := r.queues[k]; len(q) > 0 {
+		e, ok = q[0], true
+		r.queues[k] = q[1:]
+		r.last[k] = e
+	} else if ok {
+		r.pastEnd = true
+	}
+	if !ok {
+		return "", fmt.Errorf("%s %v: not in capture", name, args)
+	}
+	if e.Error != "" {
+		return e.Output, e.err()
+	}
+	return e.Output, nil
+}
+
+// err rebuilds a recorded error. A command killed at its deadline keeps
+// context.DeadlineExceeded, since callers treat a killed ping as lost.
+func (e Entry) err() error {
+	if msg, ok := strings.CutSuffix(e.Error, ": "+context.DeadlineExceeded.Error()); ok {
+		return fmt.Errorf("%s: %w", msg, context.DeadlineExceeded)
+	}
+	return errors.New(e.Error)
+}
+
+// Exhausted reports whether the replay has run past the end of the capture,
+// i.e. some command was asked for more often than it was recorded.
+func (r *Replayer) Exhausted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pastEnd
+}
+
+// Start returns the time of the first recorded command.
+func (r *Replayer) Start() time.Time {
+	if len(r.Entries) == 0 {
+		return time.Time{}
+	}
+	return r.Entries[0].Time
+}
+
+// OS reports which OS the capture was recorded on, judging by the Wi-Fi
+// tool it used, so replayed ping commands use the same syntax.
+func (r *Replayer) OS() string {
+	for _, e := range r.Entries {
+		switch e.Name {
+		case "netsh":
+			return "windows"
+		case "nmcli":
+			return "linux"
+		}
+	}
+	return ""
+}
+
+// NewReplayManager builds the wifi.Manager matching the recorded commands,
+// feeding recorded output back through its parsers.
+func NewReplayManager(r *Replayer) (wifi.Manager, error) {
+	for _, e := range r.Entries {
+		switch e.Name {
+		case "netsh":
+			return wifi.WindowsManager{Runner: r}, nil
+		case "nmcli":
+			return wifi.LinuxManager{Runner: r}, nil
+		}
+	}
+	return nil, fmt.Errorf("capture has no netsh or nmcli commands")
+}
@@ -0,0 +1,191 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"netshield/agent/internal/probe"
+)
+
+// scripted answers each command line with its next reply in order.
+type scripted map[string][]reply
+
+type reply struct {
+	out string
+	err error
+}
+
+func (s scripted) Run(ctx context.Context, name string, args ...string) (string, error) {
+	k := name + " " + strings.Join(args, " ")
+	q := s[k]
+	if len(q) == 0 {
+		return "", fmt.Errorf("unexpected %s", k)
+	}
+	s[k] = q[1:]
+	return q[0].out, q[0].err
+}
+
+func TestRecordReplay(t *testing.T) {
+	killed := fmt.Errorf("ping [-c 3 -W 2 1.1.1.1]: %w", context.DeadlineExceeded)
+	src := scripted{
+		"nmcli -t device status": {{out: "wlan0:wifi:connected:Lab\n"}, {out: "wlan0:wifi:disconnected:\n"}},
+		"ping -c 3 -W 2 1.1.1.1": {{out: "3 packets transmitted, 3 received\n"}, {out: "PING 1.1.1.1\n", err: killed}},
+		"nmcli -t connection up": {{err: errors.New("nmcli [-t connection up] failed: exit status 4 | stderr: Error: no such connection")}},
+	}
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	rec, err := NewRecorder(path, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	calls := [][]string{
+		{"nmcli", "-t", "device", "status"},
+		{"ping", "-c", "3", "-W", "2", "1.1.1.1"},
+		{"nmcli", "-t", "device", "status"},
+		{"nmcli", "-t", "connection", "up"},
+		{"ping", "-c", "3", "-W", "2", "1.1.1.1"},
+	}
+	var want []string
+	for _, c := range calls {
+		out, _ := rec.Run(ctx, c[0], c[1:]...)
+		want = append(want, out)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := LoadSession(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(calls) {
+		t.Fatalf("loaded %d entries, want %d", len(entries), len(calls))
+	}
+	r := NewReplayer(entries)
+
+	// Each command line replays its own recordings in order, whatever
+	// came in between.
+	for i, c := range calls {
+		out, _ := r.Run(ctx, c[0], c[1:]...)
+		if out != want[i] {
+			t.Errorf("call %d %v = %q, want %q", i, c, out, want[i])
+		}
+	}
+	if r.Exhausted() {
+		t.Error("Exhausted before any command ran past its recordings")
+	}
+
+	// Past the end the last recording repeats.
+	out, err := r.Run(ctx, "nmcli", "-t", "device", "status")
+	if out != "wlan0:wifi:disconnected:\n" || err != nil {
+		t.Errorf("past the end = %q, %v", out, err)
+	}
+	if !r.Exhausted() {
+		t.Error("not Exhausted after running past the capture")
+	}
+
+	// Recorded errors come back, a killed command still as a deadline.
+	out, err = r.Run(ctx, "ping", "-c", "3", "-W", "2", "1.1.1.1")
+	if out != "PING 1.1.1.1\n" || !errors.Is(err, context.DeadlineExceeded) || err.Error() != killed.Error() {
+		t.Errorf("killed ping = %q, %v", out, err)
+	}
+	if _, err := r.Run(ctx, "nmcli", "-t", "connection", "up"); err == nil || errors.Is(err, context.DeadlineExceeded) ||
+		!strings.Contains(err.Error(), "no such connection") {
+		t.Errorf("failed connect = %v", err)
+	}
+
+	if _, err := r.Run(ctx, "nmcli", "-t", "general"); err == nil {
+		t.Error("a command missing from the capture succeeded")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := r.Run(cancelled, "nmcli", "-t", "device", "status"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run with a cancelled ctx = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewReplayManager(t *testing.T) {
+	ping := Entry{Name: "ping", Args: []string{"8.8.8.8"}}
+	for _, tc := range []struct {
+		name    string
+		entries []Entry
+		os      string
+		want    string // the Manager's type; empty for none
+	}{
+		{"windows", []Entry{ping, {Name: "netsh", Args: []string{"wlan", "show", "interfaces"}}}, "windows", "wifi.WindowsManager"},
+		{"linux", []Entry{ping, {Name: "nmcli", Args: []string{"-t", "device", "status"}}}, "linux", "wifi.LinuxManager"},
+		{"ping only", []Entry{ping}, "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewReplayer(tc.entries)
+			if got := r.OS(); got != tc.os {
+				t.Errorf("OS = %q, want %q", got, tc.os)
+			}
+			m, err := NewReplayManager(r)
+			if tc.want == "" {
+				if err == nil {
+					t.Errorf("NewReplayManager = %#v with no Wi-Fi commands", m)
+				}
+				return
+			}
+			if err != nil || fmt.Sprintf("%T", m) != tc.want {
+				t.Fatalf("NewReplayManager = %#v, %v; want a %s", m, err, tc.want)
+			}
+			// Whichever it is, it runs its commands through the replay.
+			if _, err := m.ListProfiles(context.Background()); err == nil || !strings.Contains(err.Error(), "not in capture") {
+				t.Errorf("ListProfiles = %v, want it asked of the capture", err)
+			}
+		})
+	}
+}
+
+// testdata/windows-en.jsonl is a session recorded on an English Windows
+// install: two checks, the second on a weaker signal with the ping killed
+// at its deadline.
+func TestReplayWindows(t *testing.T) {
+	entries, err := LoadSession(filepath.Join("testdata", "windows-en.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReplayer(entries)
+	mgr, err := NewReplayManager(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prober := probe.SystemProber{Runner: r, OS: r.OS()}
+	target := probe.Target{Host: "8.8.8.8", Count: 4}
+
+	for i, want := range []struct {
+		signal   int
+		received int
+	}{{91, 4}, {64, 0}} {
+		st, err := mgr.GetCurrentStatus(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.SSID != "Campus-5G" || st.InterfaceName != "Wi-Fi" || st.Signal != want.signal {
+			t.Errorf("check %d: %s on %s at %d%%, want Campus-5G on Wi-Fi at %d%%", i, st.SSID, st.InterfaceName, st.Signal, want.signal)
+		}
+
+		// However much of the deadline is left, the replay asks for the
+		// command line that was recorded.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i+3)*time.Second)
+		res, err := prober.Probe(ctx, target)
+		cancel()
+		if err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+		if res.Sent != 4 || res.Received != want.received {
+			t.Errorf("check %d: ping %d/%d, want %d/4", i, res.Received, res.Sent, want.received)
+		}
+	}
+	if r.Exhausted() {
+		t.Error("replay ran past the capture")
+	}
+}
@@ -0,0 +1,328 @@
+// Package history remembers how each Wi-Fi network, and each access point
+// (BSSID) of it, has behaved: the experience scores seen on it, how often
+// it was degraded and how often connecting to it worked, split by time of
+// day. It is kept in a JSON file so it survives restarts.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Buckets is how many parts the day is split into; a campus network that
+// is fine at night may be saturated between lectures.
+const Buckets = 6
+
+const bucketHours = 24 / Buckets
+
+// Bucket returns the part of the day t falls in, in t's location.
+func Bucket(t time.Time) int { return t.Hour() / bucketHours }
+
+// BucketLabel names a bucket, e.g. "08-12".
+func BucketLabel(b int) string {
+	return fmt.Sprintf("%02d-%02d", b*bucketHours, (b+1)*bucketHours)
+}
+
+// minBucketSamples is how many checks a bucket needs before it is trusted
+// over the whole-day figures.
+const minBucketSamples = 5
+
+// Stats is what was seen on a network in one part of the day.
+type Stats struct {
+	Checks          int     `json:"checks"`
+	AvgScore        float64 `json:"avg_score"`
+	Failures        int     `json:"failures"` // checks where it was degraded
+	ConnectAttempts int     `json:"connect_attempts"`
+	Connects        int     `json:"connects"` // attempts that held up
+}
+
+func (s *Stats) add(o Stats) {
+	if n := s.Checks + o.Checks; n > 0 {
+		s.AvgScore = (s.AvgScore*float64(s.Checks) + o.AvgScore*float64(o.Checks)) / float64(n)
+	}
+	s.Checks += o.Checks
+	s.Failures += o.Failures
+	s.ConnectAttempts += o.ConnectAttempts
+	s.Connects += o.Connects
+}
+
+// Record is the history of one network, or of one BSSID when BSSID is set.
+type Record struct {
+	SSID     string         `json:"ssid"`
+	BSSID    string         `json:"bssid,omitempty"`
+	ByHour   [Buckets]Stats `json:"by_time_of_day"`
+	LastSeen time.Time      `json:"last_seen"`
+}
+
+// Expectation is what history says about a network at a time of day.
+type Expectation struct {
+	Source      string  `json:"source"` // "bssid", "network" or "" with no history
+	Bucket      string  `json:"time_of_day"`
+	Checks      int     `json:"checks"`
+	AvgScore    float64 `json:"avg_score"`
+	FailureRate float64 `json:"failure_rate"` // share of checks degraded
+	// ConnectRate is the share of connects to the network, over the whole
+	// day, that held up, counting two imagined successes so a network
+	// tried once and failed is not ruled out.
+	ConnectRate     float64 `json:"connect_rate"`
+	ConnectAttempts int     `json:"connect_attempts"`
+}
+
+// Store holds the records and writes them to Path. An empty Path keeps
+// them in memory only.
+type Store struct {
+	Path string
+	// SaveEvery throttles writes after checks; connects are written at
+	// once. Default a minute.
+	SaveEvery time.Duration
+	// Records not seen for MaxAge (default 90 days) are dropped when the
+	// history is written, and past MaxRecords (default 500) networks or
+	// access points the least recently seen go too.
+	MaxAge     time.Duration
+	MaxRecords int
+	// Now is the clock Save stamps writes with; nil uses the wall clock.
+	// Set it to the clock the checks are observed on.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	networks map[string]*Record // by SSID
+	bssids   map[string]*Record // by BSSID
+	dirty    bool
+	saved    time.Time
+}
+
+// file is the on-disk form.
+type file struct {
+	Networks []*Record `json:"networks"`
+	BSSIDs   []*Record `json:"bssids"`
+}
+
+// Open loads the history at path, starting empty if there is none yet.
+func Open(path string) (*Store, error) {
+	s := &Store{Path: path, networks: make(map[string]*Record), bssids: make(map[string]*Record)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("history %s: %w", path, err)
+	}
+	for _, r := range f.Networks {
+		s.networks[r.SSID] = r
+	}
+	for _, r := range f.BSSIDs {
+		s.bssids[r.BSSID] = r
+	}
+	return s, nil
+}
+
+// records returns the network's record and, when bssid is set, the
+// BSSID's, creating them. Caller holds s.mu.
+func (s *Store) records(ssid, bssid string) []*Record {
+	r, ok := s.networks[ssid]
+	if !ok {
+		r = &Record{SSID: ssid}
+		s.networks[ssid] = r
+	}
+	out := []*Record{r}
+	if bssid != "" {
+		b, ok := s.bssids[bssid]
+		if !ok {
+			b = &Record{SSID: ssid, BSSID: bssid}
+			s.bssids[bssid] = b
+		}
+		b.SSID = ssid
+		out = append(out, b)
+	}
+	return out
+}
+
+// Observe records one check of a network the agent is connected to. The
+// error is from writing the file, and the check is kept either way.
+func (s *Store) Observe(ssid, bssid string, score int, degraded bool, at time.Time) error {
+	if ssid == "" {
+		return nil
+	}
+	o := Stats{Checks: 1, AvgScore: float64(score)}
+	if degraded {
+		o.Failures = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.records(ssid, bssid) {
+		r.ByHour[Bucket(at)].add(o)
+		r.LastSeen = at
+	}
+	s.dirty = true
+	if at.Sub(s.saved) < s.saveEvery() {
+		return nil
+	}
+	return s.saveLocked(at)
+}
+
+// Connected records an attempt to join a network and whether it held up.
+func (s *Store) Connected(ssid, bssid string, ok bool, at time.Time) error {
+	if ssid == "" {
+		return nil
+	}
+	o := Stats{ConnectAttempts: 1}
+	if ok {
+		o.Connects = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.records(ssid, bssid) {
+		r.ByHour[Bucket(at)].add(o)
+		r.LastSeen = at
+	}
+	s.dirty = true
+	return s.saveLocked(at)
+}
+
+// Expect sums up a network's history for the time of day of at. Checks
+// come from the BSSID's own record once it has enough, else the
+// network's; within a record the bucket for at is used once it has
+// enough checks, else the whole day. Connects are made to the network,
+// not an access point, so they always come from its record.
+func (s *Store) Expect(ssid, bssid string, at time.Time) Expectation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := Expectation{Bucket: BucketLabel(Bucket(at)), ConnectRate: 1}
+	n, ok := s.networks[ssid]
+	if !ok {
+		return e
+	}
+	r := n
+	e.Source = "network"
+	if b, ok := s.bssids[bssid]; ok && bssid != "" && b.SSID == ssid && total(b).Checks >= minBucketSamples {
+		r, e.Source = b, "bssid"
+	}
+
+	st := r.ByHour[Bucket(at)]
+	if st.Checks < minBucketSamples {
+		st, e.Bucket = total(r), "all day"
+	}
+	e.Checks, e.AvgScore = st.Checks, st.AvgScore
+	if st.Checks > 0 {
+		e.FailureRate = float64(st.Failures) / float64(st.Checks)
+	}
+	c := total(n)
+	e.ConnectAttempts = c.ConnectAttempts
+	e.ConnectRate = float64(c.Connects+2) / float64(c.ConnectAttempts+2)
+	return e
+}
+
+func total(r *Record) Stats {
+	var t Stats
+	for _, b := range r.ByHour {
+		t.add(b)
+	}
+	return t
+}
+
+func (s *Store) saveEvery() time.Duration {
+	if s.SaveEvery > 0 {
+		return s.SaveEvery
+	}
+	return time.Minute
+}
+
+func (s *Store) maxAge() time.Duration {
+	if s.MaxAge > 0 {
+		return s.MaxAge
+	}
+	return 90 * 24 * time.Hour
+}
+
+func (s *Store) maxRecords() int {
+	if s.MaxRecords > 0 {
+		return s.MaxRecords
+	}
+	return 500
+}
+
+// Save writes the history if anything changed since it was last written.
+func (s *Store) Save() error {
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked(now)
+}
+
+// prune drops the records of m not seen since cutoff and, past limit,
+// the least recently seen. Caller holds the store's lock.
+func prune(m map[string]*Record, cutoff time.Time, limit int) {
+	for k, r := range m {
+		if r.LastSeen.Before(cutoff) {
+			delete(m, k)
+		}
+	}
+	if len(m) <= limit {
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return m[keys[i]].LastSeen.After(m[keys[j]].LastSeen) })
+	for _, k := range keys[limit:] {
+		delete(m, k)
+	}
+}
+
+// saveLocked writes to a temporary file and renames it over Path, so a
+// crash mid-write leaves the previous history. Caller holds s.mu.
+func (s *Store) saveLocked(now time.Time) error {
+	if s.Path == "" || !s.dirty {
+		return nil
+	}
+	cutoff := now.Add(-s.maxAge())
+	prune(s.networks, cutoff, s.maxRecords())
+	prune(s.bssids, cutoff, s.maxRecords())
+
+	f := file{Networks: make([]*Record, 0, len(s.networks)), BSSIDs: make([]*Record, 0, len(s.bssids))}
+	for _, r := range s.networks {
+		f.Networks = append(f.Networks, r)
+	}
+	for _, r := range s.bssids {
+		f.BSSIDs = append(f.BSSIDs, r)
+	}
+	sort.Slice(f.Networks, func(i, j int) bool { return f.Networks[i].SSID < f.Networks[j].SSID })
+	sort.Slice(f.BSSIDs, func(i, j int) bool { return f.BSSIDs[i].BSSID < f.BSSIDs[j].BSSID })
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return err
+	}
+	s.dirty, s.saved = false, now
+	return nil
+}
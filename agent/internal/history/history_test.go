@@ -0,0 +1,43 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSavePrunes(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "history.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MaxAge, s.MaxRecords = 30*24*time.Hour, 3
+	s.Now = func() time.Time { return now }
+
+	s.Observe("Old", "aa:00", 80, false, now.Add(-31*24*time.Hour))
+	for i := range 4 {
+		s.Observe(fmt.Sprintf("Net%d", i), "", 70, false, now.Add(-time.Duration(i)*time.Hour))
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ssid := range []string{"Net0", "Net1", "Net2"} {
+		if _, ok := got.networks[ssid]; !ok {
+			t.Errorf("%s dropped", ssid)
+		}
+	}
+	if len(got.networks) != 3 {
+		t.Errorf("kept %d networks, want the 3 most recently seen", len(got.networks))
+	}
+	if len(got.bssids) != 0 {
+		t.Errorf("kept %d access points, want the stale one dropped", len(got.bssids))
+	}
+}
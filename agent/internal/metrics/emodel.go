@@ -0,0 +1,75 @@
+package metrics
+
+import "math"
+
+// Codec holds the E-model parameters of a voice codec, from ITU-T G.113
+// Appendix I.
+type Codec struct {
+	Name    string  `json:"name"`
+	Ie      float64 `json:"ie"`       // equipment impairment factor
+	Bpl     float64 `json:"bpl"`      // packet-loss robustness factor
+	DelayMs float64 `json:"delay_ms"` // frame, look-ahead and packetization delay
+}
+
+// Codecs are the profiles Config.Codec may name. G.711 assumes packet loss
+// concealment and 20 ms packets.
+var Codecs = map[string]Codec{
+	"g711":  {Name: "g711", Ie: 0, Bpl: 25.1, DelayMs: 20},
+	"g729":  {Name: "g729", Ie: 11, Bpl: 19.0, DelayMs: 25},
+	"g7231": {Name: "g7231", Ie: 15, Bpl: 16.1, DelayMs: 37.5},
+}
+
+// DefaultCodec is used when no profile or an unknown one is configured.
+const DefaultCodec = "g711"
+
+// LookupCodec returns the named profile, or the default.
+func LookupCodec(name string) Codec {
+	if c, ok := Codecs[name]; ok {
+		return c
+	}
+	return Codecs[DefaultCodec]
+}
+
+// CallQuality is the estimated quality of a voice call over the link.
+type CallQuality struct {
+	Codec   string  `json:"codec"`
+	RFactor float64 `json:"r_factor"` // 0-100; 70 and up satisfies most users
+	MOS     float64 `json:"mos"`      // 1-4.5
+}
+
+// EModel estimates call quality with the ITU-T G.107 E-model:
+//
+//	R = Ro - Is - Id - Ie,eff + A
+//
+// Ro - Is is 93.2 with the G.107 default values and A is 0. Id only keeps
+// the delay term Idd, since echo is cancelled on the kinds of calls this
+// estimates. The one-way delay Ta is half the round trip plus the codec
+// delay plus a jitter buffer of twice the jitter. Loss is taken as random
+// (BurstR = 1).
+func EModel(c Codec, rttMs, jitterMs, lossPct float64) CallQuality {
+	const roMinusIs = 93.2
+
+	ta := rttMs/2 + c.DelayMs + 2*jitterMs
+	var idd float64
+	if ta > 100 {
+		x := math.Log2(ta / 100)
+		idd = 25 * (math.Pow(1+math.Pow(x, 6), 1.0/6) - 3*math.Pow(1+math.Pow(x/3, 6), 1.0/6) + 2)
+	}
+
+	ppl := math.Max(0, math.Min(lossPct, 100))
+	ieEff := c.Ie + (95-c.Ie)*ppl/(ppl+c.Bpl)
+
+	r := math.Max(0, math.Min(roMinusIs-idd-ieEff, 100))
+	return CallQuality{Codec: c.Name, RFactor: r, MOS: MOSFromR(r)}
+}
+
+// MOSFromR converts an R-factor to a mean opinion score (G.107 Annex B).
+func MOSFromR(r float64) float64 {
+	switch {
+	case r <= 0:
+		return 1
+	case r >= 100:
+		return 4.5
+	}
+	return 1 + 0.035*r + r*(r-60)*(100-r)*7e-6
+}
@@ -1,22 +1,174 @@
 package metrics
 
-// Score computes a simple 0-100 score from signal and avg ping.
-func Score(signalPercent, avgPingMs int) int {
-	score := 100
+import "math"
 
-	if signalPercent < 80 {
-		score -= (80 - signalPercent) / 2
+// Inputs are what a link is scored on. A factor whose input was not
+// measured drops out of the score rather than counting as perfect.
+type Inputs struct {
+	Signal int // percent; 0 means not associated and scores 0
+	// Probed is set when a probe burst ran. With every packet lost, RTT
+	// and jitter score 0.
+	Probed   bool
+	RTTMs    float64
+	JitterMs float64
+	LossPct  float64
+	// DNSQueries is 0 when name resolution was not checked.
+	DNSQueries  int
+	DNSFailures int
+	DNSMs       float64 // average over the lookups that answered
+	// Throughput from the latest speedtest on this link; 0 when none ran.
+	DownMbps float64
+	UpMbps   float64
+}
+
+// Factor names, also the inputs a FactorSpec scores.
+const (
+	FactorSignal   = "signal"
+	FactorLatency  = "latency"
+	FactorJitter   = "jitter"
+	FactorLoss     = "loss"
+	FactorDNS      = "dns"
+	FactorDownload = "download"
+	FactorUpload   = "upload"
+)
+
+// value returns the input a factor scores, and whether it was measured.
+func (in Inputs) value(name string) (float64, bool) {
+	switch name {
+	case FactorSignal:
+		return float64(in.Signal), true
+	case FactorLatency:
+		return in.RTTMs, in.Probed
+	case FactorJitter:
+		return in.JitterMs, in.Probed
+	case FactorLoss:
+		return in.LossPct, in.Probed
+	case FactorDNS:
+		return in.DNSMs, in.DNSQueries > 0
+	case FactorDownload:
+		return in.DownMbps, in.DownMbps > 0
+	case FactorUpload:
+		return in.UpMbps, in.UpMbps > 0
+	}
+	return 0, false
+}
+
+// FactorSpec scores one input on a straight line from Bad (0) to Good
+// (100), clamped at both ends. Good may be above or below Bad.
+type FactorSpec struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	Good   float64 `json:"good"`
+	Bad    float64 `json:"bad"`
+}
+
+func (f FactorSpec) score(v float64) float64 {
+	if f.Good == f.Bad {
+		return 100
 	}
+	return math.Max(0, math.Min((v-f.Bad)/(f.Good-f.Bad)*100, 100))
+}
+
+// Factor is one input's part in a score.
+type Factor struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`  // the measured input
+	Score  float64 `json:"score"`  // 0-100 on this input alone
+	Weight float64 `json:"weight"` // share of the total among measured factors
+}
+
+// Breakdown is a score with the factors that made it.
+type Breakdown struct {
+	Profile string   `json:"profile"`
+	Score   int      `json:"score"` // 0-100
+	Factors []Factor `json:"factors"`
+}
+
+// Scorer rates a link 0-100.
+type Scorer interface {
+	Score(in Inputs) Breakdown
+}
 
-	if avgPingMs > 40 {
-		score -= (avgPingMs - 40) / 3
+// Weighted is the weighted mean of its factors' scores. The DNS factor is
+// further scaled by the share of lookups that answered.
+type Weighted struct {
+	Name    string       `json:"name"`
+	Factors []FactorSpec `json:"factors"`
+}
+
+func (w Weighted) Score(in Inputs) Breakdown {
+	b := Breakdown{Profile: w.Name}
+	if in.Signal <= 0 {
+		return b
 	}
 
-	if score < 0 {
-		score = 0
+	var total, sum float64
+	for _, f := range w.Factors {
+		v, ok := in.value(f.Name)
+		if !ok || f.Weight <= 0 {
+			continue
+		}
+		s := f.score(v)
+		switch {
+		case f.Name == FactorDNS:
+			s *= float64(in.DNSQueries-in.DNSFailures) / float64(in.DNSQueries)
+		case (f.Name == FactorLatency || f.Name == FactorJitter) && in.LossPct >= 100:
+			s = 0
+		}
+		b.Factors = append(b.Factors, Factor{Name: f.Name, Value: v, Score: math.Round(s), Weight: f.Weight})
+		total += f.Weight
+		sum += f.Weight * s
+	}
+	if total == 0 {
+		return b
 	}
-	if score > 100 {
-		score = 100
+	for i := range b.Factors {
+		b.Factors[i].Weight = math.Round(b.Factors[i].Weight/total*1000) / 1000
+	}
+	b.Score = int(math.Round(sum / total))
+	return b
+}
+
+// Profiles are the scoring profiles Config.Domain may name. Exams run in a
+// browser and care most about staying connected; telemedicine is two-way
+// video, so jitter, loss and upload weigh most; remote work is calls and
+// file transfers in between.
+var Profiles = map[string]Weighted{
+	"exam": {Name: "exam", Factors: []FactorSpec{
+		{Name: FactorSignal, Weight: 0.30, Good: 80, Bad: 20},
+		{Name: FactorLatency, Weight: 0.20, Good: 40, Bad: 300},
+		{Name: FactorJitter, Weight: 0.05, Good: 10, Bad: 100},
+		{Name: FactorLoss, Weight: 0.20, Good: 0, Bad: 10},
+		{Name: FactorDNS, Weight: 0.15, Good: 50, Bad: 1000},
+		{Name: FactorDownload, Weight: 0.10, Good: 10, Bad: 0.5},
+	}},
+	"telemedicine": {Name: "telemedicine", Factors: []FactorSpec{
+		{Name: FactorSignal, Weight: 0.15, Good: 80, Bad: 20},
+		{Name: FactorLatency, Weight: 0.15, Good: 50, Bad: 300},
+		{Name: FactorJitter, Weight: 0.20, Good: 5, Bad: 50},
+		{Name: FactorLoss, Weight: 0.25, Good: 0, Bad: 5},
+		{Name: FactorDNS, Weight: 0.05, Good: 50, Bad: 1000},
+		{Name: FactorDownload, Weight: 0.05, Good: 5, Bad: 1},
+		{Name: FactorUpload, Weight: 0.15, Good: 5, Bad: 1},
+	}},
+	"remote-work": {Name: "remote-work", Factors: []FactorSpec{
+		{Name: FactorSignal, Weight: 0.20, Good: 80, Bad: 20},
+		{Name: FactorLatency, Weight: 0.15, Good: 50, Bad: 300},
+		{Name: FactorJitter, Weight: 0.15, Good: 10, Bad: 60},
+		{Name: FactorLoss, Weight: 0.20, Good: 0, Bad: 8},
+		{Name: FactorDNS, Weight: 0.10, Good: 50, Bad: 1000},
+		{Name: FactorDownload, Weight: 0.10, Good: 20, Bad: 1},
+		{Name: FactorUpload, Weight: 0.10, Good: 10, Bad: 0.5},
+	}},
+}
+
+// DefaultProfile is used when no domain or an unknown one is configured.
+const DefaultProfile = "exam"
+
+// LookupProfile returns the profile for a domain, or the default.
+func LookupProfile(domain string) Weighted {
+	if p, ok := Profiles[domain]; ok {
+		return p
 	}
-	return score
+	return Profiles[DefaultProfile]
 }
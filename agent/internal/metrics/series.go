@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+)
+
+// Series smooths one measurement: an EWMA over every sample and
+// percentiles over the last Size.
+type Series struct {
+	Alpha float64 // weight of a new sample in the EWMA; default 0.3
+	Size  int     // samples kept for percentiles; default 12
+
+	ewma float64
+	buf  []float64 // ring of the last Size samples
+	next int
+	n    int
+}
+
+func (s *Series) Add(v float64) {
+	alpha, size := s.Alpha, s.Size
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.3
+	}
+	if size <= 0 {
+		size = 12
+	}
+	if s.n == 0 {
+		s.ewma = v
+	} else {
+		s.ewma += alpha * (v - s.ewma)
+	}
+	s.n++
+
+	if len(s.buf) < size {
+		s.buf = append(s.buf, v)
+		return
+	}
+	s.buf[s.next] = v
+	s.next = (s.next + 1) % len(s.buf)
+}
+
+// Len is the number of samples added so far.
+func (s *Series) Len() int { return s.n }
+
+// EWMA is the smoothed value, 0 before the first sample.
+func (s *Series) EWMA() float64 { return s.ewma }
+
+// Percentile returns the p-th percentile (0-100) of the kept samples by
+// linear interpolation, 0 before the first sample.
+func (s *Series) Percentile(p float64) float64 {
+	if len(s.buf) == 0 {
+		return 0
+	}
+	v := append([]float64(nil), s.buf...)
+	sort.Float64s(v)
+	rank := math.Max(0, math.Min(p, 100)) / 100 * float64(len(v)-1)
+	lo := int(rank)
+	if lo == len(v)-1 {
+		return v[lo]
+	}
+	return v[lo] + (rank-float64(lo))*(v[lo+1]-v[lo])
+}
@@ -0,0 +1,46 @@
+package metrics
+
+// Fit is a least-squares line y = Slope*x + Intercept.
+type Fit struct {
+	Slope     float64
+	Intercept float64
+	R2        float64 // share of the variance the line explains, 0-1
+	N         int
+}
+
+// At evaluates the line at x.
+func (f Fit) At(x float64) float64 { return f.Slope*x + f.Intercept }
+
+// LinearFit fits a line through the points (x[i], y[i]). With fewer than
+// two distinct x it returns a flat line through the mean.
+func LinearFit(x, y []float64) Fit {
+	n := min(len(x), len(y))
+	f := Fit{N: n}
+	if n == 0 {
+		return f
+	}
+	var sx, sy float64
+	for i := 0; i < n; i++ {
+		sx += x[i]
+		sy += y[i]
+	}
+	mx, my := sx/float64(n), sy/float64(n)
+
+	var sxx, sxy, syy float64
+	for i := 0; i < n; i++ {
+		dx, dy := x[i]-mx, y[i]-my
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		f.Intercept = my
+		return f
+	}
+	f.Slope = sxy / sxx
+	f.Intercept = my - f.Slope*mx
+	if syy > 0 {
+		f.R2 = sxy * sxy / (sxx * syy)
+	}
+	return f
+}
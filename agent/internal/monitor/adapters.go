@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"netshield/agent/internal/metrics"
+	"netshield/agent/internal/wifi"
+	"sort"
+)
+
+// pickPrimary chooses the adapter treated as the active link: the pinned
+// Config.Interface, else the adapter used last time if it is still
+// associated, else the associated adapter with the strongest signal.
+func (m *Monitor) pickPrimary(statuses []*wifi.WifiStatus) *wifi.WifiStatus {
+	m.mu.RLock()
+	last := m.primary
+	m.mu.RUnlock()
+
+	var best *wifi.WifiStatus
+	for _, st := range statuses {
+		if st.SSID == "" {
+			continue
+		}
+		if m.Config.Interface != "" {
+			if st.InterfaceName == m.Config.Interface {
+				return st
+			}
+			continue
+		}
+		if st.InterfaceName == last {
+			return st
+		}
+		if best == nil || st.Signal > best.Signal {
+			best = st
+		}
+	}
+	return best
+}
+
+// adaptersFor orders the adapters to try for ssid: those that see it in
+// the scan, strongest first, then the current adapter as a fallback.
+func (m *Monitor) adaptersFor(ssid string, current *wifi.WifiStatus, visible []wifi.VisibleNetwork) []string {
+	if m.Config.Interface != "" {
+		return []string{m.Config.Interface}
+	}
+
+	type seen struct {
+		iface  string
+		signal int
+	}
+	var candidates []seen
+	for _, v := range visible {
+		if v.SSID == ssid && v.InterfaceName != "" {
+			candidates = append(candidates, seen{v.InterfaceName, v.BestSignal()})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].signal > candidates[j].signal
+	})
+
+	var out []string
+	added := make(map[string]bool)
+	for _, c := range candidates {
+		if !added[c.iface] {
+			out = append(out, c.iface)
+			added[c.iface] = true
+		}
+	}
+	if !added[current.InterfaceName] {
+		out = append(out, current.InterfaceName)
+	}
+	return out
+}
+
+// interfaceStatus re-reads a single adapter.
+func (m *Monitor) interfaceStatus(ctx context.Context, iface string) (*wifi.WifiStatus, error) {
+	statuses, err := m.Wifi.GetInterfaceStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range statuses {
+		if st.InterfaceName == iface {
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %q not found", iface)
+}
+
+func newInterfaceSnapshot(st *wifi.WifiStatus, b metrics.Breakdown) InterfaceSnapshot {
+	return InterfaceSnapshot{
+		SSID:             st.SSID,
+		Profile:          st.ProfileName,
+		Interface:        st.InterfaceName,
+		State:            st.State,
+		BSSID:            st.BSSID,
+		RadioType:        st.RadioType,
+		Channel:          st.Channel,
+		Band:             st.Band,
+		ReceiveRateMbps:  st.ReceiveRateMbps,
+		TransmitRateMbps: st.TransmitRateMbps,
+		Authentication:   st.Authentication,
+		Cipher:           st.Cipher,
+		Signal:           st.Signal,
+		RSSI:             st.RSSI,
+		Score:            b.Score,
+		Breakdown:        b,
+	}
+}
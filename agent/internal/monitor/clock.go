@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock abstracts time so the monitor can run on a simulated timeline.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// VirtualClock only moves when slept on or advanced, so a monitor driven
+// by it runs deterministically and as fast as the CPU allows.
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func (c *VirtualClock) Sleep(d time.Duration) { c.Advance(d) }
+
+// After advances the clock by d and returns an already-fired channel.
+func (c *VirtualClock) After(d time.Duration) <-chan time.Time {
+	c.Advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"netshield/agent/internal/probe"
+	"netshield/agent/internal/wifi"
+	agentpb "netshield/agent/proto"
+)
+
+// Reasons a link counts as degraded, as listed in a DegradationEvent.
+const (
+	ReasonWeakSignal    = "weak_signal"
+	ReasonHighPing      = "high_ping"
+	ReasonPacketLoss    = "packet_loss"
+	ReasonLowMOS        = "low_mos"
+	ReasonDNS           = "dns"
+	ReasonEndpoint      = "endpoint"
+	ReasonCaptivePortal = "captive_portal"
+)
+
+// traceTimeout bounds the traceroute taken when a link degrades. It runs
+// beside the checks; a failover that switches networks cuts it short,
+// since the path it was following is gone, and the hops found so far are
+// kept.
+const traceTimeout = 20 * time.Second
+
+// DegradationEvent records the moment the primary link went bad and what
+// the path looked like then.
+type DegradationEvent struct {
+	Start     time.Time    `json:"start"`
+	SSID      string       `json:"ssid"`
+	BSSID     string       `json:"bssid"`
+	Interface string       `json:"interface"`
+	Reasons   []string     `json:"reasons"`
+	Fault     string       `json:"fault"`
+	Trace     *probe.Trace `json:"trace,omitempty"`
+	Tracing   bool         `json:"tracing,omitempty"` // the traceroute is still running
+}
+
+// trackDegradation opens an event when the primary link turns degraded
+// (or degrades on a new network) and closes it once the link recovers.
+// Events are published whole and replaced rather than changed, since the
+// snapshot shares them. It returns an event once, on the first check
+// after its traceroute is done, so the hops go to the server with it.
+func (m *Monitor) trackDegradation(ctx context.Context, status *wifi.WifiStatus, reasons []string, fault string, now time.Time) *DegradationEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	open := m.event
+
+	if len(reasons) == 0 {
+		if open != nil {
+			log.Printf("[monitor] degradation on %s ended after %s", open.SSID, now.Sub(open.Start).Round(time.Second))
+		}
+		m.event = nil
+		return m.takeEvent()
+	}
+	if open != nil && open.SSID == status.SSID && open.Interface == status.InterfaceName {
+		return m.takeEvent()
+	}
+
+	ev := &DegradationEvent{
+		Start:     now,
+		SSID:      status.SSID,
+		BSSID:     status.BSSID,
+		Interface: status.InterfaceName,
+		Reasons:   reasons,
+		Fault:     fault,
+		Tracing:   m.Tracer != nil,
+	}
+	log.Printf("[monitor] %s degraded: %v, fault %q", ev.SSID, reasons, fault)
+	// An older event still waiting on its trace goes out now without it.
+	var superseded *DegradationEvent
+	if prev := m.unsentEvent; prev != nil && prev.Tracing {
+		cp := *prev
+		cp.Tracing = false
+		superseded = &cp
+		m.stopTraceLocked()
+	}
+	m.event, m.unsentEvent = ev, ev
+	if ev.Tracing {
+		m.startTraceLocked(ctx, ev)
+	}
+	if superseded != nil {
+		return superseded
+	}
+	return m.takeEvent()
+}
+
+// takeEvent hands out the event waiting to be reported once its trace is
+// done. Caller holds m.mu.
+func (m *Monitor) takeEvent() *DegradationEvent {
+	ev := m.unsentEvent
+	if ev == nil || ev.Tracing {
+		return nil
+	}
+	m.unsentEvent = nil
+	return ev
+}
+
+// startTraceLocked traces the path to the first probe target in the
+// background and attaches the hops to ev when done. Caller holds m.mu.
+func (m *Monitor) startTraceLocked(ctx context.Context, ev *DegradationEvent) {
+	host := m.Config.PingHost
+	if len(m.Config.Targets) > 0 {
+		host = m.Config.Targets[0].Host
+	}
+	ctx, cancel := context.WithTimeout(ctx, traceTimeout)
+	m.stopTrace = cancel
+	go func() {
+		defer cancel()
+		tr, err := m.Tracer.Trace(ctx, host, probe.TraceOptions{})
+		if err != nil {
+			log.Println("[monitor] traceroute failed:", err)
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		done := *ev
+		done.Trace, done.Tracing = tr, false
+		if m.event == ev {
+			m.event = &done
+			m.snapshot.Degradation = &done
+		}
+		if m.unsentEvent == ev {
+			m.unsentEvent = &done
+		}
+	}()
+}
+
+// stopTraceLocked cuts short the running traceroute, if any. Caller
+// holds m.mu.
+func (m *Monitor) stopTraceLocked() {
+	if m.stopTrace != nil {
+		m.stopTrace()
+		m.stopTrace = nil
+	}
+}
+
+// cutTrace stops the traceroute before the monitor leaves the network it
+// was tracing.
+func (m *Monitor) cutTrace() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopTraceLocked()
+}
+
+func addEvent(metric *agentpb.NetworkMetric, ev *DegradationEvent) {
+	if ev == nil {
+		return
+	}
+	pb := &agentpb.DegradationEvent{
+		StartedUnix:   ev.Start.Unix(),
+		Reasons:       ev.Reasons,
+		FaultLocation: ev.Fault,
+	}
+	if ev.Trace != nil {
+		pb.TraceTarget, pb.TraceMethod, pb.TraceReached = ev.Trace.Target, ev.Trace.Method, ev.Trace.Reached
+		for _, h := range ev.Trace.Hops {
+			pb.Hops = append(pb.Hops, &agentpb.TraceHop{
+				Ttl:      int32(h.TTL),
+				Addr:     h.Addr,
+				Sent:     int32(h.Sent),
+				Received: int32(h.Received),
+				LossPct:  float32(h.LossPct),
+				AvgMs:    float32(h.AvgMs),
+				BestMs:   float32(h.BestMs),
+				WorstMs:  float32(h.WorstMs),
+			})
+		}
+	}
+	metric.Degradation = pb
+}
@@ -0,0 +1,446 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"netshield/agent/internal/wifi"
+)
+
+// settleDelay is how long an association is left to settle before the
+// monitor asks what it reaches.
+const settleDelay = 7 * time.Second
+
+// failoverStep is where a failover stands between two turns of the loop.
+type failoverStep int
+
+const (
+	failoverIdle   failoverStep = iota
+	failoverSettle              // connected to a candidate, waiting for it to settle
+)
+
+// candidate is one network and adapter a failover may move to.
+type candidate struct {
+	profile wifi.WifiProfile // InterfaceName is the adapter to connect on
+	// signal and bssid are the strongest access point of it in the scan;
+	// expected is its place in the ranking.
+	signal   int
+	bssid    string
+	expected float64
+}
+
+// failover walks the candidates one at a time across turns of the loop
+// instead of sleeping on each, so checks keep their pace and a cancelled
+// context ends it between any two steps.
+type failover struct {
+	step       failoverStep
+	candidates []candidate
+	next       int
+	settleAt   time.Time
+	result     *FailoverResult
+	// The link being left, to go back to, and its score on the last
+	// full check, which a candidate must beat.
+	from      wifi.WifiProfile
+	fromScore int
+}
+
+// Outcomes of a failover.
+const (
+	FailoverSwitched   = "switched"    // a candidate held up and beat the link left
+	FailoverRolledBack = "rolled_back" // none did, so it went back to the link left
+	FailoverStayed     = "stayed"      // none did and the link left was never dropped
+	FailoverFailed     = "failed"      // none did and going back failed too
+	FailoverCancelled  = "cancelled"   // the agent stopped before it ended
+)
+
+// FailoverResult records what a failover tried and how it ended.
+type FailoverResult struct {
+	Started   time.Time         `json:"started"`
+	Finished  time.Time         `json:"finished"`
+	From      string            `json:"from"`
+	FromScore int               `json:"from_score"`
+	To        string            `json:"to"` // network the agent ended up on; empty if none
+	Outcome   string            `json:"outcome"`
+	Reason    string            `json:"reason"`
+	Attempts  []FailoverAttempt `json:"attempts"`
+}
+
+// FailoverAttempt is one candidate tried and what verifying it found.
+type FailoverAttempt struct {
+	SSID      string  `json:"ssid"`
+	Interface string  `json:"interface"`
+	OK        bool    `json:"ok"`
+	Score     int     `json:"score"`
+	AvgPingMs float64 `json:"avg_ping_ms"`
+	LossPct   float64 `json:"loss_pct"`
+	Reason    string  `json:"reason,omitempty"` // why it was turned down
+}
+
+// failingOver reports whether a failover is under way.
+func (m *Monitor) failingOver() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.failover != nil
+}
+
+// beginFailover plans a move off current and connects to the first
+// candidate; the rest happens in stepFailover.
+func (m *Monitor) beginFailover(ctx context.Context, current *wifi.WifiStatus) error {
+	now := m.clock().Now()
+	if !m.allowFailover(now) {
+		return nil
+	}
+	all, err := m.candidates(ctx, current)
+	if err != nil {
+		return err
+	}
+	if len(all) == 0 {
+		return fmt.Errorf("no suitable alternative profile found")
+	}
+	var candidates []candidate
+	for _, c := range all {
+		if m.breakerOpen(c.profile.CleanName, now) {
+			log.Println("[monitor] skipping", c.profile.CleanName, "(circuit breaker open)")
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	if len(candidates) == 0 {
+		log.Println("[monitor] staying put: every candidate's circuit breaker is open")
+		return nil
+	}
+	ranking := m.rank(candidates, current.SSID, now)
+	for i, r := range ranking.Candidates {
+		log.Printf("[monitor] candidate %d: %s on %s, expected %.0f (%s)", i+1, r.SSID, r.Interface, r.Expected, r.Why)
+	}
+
+	from := wifi.WifiProfile{RawName: current.ProfileName, CleanName: current.SSID, InterfaceName: current.InterfaceName}
+	m.mu.Lock()
+	fromScore := m.snapshot.Score
+	m.failover = &failover{
+		candidates: candidates,
+		from:       from,
+		fromScore:  fromScore,
+		result:     &FailoverResult{Started: now, From: current.SSID, FromScore: fromScore},
+	}
+	m.ranking = ranking
+	m.mu.Unlock()
+	return m.stepFailover(ctx)
+}
+
+// candidates lists the networks a failover off current may try: the
+// scanned ones when switching automatically, else PreferredProfiles.
+func (m *Monitor) candidates(ctx context.Context, current *wifi.WifiStatus) ([]candidate, error) {
+	if m.autoSwitch() {
+		return m.visibleCandidates(ctx, current)
+	}
+	return m.preferredCandidates(ctx, current)
+}
+
+// stepFailover verifies the candidate that has settled, if any, and
+// otherwise connects to the next one. It ends the failover on the first
+// candidate that holds up, or goes back to the link it left once every
+// candidate has failed.
+func (m *Monitor) stepFailover(ctx context.Context) error {
+	m.mu.RLock()
+	f := m.failover
+	m.mu.RUnlock()
+	if f == nil {
+		return nil
+	}
+
+	for ctx.Err() == nil {
+		if f.step == failoverSettle {
+			if m.clock().Now().Before(f.settleAt) {
+				return nil // not settled yet; the loop comes back at settleAt
+			}
+			f.step = failoverIdle
+			c := f.candidates[f.next-1]
+			if m.verifyCandidate(ctx, f, c) {
+				m.adopt(c.profile.InterfaceName)
+				m.chose(ctx, c)
+				log.Println("[monitor] switched to", c.profile.CleanName)
+				m.endFailover(FailoverSwitched, c.profile.CleanName, "")
+				return nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		if f.next == len(f.candidates) {
+			return m.rollBack(ctx, f)
+		}
+		c := f.candidates[f.next]
+		f.next++
+		if m.recentlyCaptive(c.profile.CleanName) {
+			log.Println("[monitor] skipping", c.profile.CleanName, "(captive portal)")
+			continue
+		}
+
+		if !m.spendSwitch(m.clock().Now()) {
+			log.Println("[monitor] switch budget for the hour spent; not trying", c.profile.CleanName)
+			f.result.Attempts = append(f.result.Attempts, FailoverAttempt{
+				SSID: c.profile.CleanName, Interface: c.profile.InterfaceName, Reason: "hourly switch budget spent",
+			})
+			f.next = len(f.candidates)
+			continue
+		}
+		log.Println("[monitor] attempting switch from", f.from.CleanName, "to:", c.profile.CleanName, "on", c.profile.InterfaceName)
+		m.cutTrace()
+		if err := m.Wifi.Connect(ctx, c.profile); err != nil {
+			log.Println("[monitor] connect failed:", err)
+			f.result.Attempts = append(f.result.Attempts, FailoverAttempt{
+				SSID: c.profile.CleanName, Interface: c.profile.InterfaceName, Reason: "connect: " + err.Error(),
+			})
+			m.rememberConnect(ctx, c, false)
+			continue
+		}
+		f.step, f.settleAt = failoverSettle, m.clock().Now().Add(settleDelay)
+		return nil
+	}
+	m.endFailover(FailoverCancelled, "", "agent stopping")
+	return ctx.Err()
+}
+
+// verifyCandidate runs the full probe set on a settled connection and
+// accepts it only if it is online, meets the thresholds and outscores the
+// link left, degraded or not. The attempt goes into the result.
+func (m *Monitor) verifyCandidate(ctx context.Context, f *failover, c candidate) bool {
+	a := m.checkCandidate(ctx, f, c)
+	if a.OK {
+		log.Printf("[monitor] verified %s: score %d, ping %.0f ms, loss %.0f%%", a.SSID, a.Score, a.AvgPingMs, a.LossPct)
+	} else {
+		log.Printf("[monitor] verified %s: score %d, ping %.0f ms, loss %.0f%%, turned down: %s", a.SSID, a.Score, a.AvgPingMs, a.LossPct, a.Reason)
+	}
+	f.result.Attempts = append(f.result.Attempts, a)
+	if !a.OK && ctx.Err() == nil {
+		// Cut short, the candidate was not judged; it keeps its record.
+		m.rememberConnect(ctx, c, false)
+	}
+	return a.OK
+}
+
+func (m *Monitor) checkCandidate(ctx context.Context, f *failover, c candidate) FailoverAttempt {
+	p := c.profile
+	a := FailoverAttempt{SSID: p.CleanName, Interface: p.InterfaceName}
+	st := m.landedOn(ctx, p)
+	if st == nil {
+		a.Reason = "not associated"
+		return a
+	}
+	log.Println("[monitor] after-switch:", wifi.DebugStatus(st))
+	a.Interface = st.InterfaceName
+
+	ps := m.runProbes(ctx)
+	if ctx.Err() != nil {
+		a.Reason = "cancelled"
+		return a
+	}
+	a.Score = m.scorer().Score(scoreInputs(st, ps.res, ps.dns, nil)).Score
+	var rtt float64
+	if ps.res != nil {
+		a.LossPct = ps.res.LossPct
+		if ps.res.HasRTT() {
+			rtt = ps.res.AvgMs
+			a.AvgPingMs = math.Round(rtt*10) / 10
+		}
+	}
+
+	problems := m.problemsAt(m.enterThresholds(), float64(st.Signal), rtt, a.LossPct, m.callQuality(ps.res))
+	switch {
+	case ps.behindPortal():
+		m.markCaptive(p.CleanName)
+		a.Score, a.Reason = 0, "behind a captive portal"
+	case ps.res != nil && ps.res.Received == 0:
+		a.Reason = "probes got no reply"
+	case ps.criticalDown:
+		a.Reason = "critical endpoint unreachable"
+	case ps.dns.Degraded():
+		a.Reason = "name resolution failing"
+	case len(problems) > 0:
+		a.Reason = strings.Join(problems, ", ")
+	case a.Score <= f.fromScore:
+		a.Reason = fmt.Sprintf("score %d is no better than %d on %s", a.Score, f.fromScore, f.from.CleanName)
+	default:
+		a.OK = true
+	}
+	return a
+}
+
+// landedOn returns the status of the adapter that joined p, or nil if
+// none is on it.
+func (m *Monitor) landedOn(ctx context.Context, p wifi.WifiProfile) *wifi.WifiStatus {
+	statuses, err := m.Wifi.GetInterfaceStatuses(ctx)
+	if err != nil {
+		log.Println("[monitor] after-switch status error:", err)
+		return nil
+	}
+	for _, st := range statuses {
+		if p.InterfaceName != "" && st.InterfaceName != p.InterfaceName {
+			continue
+		}
+		if st.SSID == p.CleanName || (st.ProfileName != "" && st.ProfileName == p.RawName) {
+			return st
+		}
+	}
+	return nil
+}
+
+// rollBack ends a failover in which no candidate held up by going back
+// to the link it left, unless that is still up.
+func (m *Monitor) rollBack(ctx context.Context, f *failover) error {
+	if st := m.landedOn(ctx, f.from); st != nil {
+		m.adopt(st.InterfaceName)
+		log.Println("[monitor] no candidate held up; staying on", f.from.CleanName)
+		m.endFailover(FailoverStayed, f.from.CleanName, "no candidate held up")
+		return fmt.Errorf("no suitable alternative profile found or all failed")
+	}
+	if ctx.Err() != nil {
+		m.endFailover(FailoverCancelled, "", "agent stopping")
+		return ctx.Err()
+	}
+
+	log.Println("[monitor] no candidate held up; rolling back to", f.from.CleanName)
+	if err := m.Wifi.Connect(ctx, f.from); err != nil {
+		m.endFailover(FailoverFailed, "", "no candidate held up and rolling back failed: "+err.Error())
+		return fmt.Errorf("roll back to %s: %w", f.from.CleanName, err)
+	}
+	m.adopt(f.from.InterfaceName)
+	m.endFailover(FailoverRolledBack, f.from.CleanName, "no candidate held up")
+	return nil
+}
+
+func (m *Monitor) adopt(iface string) {
+	if iface == "" {
+		return
+	}
+	m.mu.Lock()
+	m.primary = iface
+	m.mu.Unlock()
+}
+
+// chose records that the failover settled on c.
+func (m *Monitor) chose(ctx context.Context, c candidate) {
+	m.rememberConnect(ctx, c, true)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ranking != nil {
+		m.ranking.Chosen = c.profile.CleanName
+	}
+}
+
+// endFailover closes the failover under way and keeps its result.
+func (m *Monitor) endFailover(outcome, to, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := m.failover
+	m.failover = nil
+	if f == nil {
+		return
+	}
+	r := f.result
+	r.Finished, r.Outcome, r.To, r.Reason = m.clock().Now(), outcome, to, reason
+	m.governFailover(outcome, r.Finished)
+	if reason == "" && len(r.Attempts) > 0 {
+		r.Reason = fmt.Sprintf("%s held up with score %d; %s had %d", to, r.Attempts[len(r.Attempts)-1].Score, r.From, r.FromScore)
+	}
+	m.lastFailover = r
+	m.snapshot.LastFailover = r
+	log.Printf("[monitor] failover from %s: %s (%s)", r.From, r.Outcome, r.Reason)
+}
+
+// preferredCandidates lists Config.PreferredProfiles in order, each on
+// the adapters that see it best; rank may reorder them.
+func (m *Monitor) preferredCandidates(ctx context.Context, current *wifi.WifiStatus) ([]candidate, error) {
+	profiles, err := m.Wifi.ListProfiles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list profiles: %w", err)
+	}
+
+	// A scan tells us which adapter sees each network best; failover still
+	// works without it by staying on the current adapter.
+	visible, err := m.Wifi.ScanNetworks(ctx)
+	if err != nil {
+		log.Println("[monitor] scan failed, using current adapter:", err)
+	}
+
+	var out []candidate
+	for _, preferredName := range m.Config.PreferredProfiles {
+		if preferredName == current.ProfileName || preferredName == current.SSID {
+			continue
+		}
+		p := wifi.FindProfileByCleanName(profiles, preferredName)
+		if p == nil {
+			continue
+		}
+		for _, iface := range m.adaptersFor(p.CleanName, current, visible) {
+			c := candidate{profile: *p}
+			c.profile.InterfaceName = iface
+			c.signal, c.bssid = seenAs(visible, p.CleanName, iface)
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// visibleCandidates lists the scanned networks with a saved profile,
+// strongest first; rank may reorder them.
+func (m *Monitor) visibleCandidates(ctx context.Context, current *wifi.WifiStatus) ([]candidate, error) {
+	visible, err := m.Wifi.ScanNetworks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("scan networks: %w", err)
+	}
+
+	// Try the strongest networks first.
+	sort.SliceStable(visible, func(i, j int) bool {
+		return visible[i].BestSignal() > visible[j].BestSignal()
+	})
+
+	savedProfiles, err := m.Wifi.ListProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build lookup for saved profiles
+	savedByName := make(map[string]wifi.WifiProfile)
+	for _, p := range savedProfiles {
+		savedByName[p.CleanName] = p
+	}
+
+	log.Println("[monitor] visible SSIDs:")
+	for _, v := range visible {
+		log.Printf(" - %s on %s (%d%%, %d BSSIDs, %s)", v.SSID, v.InterfaceName, v.BestSignal(), len(v.BSSIDs), v.Authentication)
+	}
+	var out []candidate
+	for _, v := range visible {
+		if v.SSID == "" {
+			continue // hidden network
+		}
+		if m.Config.Interface != "" && v.InterfaceName != "" && v.InterfaceName != m.Config.Interface {
+			continue // seen by an adapter we were told not to use
+		}
+		// Check if visible SSID has a saved profile
+		p, ok := savedByName[strings.TrimSpace(v.SSID)]
+		if !ok {
+			log.Println("[monitor] visible SSID has no saved profile:", v.SSID)
+			continue // visible but no credentials
+		}
+
+		// Skip current connection
+		if p.CleanName == current.ProfileName && (v.InterfaceName == "" || v.InterfaceName == current.InterfaceName) {
+			log.Println("[monitor] already connected to:", p.RawName)
+			continue
+		}
+		p.InterfaceName = v.InterfaceName
+		c := candidate{profile: p}
+		c.signal, c.bssid = seenAs([]wifi.VisibleNetwork{v}, p.CleanName, v.InterfaceName)
+		out = append(out, c)
+	}
+	return out, nil
+}
@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"netshield/agent/internal/probe"
+	"netshield/agent/internal/wifi"
+)
+
+var simStart = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+func flat(v float64) wifi.Curve { return wifi.Curve{{Value: v}} }
+
+// labFades is a lab network whose signal sinks below MinSignalPercent a
+// minute in and settles at 30%, still in range to go back to.
+func labFades() wifi.SimNetwork {
+	return wifi.SimNetwork{
+		SSID:    "Lab-5G",
+		BSSID:   "02:00:00:00:00:01",
+		Channel: 36,
+		Signal:  wifi.Curve{{At: 0, Value: 85}, {At: time.Minute, Value: 30}},
+		Ping:    flat(20),
+	}
+}
+
+// newSimMonitor runs a Monitor against scn on a virtual clock, failing
+// over to the scenario's other networks in order.
+func newSimMonitor(scn *wifi.Scenario) (*Monitor, *VirtualClock, *wifi.SimulatedManager) {
+	if len(scn.Interfaces) == 0 {
+		scn.Interfaces = []string{"sim0"}
+	}
+	clock := NewVirtualClock(simStart)
+	sim := wifi.NewSimulatedManager(scn, clock.Now)
+	m := &Monitor{
+		Wifi:    sim,
+		Prober:  probe.Simulated{RTT: sim.PingRTT},
+		Captive: probe.SimulatedCaptive{Captive: sim.CaptivePortal},
+		Clock:   clock,
+		Config: Config{
+			MinSignalPercent: 60,
+			MaxAvgPingMs:     120,
+			PingHost:         "8.8.8.8",
+			CheckInterval:    5 * time.Second,
+		},
+	}
+	for _, n := range scn.Networks {
+		m.Config.PreferredProfiles = append(m.Config.PreferredProfiles, n.SSID)
+	}
+	return m, clock, sim
+}
+
+// run turns the monitor's loop the way Start does, one check or failover
+// step at a time, until done holds or limit of scenario time has passed.
+func run(t *testing.T, ctx context.Context, m *Monitor, clock *VirtualClock, limit time.Duration, done func() bool) {
+	t.Helper()
+	for clock.Now().Sub(simStart) < limit {
+		clock.Advance(m.nextWait(clock.Now()))
+		var err error
+		if m.failingOver() {
+			err = m.stepFailover(ctx)
+		} else {
+			err = m.checkOnce(ctx)
+		}
+		if err != nil {
+			t.Logf("step at %s: %v", clock.Now().Sub(simStart), err)
+		}
+		if done() {
+			return
+		}
+	}
+	t.Fatalf("still waiting after %s of scenario time", limit)
+}
+
+func (m *Monitor) failoverResult() *FailoverResult {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastFailover
+}
+
+func TestFailoverSwitches(t *testing.T) {
+	m, clock, sim := newSimMonitor(&wifi.Scenario{
+		Start: map[string]string{"sim0": "Lab-5G"},
+		Networks: []wifi.SimNetwork{
+			labFades(),
+			{SSID: "Backup", BSSID: "02:00:00:00:00:02", Channel: 6, Signal: flat(80), Ping: flat(30)},
+		},
+	})
+
+	var degradedAt time.Duration
+	run(t, context.Background(), m, clock, 5*time.Minute, func() bool {
+		if degradedAt == 0 && m.GetSnapshot().Degraded {
+			degradedAt = clock.Now().Sub(simStart)
+		}
+		return m.failoverResult() != nil
+	})
+
+	if degradedAt < 40*time.Second {
+		t.Errorf("degraded at %s, want once the smoothed signal has crossed 60%%", degradedAt)
+	}
+	r := m.failoverResult()
+	if r.Outcome != FailoverSwitched || r.From != "Lab-5G" || r.To != "Backup" {
+		t.Fatalf("failover = %s from %s to %s (%s)", r.Outcome, r.From, r.To, r.Reason)
+	}
+	if len(r.Attempts) != 1 || !r.Attempts[0].OK || r.Attempts[0].Score <= r.FromScore {
+		t.Errorf("attempts = %+v, from score %d", r.Attempts, r.FromScore)
+	}
+	if got := sim.ConnectLog(); !slices.Equal(got, []string{"sim0:Backup:ok"}) {
+		t.Errorf("connects = %q", got)
+	}
+
+	run(t, context.Background(), m, clock, 6*time.Minute, func() bool { return true })
+	if s := m.GetSnapshot(); s.SSID != "Backup" || s.Degraded {
+		t.Errorf("after failover on %q, degraded %v", s.SSID, s.Degraded)
+	}
+	if g := m.governorState(clock.Now()); g.SwitchesLastHour != 1 || g.ConsecutiveFailures != 0 || g.CooldownUntil != nil {
+		t.Errorf("governor = %+v", g)
+	}
+}
+
+func TestFailoverRollsBack(t *testing.T) {
+	m, clock, sim := newSimMonitor(&wifi.Scenario{
+		Start: map[string]string{"sim0": "Lab-5G"},
+		Networks: []wifi.SimNetwork{
+			labFades(),
+			{SSID: "Campus-Guest", Channel: 1, Signal: flat(90), Ping: flat(25), Captive: true},
+			{SSID: "Backup", Channel: 6, Signal: flat(80), Connect: wifi.SimConnect{Fail: true}},
+		},
+	})
+
+	run(t, context.Background(), m, clock, 5*time.Minute, func() bool { return m.failoverResult() != nil })
+
+	r := m.failoverResult()
+	if r.Outcome != FailoverRolledBack || r.To != "Lab-5G" {
+		t.Fatalf("failover = %s to %s (%s)", r.Outcome, r.To, r.Reason)
+	}
+	if len(r.Attempts) != 2 || r.Attempts[0].Reason != "behind a captive portal" {
+		t.Errorf("attempts = %+v", r.Attempts)
+	}
+	want := []string{"sim0:Campus-Guest:ok", "sim0:Backup:fail", "sim0:Lab-5G:ok"}
+	if got := sim.ConnectLog(); !slices.Equal(got, want) {
+		t.Errorf("connects = %q, want %q", got, want)
+	}
+
+	g := m.governorState(clock.Now())
+	if g.SwitchesLastHour != 2 {
+		t.Errorf("switches last hour = %d, want the two candidates and not the rollback", g.SwitchesLastHour)
+	}
+	if g.ConsecutiveFailures != 1 || g.CooldownUntil == nil || !g.StayPut {
+		t.Errorf("governor = %+v, want a cooldown after the failed failover", g)
+	}
+}
+
+func TestFailoverCancelled(t *testing.T) {
+	m, clock, sim := newSimMonitor(&wifi.Scenario{
+		Start: map[string]string{"sim0": "Lab-5G"},
+		Networks: []wifi.SimNetwork{
+			labFades(),
+			{SSID: "Backup", Channel: 6, Signal: flat(80), Ping: flat(30), Connect: wifi.SimConnect{Latency: 3 * time.Second}},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run(t, ctx, m, clock, 5*time.Minute, func() bool { return len(sim.ConnectLog()) > 0 })
+	if !m.failingOver() {
+		t.Fatal("failover ended before the candidate settled")
+	}
+
+	// The agent stops while the candidate is settling.
+	cancel()
+	clock.Advance(m.nextWait(clock.Now()))
+	if err := m.stepFailover(ctx); err != context.Canceled {
+		t.Errorf("stepFailover = %v, want context.Canceled", err)
+	}
+	r := m.failoverResult()
+	if r == nil || r.Outcome != FailoverCancelled {
+		t.Fatalf("failover = %+v, want cancelled", r)
+	}
+
+	g := m.governorState(clock.Now())
+	if g.ConsecutiveFailures != 0 || g.CooldownUntil != nil || len(g.OpenBreakers) != 0 {
+		t.Errorf("governor = %+v, want a cancelled failover not counted", g)
+	}
+	if b := m.gov.breakers["Backup"]; b != nil {
+		t.Errorf("Backup breaker = %+v, want it untouched", *b)
+	}
+
+	if err := m.Start(ctx); err != context.Canceled {
+		t.Errorf("Start = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	agentpb "netshield/agent/proto"
+)
+
+// Every reconnect drops the user's sessions, so the governor holds back
+// failover that would only churn between marginal networks.
+type governor struct {
+	switches  []time.Time // connects to candidates in the last hour
+	failures  int         // failovers in a row that did not switch
+	coolUntil time.Time
+	breakers  map[string]*breaker // by SSID
+	heldBack  string              // reason last logged for staying put
+}
+
+// breaker counts a network's failed attempts in a row and, past
+// BreakerThreshold, keeps failover off it until openUntil.
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// GovernorState is what the governor allows right now.
+type GovernorState struct {
+	SwitchesLastHour    int            `json:"switches_last_hour"`
+	MaxSwitchesPerHour  int            `json:"max_switches_per_hour"`
+	ConsecutiveFailures int            `json:"consecutive_failures"`
+	CooldownUntil       *time.Time     `json:"cooldown_until,omitempty"`
+	StayPut             bool           `json:"stay_put"` // no failover will start until the limits clear
+	Reason              string         `json:"reason,omitempty"`
+	OpenBreakers        []BreakerState `json:"open_breakers,omitempty"`
+}
+
+// BreakerState is a network failover is keeping away from.
+type BreakerState struct {
+	SSID      string    `json:"ssid"`
+	Failures  int       `json:"failures"`
+	OpenUntil time.Time `json:"open_until"`
+}
+
+func (m *Monitor) maxSwitchesPerHour() int {
+	if m.Config.MaxSwitchesPerHour > 0 {
+		return m.Config.MaxSwitchesPerHour
+	}
+	return 6
+}
+
+func (m *Monitor) breakerThreshold() int {
+	if m.Config.BreakerThreshold > 0 {
+		return m.Config.BreakerThreshold
+	}
+	return 3
+}
+
+func (m *Monitor) breakerOpenFor() time.Duration {
+	if m.Config.BreakerOpenFor > 0 {
+		return m.Config.BreakerOpenFor
+	}
+	return 15 * time.Minute
+}
+
+// cooldown is the wait after the n-th failed failover in a row:
+// FailoverCooldown doubled per failure, up to MaxFailoverCooldown.
+func (m *Monitor) cooldown(n int) time.Duration {
+	base, hi := m.Config.FailoverCooldown, m.Config.MaxFailoverCooldown
+	if base <= 0 {
+		base = 30 * time.Second
+	}
+	if hi <= 0 {
+		hi = 10 * time.Minute
+	}
+	d := base
+	for i := 1; i < n && d < hi; i++ {
+		d *= 2
+	}
+	return min(d, hi)
+}
+
+// stayPut returns why no failover may start at now, or "" if one may.
+// Caller holds m.mu.
+func (m *Monitor) stayPut(now time.Time) string {
+	g := &m.gov
+	g.prune(now)
+	switch {
+	case now.Before(g.coolUntil):
+		return fmt.Sprintf("cooling down until %s (failed failovers in a row: %d)", g.coolUntil.Format(time.TimeOnly), g.failures)
+	case len(g.switches) >= m.maxSwitchesPerHour():
+		return fmt.Sprintf("%d switches in the last hour", len(g.switches))
+	}
+	return ""
+}
+
+// prune drops switches older than an hour. Caller holds m.mu.
+func (g *governor) prune(now time.Time) {
+	cut := 0
+	for cut < len(g.switches) && now.Sub(g.switches[cut]) >= time.Hour {
+		cut++
+	}
+	g.switches = g.switches[cut:]
+}
+
+// allowFailover reports whether a failover may start now, logging the
+// reason when it may not and it has not been logged already.
+func (m *Monitor) allowFailover(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	why := m.stayPut(now)
+	if why != "" && why != m.gov.heldBack {
+		log.Println("[monitor] staying put:", why)
+	}
+	m.gov.heldBack = why
+	return why == ""
+}
+
+// spendSwitch counts a connect to a candidate against the hourly budget
+// and reports whether the budget allowed it. Going back to the link left
+// is neither limited nor counted, since it is what stops the churn.
+func (m *Monitor) spendSwitch(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g := &m.gov
+	g.prune(now)
+	if len(g.switches) >= m.maxSwitchesPerHour() {
+		return false
+	}
+	g.switches = append(g.switches, now)
+	return true
+}
+
+// breakerOpen reports whether failover is keeping away from ssid.
+func (m *Monitor) breakerOpen(ssid string, now time.Time) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	b := m.gov.breakers[ssid]
+	return b != nil && now.Before(b.openUntil)
+}
+
+// tripBreaker counts an attempt on ssid; BreakerThreshold failures in a
+// row open its breaker, and a success closes it.
+func (m *Monitor) tripBreaker(ssid string, ok bool, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g := &m.gov
+	if ok {
+		delete(g.breakers, ssid)
+		return
+	}
+	if g.breakers == nil {
+		g.breakers = make(map[string]*breaker)
+	}
+	b := g.breakers[ssid]
+	if b == nil {
+		b = &breaker{}
+		g.breakers[ssid] = b
+	}
+	b.failures++
+	if b.failures >= m.breakerThreshold() {
+		b.openUntil = now.Add(m.breakerOpenFor())
+		log.Printf("[monitor] %s failed %d times in a row; leaving it out until %s", ssid, b.failures, b.openUntil.Format(time.TimeOnly))
+	}
+}
+
+// governFailover updates the cooldown once a failover ends: a switch
+// clears it, a cancelled one leaves it be, anything else doubles it.
+// Caller holds m.mu.
+func (m *Monitor) governFailover(outcome string, now time.Time) {
+	g := &m.gov
+	switch outcome {
+	case FailoverSwitched:
+		g.failures, g.coolUntil = 0, time.Time{}
+		return
+	case FailoverCancelled:
+		return
+	}
+	g.failures++
+	g.coolUntil = now.Add(m.cooldown(g.failures))
+}
+
+// governorState reports the governor at now.
+func (m *Monitor) governorState(now time.Time) GovernorState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g := &m.gov
+	s := GovernorState{
+		MaxSwitchesPerHour:  m.maxSwitchesPerHour(),
+		ConsecutiveFailures: g.failures,
+		Reason:              m.stayPut(now),
+	}
+	s.SwitchesLastHour, s.StayPut = len(g.switches), s.Reason != ""
+	if now.Before(g.coolUntil) {
+		until := g.coolUntil
+		s.CooldownUntil = &until
+	}
+	for ssid, b := range g.breakers {
+		if now.Before(b.openUntil) {
+			s.OpenBreakers = append(s.OpenBreakers, BreakerState{SSID: ssid, Failures: b.failures, OpenUntil: b.openUntil})
+		}
+	}
+	sort.Slice(s.OpenBreakers, func(i, j int) bool { return s.OpenBreakers[i].SSID < s.OpenBreakers[j].SSID })
+	return s
+}
+
+func addGovernor(metric *agentpb.NetworkMetric, s GovernorState) {
+	pb := &agentpb.FailoverGovernor{
+		SwitchesLastHour:    int32(s.SwitchesLastHour),
+		MaxSwitchesPerHour:  int32(s.MaxSwitchesPerHour),
+		ConsecutiveFailures: int32(s.ConsecutiveFailures),
+		StayPut:             s.StayPut,
+		Reason:              s.Reason,
+	}
+	if s.CooldownUntil != nil {
+		pb.CooldownUntilUnix = s.CooldownUntil.Unix()
+	}
+	for _, b := range s.OpenBreakers {
+		pb.OpenBreakers = append(pb.OpenBreakers, b.SSID)
+	}
+	metric.Governor = pb
+}
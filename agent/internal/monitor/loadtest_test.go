@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"netshield/agent/internal/history"
+	"netshield/agent/internal/speedtest"
+	"netshield/agent/internal/wifi"
+)
+
+func (m *Monitor) setLoadTest(running bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loadTestRunning = running
+}
+
+func until(clock *VirtualClock, d time.Duration) func() bool {
+	return func() bool { return clock.Now().Sub(simStart) >= d }
+}
+
+// pingSpike is a network whose ping sits at 400ms from the first minute
+// to the third, the way it does while a load test fills the link.
+func pingSpike() wifi.SimNetwork {
+	return wifi.SimNetwork{
+		SSID:   "Lab-5G",
+		Signal: flat(85),
+		Ping: wifi.Curve{
+			{At: 0, Value: 20}, {At: 59 * time.Second, Value: 20},
+			{At: time.Minute, Value: 400}, {At: 3 * time.Minute, Value: 400},
+			{At: 3*time.Minute + time.Second, Value: 20},
+		},
+	}
+}
+
+// Checks taken while a bufferbloat test loads the link are reported but
+// kept out of the link model and the failover decision.
+func TestLoadTestHoldsLinkModel(t *testing.T) {
+	m, clock, sim := newSimMonitor(&wifi.Scenario{
+		Start: map[string]string{"sim0": "Lab-5G"},
+		Networks: []wifi.SimNetwork{
+			pingSpike(),
+			{SSID: "Backup", Channel: 6, Signal: flat(80), Ping: flat(30)},
+		},
+	})
+	ctx := context.Background()
+
+	run(t, ctx, m, clock, 2*time.Minute, until(clock, 55*time.Second))
+	before := m.GetSnapshot().Quality
+	if before == nil || before.Degraded {
+		t.Fatalf("quality before the test = %+v", before)
+	}
+
+	m.setLoadTest(true)
+	run(t, ctx, m, clock, 4*time.Minute, until(clock, 2*time.Minute+30*time.Second))
+	s := m.GetSnapshot()
+	if s.AvgPingMs < 300 {
+		t.Errorf("reported ping %dms, want the loaded ping", s.AvgPingMs)
+	}
+	if s.Quality == nil || s.Quality.Samples != before.Samples || s.Quality.RTTEWMA != before.RTTEWMA {
+		t.Errorf("quality under load = %+v, want it held at %+v", s.Quality, before)
+	}
+	if m.failingOver() || m.failoverResult() != nil || len(sim.ConnectLog()) > 0 {
+		t.Errorf("failed over under load: %q", sim.ConnectLog())
+	}
+
+	run(t, ctx, m, clock, 4*time.Minute, until(clock, 3*time.Minute+5*time.Second))
+	m.setLoadTest(false)
+	run(t, ctx, m, clock, 5*time.Minute, until(clock, 3*time.Minute+30*time.Second))
+	s = m.GetSnapshot()
+	if s.SSID != "Lab-5G" || s.Quality == nil || s.Quality.Samples <= before.Samples || s.Quality.Degraded {
+		t.Errorf("quality after the test on %s = %+v, want checks counted again", s.SSID, s.Quality)
+	}
+}
+
+// A speedtest in flight keeps its checks out of the link model and out of
+// History, so the network is not ranked on the test's own load.
+func TestSpeedtestHoldsChecks(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/speedtest/download" {
+			close(started)
+			<-release
+			io.Copy(w, strings.NewReader(strings.Repeat("x", 1024)))
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	m, clock, sim := newSimMonitor(&wifi.Scenario{
+		Start: map[string]string{"sim0": "Lab-5G"},
+		Networks: []wifi.SimNetwork{
+			pingSpike(),
+			{SSID: "Backup", Channel: 6, Signal: flat(80), Ping: flat(30)},
+		},
+	})
+	m.Speedtest = &speedtest.Tester{BaseURL: srv.URL, MaxBytes: 1024}
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.History = store
+	ctx := context.Background()
+
+	run(t, ctx, m, clock, 2*time.Minute, until(clock, 55*time.Second))
+	before := m.GetSnapshot().Quality
+	checks := store.Expect("Lab-5G", "", clock.Now()).Checks
+
+	done := make(chan error)
+	go func() {
+		_, err := m.RunSpeedtest(ctx)
+		done <- err
+	}()
+	<-started
+	run(t, ctx, m, clock, 4*time.Minute, until(clock, 3*time.Minute+5*time.Second))
+	if got := store.Expect("Lab-5G", "", clock.Now()).Checks; got != checks {
+		t.Errorf("History counted %d checks, want %d from before the speedtest", got, checks)
+	}
+	s := m.GetSnapshot()
+	if s.Quality == nil || s.Quality.Samples != before.Samples || s.Degradation != nil || s.Prediction != nil {
+		t.Errorf("snapshot under the speedtest: quality %+v, event %+v, prediction %+v", s.Quality, s.Degradation, s.Prediction)
+	}
+	if len(sim.ConnectLog()) > 0 {
+		t.Errorf("failed over during the speedtest: %q", sim.ConnectLog())
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	run(t, ctx, m, clock, 5*time.Minute, until(clock, 3*time.Minute+30*time.Second))
+	if got := store.Expect("Lab-5G", "", clock.Now()).Checks; got <= checks {
+		t.Errorf("History has %d checks after the speedtest, want more than %d", got, checks)
+	}
+}
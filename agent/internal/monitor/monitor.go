@@ -1,281 +1,1034 @@
 package monitor
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"netshield/agent/internal/history"
+	"netshield/agent/internal/metrics"
+	"netshield/agent/internal/probe"
+	"netshield/agent/internal/speedtest"
 	"netshield/agent/internal/wifi"
 	agentpb "netshield/agent/proto"
-	"os/exec"
-	"strings"
+	"os"
+	"slices"
 	"sync"
 	"time"
 )
 
 type Config struct {
-	MinSignalPercent  int
-	MaxAvgPingMs      int
-	PingHost          string
-	CheckInterval     time.Duration
-	PreferredProfiles []string
+	MinSignalPercent int
+	MaxAvgPingMs     int
+	PingHost         string
+	// CheckInterval is the pace of checks on a healthy link at first.
+	// While the primary is degraded or a failover is under way checks
+	// come every MinCheckInterval (default a quarter of CheckInterval);
+	// while it stays healthy they back off to MaxCheckInterval (default
+	// twice CheckInterval).
+	CheckInterval    time.Duration
+	MinCheckInterval time.Duration
+	MaxCheckInterval time.Duration
+	// ProbeTimeout bounds each probe of a check: a ping burst, a DNS
+	// query, an endpoint request, the portal check. Default 5s.
+	ProbeTimeout time.Duration
+	// Signal, ping and call quality are judged on an EWMA over checks
+	// (SmoothingAlpha, default 0.3) rather than one sample, and the window
+	// of the last WindowSize checks (default 12) gives the percentiles.
+	// A link turns degraded past MinSignalPercent, MaxAvgPingMs,
+	// MaxLossPct (default 20) or MinMOS and healthy only once back past
+	// the Exit thresholds (default 10 points of signal, 80% of the ping,
+	// a quarter of the loss and 0.2 MOS better); either way the smoothed
+	// values must stay across for MinDwell (default 10s).
+	SmoothingAlpha    float64
+	WindowSize        int
+	MaxLossPct        float64
+	ExitSignalPercent int
+	ExitAvgPingMs     int
+	ExitLossPct       float64
+	ExitMOS           float64
+	MinDwell          time.Duration
+	// A line is fitted through the signal and RTT of the last TrendWindow
+	// (default 2m) of checks; one that reaches the enter threshold within
+	// PredictHorizon (default 2m) raises a degradation_predicted event.
+	// With FailoverOnPrediction a falling signal starts failover once it
+	// is due within PredictLeadTime (default 30s) rather than after the
+	// link has gone bad. A rising RTT may be upstream of the Wi-Fi, so it
+	// is only reported.
+	TrendWindow          time.Duration
+	PredictHorizon       time.Duration
+	FailoverOnPrediction bool
+	PredictLeadTime      time.Duration
+	// The failover governor stops the monitor reconnecting forever when
+	// every network is marginal. At most MaxSwitchesPerHour connects
+	// (default 6) happen in any hour; after a failover that does not
+	// switch, the next waits FailoverCooldown (default 30s), doubled for
+	// each further one up to MaxFailoverCooldown (default 10m); a network
+	// turned down BreakerThreshold times in a row (default 3) is left out
+	// for BreakerOpenFor (default 15m). While a limit holds the monitor
+	// stays put on the link it has.
+	MaxSwitchesPerHour  int
+	FailoverCooldown    time.Duration
+	MaxFailoverCooldown time.Duration
+	BreakerThreshold    int
+	BreakerOpenFor      time.Duration
+	PreferredProfiles   []string
+	// Interface pins monitoring and failover to one adapter (e.g. "Wi-Fi 2").
+	// Empty lets the monitor pick among all wireless adapters.
+	Interface string
+	// Targets are probed in order and the first that answers measures the
+	// link, so e.g. a TCP target can stand in where ICMP is filtered.
+	// Empty probes PingHost over ICMP.
+	Targets []probe.Target
+	// ProbeCount is the burst size for targets that do not set Count.
+	// Jitter and loss come from the burst, so it should be more than a few.
+	ProbeCount int
+	// SpeedtestInterval is how often the throughput test runs; it moves
+	// real data, so it should be far longer than CheckInterval. Zero runs
+	// it only on demand.
+	SpeedtestInterval time.Duration
+	// DNSNames are resolved every check through each of DNSServers ("" for
+	// the system resolver, "1.1.1.1", "tcp://1.1.1.1:53"). Broken name
+	// resolution marks the link degraded even when ping is fine.
+	DNSNames   []string
+	DNSServers []string
+	// Endpoints are application URLs checked every interval. A Critical
+	// endpoint that fails marks the link degraded whatever the signal.
+	Endpoints []probe.Endpoint
+	// Domain is reported with every metric: "exam", "telemedicine",
+	// "remote-work". Voice and video domains judge the link by the
+	// estimated call quality (MOS below MinMOS) instead of MaxAvgPingMs.
+	Domain string
+	Codec  string  // metrics.Codecs profile for the estimate; default g711
+	MinMOS float64 // default 3.6, roughly R = 70
+	// BufferbloatInterval is how often latency under load is measured;
+	// like the speedtest it saturates the link. Zero runs it only on demand.
+	BufferbloatInterval time.Duration
+	// ExamWindows are scheduled exams. Tests that load the link never
+	// start inside one.
+	ExamWindows []Window
+	// DeviceID names this host in every metric; default its hostname.
+	// Each adapter is reported under it with its own interface name.
+	DeviceID string
 }
 
+// Window is a span of wall-clock time, e.g. an exam.
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+func (m *Monitor) deviceID() string {
+	if m.Config.DeviceID != "" {
+		return m.Config.DeviceID
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "unknown-device"
+}
+
+// voiceDomain reports whether the domain lives on calls rather than pages.
+func voiceDomain(domain string) bool {
+	return domain == "telemedicine" || domain == "remote-work"
+}
+
+// InterfaceSnapshot is the last reading of one wireless adapter.
+type InterfaceSnapshot struct {
+	SSID             string  `json:"ssid"`
+	Profile          string  `json:"profile"`
+	Interface        string  `json:"interface"`
+	State            string  `json:"state"`
+	BSSID            string  `json:"bssid"`
+	RadioType        string  `json:"radio_type"`
+	Channel          int     `json:"channel"`
+	Band             string  `json:"band"`
+	ReceiveRateMbps  float64 `json:"rx_rate_mbps"`
+	TransmitRateMbps float64 `json:"tx_rate_mbps"`
+	Authentication   string  `json:"authentication"`
+	Cipher           string  `json:"cipher"`
+	Signal           int     `json:"signal_percent"`
+	RSSI             int     `json:"rssi_dbm"`
+	Score            int     `json:"score"`
+	Degraded         bool    `json:"degraded"`
+	// Breakdown shows what the score is made of; adapters that are not
+	// the primary are scored on signal alone.
+	Breakdown metrics.Breakdown `json:"score_breakdown"`
+}
+
+// Snapshot flattens the primary adapter into the top level so the widget
+// keeps working, and lists every adapter under Interfaces.
 type Snapshot struct {
-	SSID        string    `json:"ssid"`
-	Profile     string    `json:"profile"`
-	Signal      int       `json:"signal_percent"`
-	AvgPingMs   int       `json:"avg_ping_ms"`
-	Score       int       `json:"score"`
-	LastUpdated time.Time `json:"last_updated"`
+	InterfaceSnapshot
+	AvgPingMs     int                          `json:"avg_ping_ms"`
+	JitterMs      float64                      `json:"jitter_ms"`
+	PacketLossPct float64                      `json:"packet_loss_pct"`
+	Probe         *probe.Result                `json:"probe,omitempty"`
+	Throughput    *speedtest.Result            `json:"throughput,omitempty"`
+	Bufferbloat   *speedtest.BufferbloatResult `json:"bufferbloat,omitempty"`
+	DNS           *probe.DNSSummary            `json:"dns,omitempty"`
+	Endpoints     []probe.EndpointResult       `json:"endpoints,omitempty"`
+	CallQuality   *metrics.CallQuality         `json:"call_quality,omitempty"`
+	Connectivity  string                       `json:"connectivity,omitempty"` // probe.State*; captive_portal means log in
+	PortalURL     string                       `json:"portal_url,omitempty"`   // login page, when the portal redirected to one
+	Fault         string                       `json:"fault"`                  // probe.Fault*; where a degraded link breaks
+	Path          *probe.PathReport            `json:"path,omitempty"`
+	Degradation   *DegradationEvent            `json:"degradation,omitempty"` // open while the link stays degraded
+	Quality       *Quality                     `json:"quality,omitempty"`     // smoothed; what degradation is decided on
+	Prediction    *Prediction                  `json:"prediction,omitempty"`  // set while the trend says it will degrade soon
+	LastFailover  *FailoverResult              `json:"last_failover,omitempty"`
+	Governor      GovernorState                `json:"governor"`
+	Interfaces    []InterfaceSnapshot          `json:"interfaces"`
+	LastUpdated   time.Time                    `json:"last_updated"`
 }
 
 type Monitor struct {
 	Wifi                wifi.Manager
+	Prober              probe.Prober         // nil uses probe.Default
+	DNS                 probe.DNSProber      // nil uses probe.DNSClient
+	HTTP                probe.EndpointProber // nil uses probe.HTTPProber
+	Clock               Clock                // nil uses the wall clock
+	Speedtest           *speedtest.Tester    // nil disables throughput tests
+	Bufferbloat         *speedtest.Tester    // nil disables latency-under-load tests
+	Captive             probe.CaptiveChecker // nil disables captive-portal detection
+	Path                probe.Discoverer     // nil disables fault localization
+	Tracer              probe.Tracer         // nil disables traceroutes on degradation
+	Scorer              metrics.Scorer       // nil uses the profile for Config.Domain
+	History             *history.Store       // nil ranks failover candidates on live signal alone
 	Config              Config
-	SwitchAutomatically bool
+	SwitchAutomatically bool // fail over to scanned networks, not PreferredProfiles; once started, change it with ToggleAutoSwitch
 	mu                  sync.RWMutex
 	snapshot            Snapshot
+	primary             string // adapter currently treated as the active link
+	throughput          *speedtest.Result
+	lastSpeedtest       time.Time
+	bufferbloat         *speedtest.BufferbloatResult
+	lastBufferbloat     time.Time
+	loadTestRunning     bool                 // a speedtest or bufferbloat test owns the link
+	event               *DegradationEvent    // open while the primary stays degraded
+	unsentEvent         *DegradationEvent    // waiting on its trace to be reported
+	stopTrace           context.CancelFunc   // cuts short the running traceroute
+	captiveSince        map[string]time.Time // SSID -> when a portal was last seen on it
+	failover            *failover            // nil unless switching networks; owned by the loop
+	interval            time.Duration        // current pace of checks
+	link                *linkModel           // smoothed history of the primary
+	ranking             *RankingReport       // candidates of the last failover
+	lastFailover        *FailoverResult
+	gov                 governor
 	OnMetric            func(*agentpb.NetworkMetric)
 }
 
-func (m *Monitor) Start(ctx context.Context) error {
-	ticker := time.NewTicker(m.Config.CheckInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			if err := m.checkOnce(); err != nil {
-				fmt.Println("[monitor] error:", err)
-			}
-		}
-	}
+// ToggleAutoSwitch flips SwitchAutomatically and returns its new value.
+func (m *Monitor) ToggleAutoSwitch() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SwitchAutomatically = !m.SwitchAutomatically
+	return m.SwitchAutomatically
 }
 
-func (m *Monitor) GetSnapshot() Snapshot {
+func (m *Monitor) autoSwitch() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.snapshot
+	return m.SwitchAutomatically
 }
 
-func (m *Monitor) checkOnce() error {
-	status, err := m.Wifi.GetCurrentStatus()
-	if err != nil {
-		return fmt.Errorf("get current status: %w", err)
+func (m *Monitor) clock() Clock {
+	if m.Clock == nil {
+		return realClock{}
 	}
+	return m.Clock
+}
 
-	pingRes, err := pingHost(m.Config.PingHost)
-	if err != nil {
-		fmt.Println("[monitor] ping failed:", err)
+func (m *Monitor) scorer() metrics.Scorer {
+	if m.Scorer == nil {
+		return metrics.LookupProfile(m.Config.Domain)
 	}
+	return m.Scorer
+}
 
-	var avgPing int
-	if pingRes != nil {
-		avgPing = pingRes.AvgMs
+func (m *Monitor) prober() probe.Prober {
+	if m.Prober == nil {
+		return probe.Default(nil)
 	}
+	return m.Prober
+}
 
-	score := computeScore(status.Signal, avgPing)
+// probeTimeout is the deadline each probe of a check gets.
+func (m *Monitor) probeTimeout() time.Duration {
+	if m.Config.ProbeTimeout > 0 {
+		return m.Config.ProbeTimeout
+	}
+	return 5 * time.Second
+}
 
-	m.mu.Lock()
-	m.snapshot = Snapshot{
-		SSID:        status.SSID,
-		Profile:     status.ProfileName,
-		Signal:      status.Signal,
-		AvgPingMs:   avgPing,
-		Score:       score,
-		LastUpdated: time.Now(),
+// probeDNS resolves every DNSNames entry through every DNSServers entry,
+// all at once.
+func (m *Monitor) probeDNS(ctx context.Context) *probe.DNSSummary {
+	if len(m.Config.DNSNames) == 0 {
+		return nil
+	}
+	var dns probe.DNSProber = probe.DNSClient{}
+	if m.DNS != nil {
+		dns = m.DNS
+	}
+	servers := m.Config.DNSServers
+	if len(servers) == 0 {
+		servers = []string{""}
 	}
 
-	m.mu.Unlock()
-	log.Print("profile:", m.snapshot.Profile)
-	if m.OnMetric != nil {
-		metric := &agentpb.NetworkMetric{
-			DeviceId:        status.SSID,        // or hostname / generated ID
-			UserId:          status.ProfileName, // optional
-			Domain:          "laptop",           // optional
-			TimestampUnix:   time.Now().Unix(),
-			Ssid:            status.SSID,
-			InterfaceName:   status.InterfaceName, // if available
-			SignalPercent:   int32(status.Signal),
-			AvgPingMs:       int32(avgPing),
-			ExperienceScore: int32(score),
+	results := make([]probe.DNSResult, len(servers)*len(m.Config.DNSNames))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		for j, name := range m.Config.DNSNames {
+			wg.Add(1)
+			go func(dst *probe.DNSResult, q probe.DNSQuery) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(ctx, m.probeTimeout())
+				defer cancel()
+				res := dns.Query(ctx, q)
+				*dst = *res
+			}(&results[i*len(m.Config.DNSNames)+j], probe.DNSQuery{Name: name, Server: server})
 		}
+	}
+	wg.Wait()
+	return probe.Summarize(results)
+}
 
-		m.OnMetric(metric)
-	} else {
-		log.Println("[monitor] no OnMetric handler set")
+// checkEndpoints requests every Config.Endpoints entry at once. The
+// second result reports whether a critical endpoint is unreachable.
+func (m *Monitor) checkEndpoints(ctx context.Context) ([]probe.EndpointResult, bool) {
+	var http probe.EndpointProber = probe.HTTPProber{}
+	if m.HTTP != nil {
+		http = m.HTTP
+	}
+
+	results := make([]probe.EndpointResult, len(m.Config.Endpoints))
+	var wg sync.WaitGroup
+	for i, e := range m.Config.Endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, m.probeTimeout())
+			defer cancel()
+			results[i] = *http.Check(ctx, e)
+		}()
 	}
-	badSignal := status.Signal > 0 && status.Signal < m.Config.MinSignalPercent
-	badPing := avgPing > 0 && avgPing > m.Config.MaxAvgPingMs
+	wg.Wait()
 
-	if !badSignal && !badPing {
+	criticalDown := false
+	for i, res := range results {
+		if !res.OK {
+			log.Printf("[monitor] endpoint %s failed: %s", res.Name, res.Error)
+			criticalDown = criticalDown || m.Config.Endpoints[i].Critical
+		}
+	}
+	return results, criticalDown
+}
+
+// checkCaptive reports what the primary link reaches, or nil when
+// detection is off.
+func (m *Monitor) checkCaptive(ctx context.Context) *probe.CaptiveResult {
+	if m.Captive == nil {
 		return nil
 	}
+	ctx, cancel := context.WithTimeout(ctx, m.probeTimeout())
+	defer cancel()
+	res := m.Captive.Detect(ctx)
+	if res.State != probe.StateOnline {
+		log.Printf("[monitor] connectivity: %s %s", res.State, res.Detail)
+	}
+	return res
+}
+
+// captiveRetry is how long a network that landed on a captive portal is
+// left out of failover; the user may have logged in by then.
+const captiveRetry = 30 * time.Minute
 
-	if m.SwitchAutomatically {
-		return m.tryConnectVisibleNetworks()
+func (m *Monitor) markCaptive(ssid string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.captiveSince == nil {
+		m.captiveSince = make(map[string]time.Time)
 	}
-	return m.tryFailover(status)
+	m.captiveSince[ssid] = m.clock().Now()
 }
 
-func computeScore(signal, ping int) int {
-	if signal <= 0 {
-		return 0
+// recentlyCaptive reports whether ssid put us behind a portal lately.
+func (m *Monitor) recentlyCaptive(ssid string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	seen, ok := m.captiveSince[ssid]
+	return ok && m.clock().Now().Sub(seen) < captiveRetry
+}
+
+// tracePath probes the gateway and resolvers next to the public result
+// to find how far traffic gets. It returns nil when the hops are unknown.
+func (m *Monitor) tracePath(ctx context.Context, public *probe.Result, resolveFailed, appFailed bool) *probe.PathReport {
+	if m.Path == nil {
+		return nil
 	}
-	if ping <= 0 {
-		return signal
+	dctx, cancel := context.WithTimeout(ctx, m.probeTimeout())
+	hops, err := m.Path.Discover(dctx)
+	cancel()
+	if err != nil {
+		log.Println("[monitor] discover gateway:", err)
+		return nil
+	}
+
+	report := &probe.PathReport{Public: public, ResolveFailed: resolveFailed, AppFailed: appFailed}
+	resolvers := make([]*probe.Result, len(hops.DNSServers))
+	var wg sync.WaitGroup
+	probeHop := func(host string, dst **probe.Result) {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(ctx, m.probeTimeout())
+		defer cancel()
+		res, err := m.prober().Probe(ctx, probe.Target{Host: host, Count: m.Config.ProbeCount})
+		if err != nil {
+			log.Println("[monitor] probe", host, "failed:", err)
+			return
+		}
+		*dst = res
 	}
-	penalty := ping / 5
-	if penalty > 40 {
-		penalty = 40
+	wg.Add(1 + len(hops.DNSServers))
+	go probeHop(hops.Gateway, &report.Gateway)
+	for i, server := range hops.DNSServers {
+		go probeHop(server, &resolvers[i])
+	}
+	wg.Wait()
+	for _, r := range resolvers {
+		if r != nil {
+			report.Resolvers = append(report.Resolvers, r)
+		}
+	}
+
+	report.Localize(float64(m.Config.MaxAvgPingMs))
+	return report
+}
+
+// probeLink probes Config.Targets in order and returns the first result
+// with a reply, or the last result if nothing answered.
+func (m *Monitor) probeLink(ctx context.Context) *probe.Result {
+	targets := m.Config.Targets
+	if len(targets) == 0 {
+		targets = []probe.Target{{Host: m.Config.PingHost}}
+	}
+
+	var last *probe.Result
+	for _, t := range targets {
+		if t.Count == 0 {
+			t.Count = m.Config.ProbeCount
+		}
+		res, err := m.probeTarget(ctx, t)
+		if err != nil {
+			log.Println("[monitor] probe", t.Host, "failed:", err)
+			continue
+		}
+		if res.Received > 0 {
+			return res
+		}
+		last = res
 	}
-	score := signal - penalty
-	if score < 0 {
+	return last
+}
+
+func (m *Monitor) probeTarget(ctx context.Context, t probe.Target) (*probe.Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.probeTimeout())
+	defer cancel()
+	return m.prober().Probe(ctx, t)
+}
+
+// Start checks the link until ctx is done, at the pace nextWait sets.
+// Everything a check or a failover step does is bound to ctx, so
+// cancelling it returns promptly.
+func (m *Monitor) Start(ctx context.Context) error {
+	clock := m.clock()
+	wait := m.Config.CheckInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(wait):
+		}
+		started := clock.Now()
+		var err error
+		if m.failingOver() {
+			err = m.stepFailover(ctx)
+		} else {
+			err = m.checkOnce(ctx)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Println("[monitor] error:", err)
+		}
+		// Time spent checking comes off the wait so ticks keep their pace.
+		wait = max(m.nextWait(clock.Now())-clock.Now().Sub(started), 0)
+	}
+}
+
+// nextWait is how long the loop sleeps after a check or failover step:
+// until a connecting candidate has settled, else the current pace.
+func (m *Monitor) nextWait(now time.Time) time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if f := m.failover; f != nil {
+		if f.step == failoverSettle {
+			return f.settleAt.Sub(now)
+		}
 		return 0
 	}
-	if score > 100 {
-		return 100
+	if m.interval <= 0 {
+		return m.Config.CheckInterval
+	}
+	return m.interval
+}
+
+// adapt sets the pace of checks: straight down to MinCheckInterval when
+// the primary is degraded or about to be, then up by half per healthy
+// check, starting from CheckInterval, until MaxCheckInterval.
+func (m *Monitor) adapt(atRisk bool) {
+	base := m.Config.CheckInterval
+	lo := m.Config.MinCheckInterval
+	if lo <= 0 {
+		lo = max(base/4, time.Second)
+	}
+	hi := m.Config.MaxCheckInterval
+	if hi <= 0 {
+		hi = 2 * base
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := m.interval
+	switch {
+	case atRisk:
+		m.interval = lo
+	case prev < base:
+		m.interval = base // recovering: back to the normal pace first
+	default:
+		m.interval = min(prev+prev/2, hi)
+	}
+	if atRisk && prev != lo {
+		log.Printf("[monitor] link at risk, checking every %s", lo)
+	}
+}
+
+func (m *Monitor) GetSnapshot() Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.snapshot
+}
+
+// probeSet is one round of every probe of the link.
+type probeSet struct {
+	res          *probe.Result
+	dns          *probe.DNSSummary
+	endpoints    []probe.EndpointResult
+	criticalDown bool
+	captive      *probe.CaptiveResult
+}
+
+func (ps probeSet) behindPortal() bool {
+	return ps.captive != nil && ps.captive.State == probe.StateCaptivePortal
+}
+
+// runProbes runs every probe once. They are independent, so they run side
+// by side and take as long as the slowest rather than the sum.
+func (m *Monitor) runProbes(ctx context.Context) probeSet {
+	var (
+		ps probeSet
+		wg sync.WaitGroup
+	)
+	wg.Add(4)
+	go func() { defer wg.Done(); ps.res = m.probeLink(ctx) }()
+	go func() { defer wg.Done(); ps.dns = m.probeDNS(ctx) }()
+	go func() { defer wg.Done(); ps.endpoints, ps.criticalDown = m.checkEndpoints(ctx) }()
+	go func() { defer wg.Done(); ps.captive = m.checkCaptive(ctx) }()
+	wg.Wait()
+	return ps
+}
+
+// callQuality estimates call quality from a probe burst, or nil when none
+// went out.
+func (m *Monitor) callQuality(res *probe.Result) *metrics.CallQuality {
+	if res == nil || res.Sent == 0 {
+		return nil
 	}
-	return score
+	q := metrics.EModel(metrics.LookupCodec(m.Config.Codec), res.AvgMs, res.JitterMs, res.LossPct)
+	return &q
 }
-func (m *Monitor) tryFailover(current *wifi.WifiStatus) error {
-	profiles, err := m.Wifi.ListProfiles()
+
+func (m *Monitor) checkOnce(ctx context.Context) error {
+	statuses, err := m.Wifi.GetInterfaceStatuses(ctx)
 	if err != nil {
-		return fmt.Errorf("list profiles: %w", err)
+		return fmt.Errorf("get interface statuses: %w", err)
+	}
+
+	status := m.pickPrimary(statuses)
+	if status == nil {
+		return fmt.Errorf("no active Wi-Fi interface found")
 	}
 
-	for _, preferredName := range m.Config.PreferredProfiles {
+	// Under a speedtest or bufferbloat test the ping measures the test,
+	// not the network: the check is reported but not judged.
+	loaded := m.loadTesting()
+	ps := m.runProbes(ctx)
+	if err := ctx.Err(); err != nil {
+		return err // half-finished probes say nothing about the link
+	}
+	loaded = loaded || m.loadTesting()
+	probeRes, dns, endpoints, criticalDown, captive := ps.res, ps.dns, ps.endpoints, ps.criticalDown, ps.captive
+	behindPortal := ps.behindPortal()
 
-		if preferredName == current.ProfileName || preferredName == current.SSID {
-			continue
+	var avgPing int
+	var jitter, loss float64
+	if probeRes != nil {
+		loss = probeRes.LossPct
+		if probeRes.HasRTT() {
+			avgPing = int(math.Round(probeRes.AvgMs))
+			jitter = probeRes.JitterMs
 		}
+	}
+
+	call := m.callQuality(probeRes)
+
+	// A throughput result only describes the link it was measured on.
+	m.mu.RLock()
+	throughput := m.throughput
+	if throughput != nil && (throughput.Interface != status.InterfaceName || throughput.SSID != status.SSID) {
+		throughput = nil
+	}
+	bloat := m.bufferbloat
+	if bloat != nil && (bloat.Interface != status.InterfaceName || bloat.SSID != status.SSID) {
+		bloat = nil
+	}
+	m.mu.RUnlock()
+
+	// The primary carries the probes, so it alone is judged on them:
+	// signal, ping and call quality smoothed over checks, the rest as
+	// they are, since a broken resolver or portal is not noise.
+	now := m.clock().Now()
+	breakdown := m.scorer().Score(scoreInputs(status, probeRes, dns, throughput))
+	var quality *Quality
+	var reasons []string
+	var prediction *Prediction
+	if loaded {
+		quality, reasons = m.held(status)
+		prediction = m.predicted(status)
+	} else {
+		quality, reasons = m.observe(status, probeRes, call, breakdown.Score, now)
+		prediction = m.predict(now)
+	}
+	if dns.Degraded() {
+		reasons = append(reasons, ReasonDNS)
+	}
+	if criticalDown {
+		reasons = append(reasons, ReasonEndpoint)
+	}
+	if behindPortal {
+		reasons = append(reasons, ReasonCaptivePortal)
+	}
 
-		p := wifi.FindProfileByCleanName(profiles, preferredName)
-		if p == nil {
+	// The ping goes out over whichever adapter owns the default route, which
+	// is taken to be the primary; the other adapters are scored on signal only.
+	var links []InterfaceSnapshot
+	var primary InterfaceSnapshot
+	for _, st := range statuses {
+		if st != status {
+			link := newInterfaceSnapshot(st, m.scorer().Score(metrics.Inputs{Signal: st.Signal}))
+			link.Degraded = st.SSID != "" && m.isBad(st.Signal, 0, 0, nil)
+			links = append(links, link)
 			continue
 		}
+		link := newInterfaceSnapshot(st, breakdown)
+		link.Degraded = len(reasons) > 0
+		if behindPortal {
+			// Strong signal is worth nothing until someone logs in.
+			link.Score, link.Breakdown.Score = 0, 0
+		}
+		links = append(links, link)
+		primary = link
+	}
 
-		fmt.Println("[monitor] attempting switch to:", p.CleanName)
-		if err := m.Wifi.Connect(*p); err != nil {
-			fmt.Println("[monitor] connect failed:", err)
-			continue
+	// Failing over only helps when the fault is the Wi-Fi network itself.
+	fault := probe.FaultNone
+	var path *probe.PathReport
+	if primary.Degraded {
+		switch {
+		case behindPortal, slices.Contains(reasons, ReasonWeakSignal):
+			// A portal or a weak signal belongs to this SSID; another one avoids it.
+			fault = probe.FaultLocalLink
+		default:
+			fault = probe.FaultUnknown
+			if path = m.tracePath(ctx, probeRes, dns.Degraded(), criticalDown); path != nil {
+				fault = path.Fault
+			}
 		}
+	}
 
-		time.Sleep(7 * time.Second)
+	// Nor does a loaded check open events or count against the network
+	// in History.
+	var event *DegradationEvent
+	if !loaded {
+		event = m.trackDegradation(ctx, status, reasons, fault, now)
+		m.rememberCheck(status, primary.Score, primary.Degraded, now)
+	}
+	gov := m.governorState(now)
+	m.adapt(primary.Degraded || quality.Pending != nil || prediction != nil)
 
-		newStatus, err := m.Wifi.GetCurrentStatus()
-		if err != nil {
-			fmt.Println("[monitor] after-switch status error:", err)
-			continue
-		}
+	m.mu.Lock()
+	m.primary = status.InterfaceName
+	m.snapshot = Snapshot{
+		InterfaceSnapshot: primary,
+		AvgPingMs:         avgPing,
+		JitterMs:          jitter,
+		PacketLossPct:     loss,
+		Probe:             probeRes,
+		Throughput:        throughput,
+		Bufferbloat:       bloat,
+		DNS:               dns,
+		Endpoints:         endpoints,
+		CallQuality:       call,
+		Fault:             fault,
+		Path:              path,
+		Degradation:       m.event,
+		Quality:           quality,
+		Prediction:        prediction,
+		LastFailover:      m.lastFailover,
+		Governor:          gov,
+		Interfaces:        links,
+		LastUpdated:       now,
+	}
+	if captive != nil {
+		m.snapshot.Connectivity, m.snapshot.PortalURL = captive.State, captive.PortalURL
+	}
+	m.mu.Unlock()
 
-		fmt.Println("[monitor] after-switch:", wifi.DebugStatus(newStatus))
+	if behindPortal {
+		log.Printf("[monitor] %s is behind a captive portal; log in to get online %s", status.SSID, captive.PortalURL)
+		m.markCaptive(status.SSID)
+	}
 
-		if newStatus.ProfileName == p.RawName || newStatus.SSID == p.CleanName {
-			if newStatus.Signal >= m.Config.MinSignalPercent {
-				fmt.Println("[monitor] failover successful 🎉")
-				return nil
+	if m.OnMetric != nil {
+		device := m.deviceID()
+		for i, st := range statuses {
+			if st.SSID == "" {
+				continue // adapter not associated; nothing to report
 			}
+			metric := newMetric(device, st, nil, nil, links[i].Score, now)
+			if st == status {
+				metric = newMetric(device, st, probeRes, throughput, links[i].Score, now)
+				addDNS(metric, dns)
+				addEndpoints(metric, endpoints)
+				if captive != nil {
+					metric.Connectivity, metric.PortalUrl = captive.State, captive.PortalURL
+				}
+				addPath(metric, fault, path)
+				addBufferbloat(metric, bloat)
+				addEvent(metric, event)
+				addBreakdown(metric, links[i].Breakdown)
+				addPrediction(metric, prediction)
+				addGovernor(metric, gov)
+				if call != nil {
+					metric.RFactor, metric.Mos, metric.Codec = float32(call.RFactor), float32(call.MOS), call.Codec
+				}
+			}
+			if m.Config.Domain != "" {
+				metric.Domain = m.Config.Domain
+			}
+			m.OnMetric(metric)
 		}
+	} else {
+		log.Println("[monitor] no OnMetric handler set")
 	}
 
-	return fmt.Errorf("no suitable alternative profile found or all failed")
+	if m.speedtestDue(now) {
+		go func() {
+			if _, err := m.RunSpeedtest(ctx); err != nil && ctx.Err() == nil {
+				log.Println("[monitor] speedtest failed:", err)
+			}
+		}()
+	} else if m.bufferbloatDue(now) {
+		go func() {
+			if _, err := m.RunBufferbloat(ctx); err != nil && ctx.Err() == nil {
+				log.Println("[monitor] bufferbloat test failed:", err)
+			}
+		}()
+	}
+
+	if loaded {
+		return nil
+	}
+	if !primary.Degraded {
+		if m.Config.FailoverOnPrediction && prediction != nil && prediction.Metric == metrics.FactorSignal &&
+			prediction.InSeconds <= m.predictLeadTime().Seconds() {
+			log.Printf("[monitor] %s predicted to degrade in %.0fs; failing over early", status.SSID, prediction.InSeconds)
+			return m.beginFailover(ctx, status)
+		}
+		return nil
+	}
+	if fault != probe.FaultLocalLink && fault != probe.FaultUnknown {
+		log.Printf("[monitor] fault is %s, not the Wi-Fi link; staying on %s", fault, status.SSID)
+		return nil
+	}
+	return m.beginFailover(ctx, status)
+}
+
+// isBad judges a single reading of a link against the enter thresholds.
+func (m *Monitor) isBad(signal, ping int, loss float64, call *metrics.CallQuality) bool {
+	return len(m.problemsAt(m.enterThresholds(), float64(signal), float64(ping), loss, call)) > 0
 }
-func pingHost(host string) (*wifi.SimplePingResult, error) {
-	cmd := exec.Command("ping", "-n", "3", host)
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("ping failed: %v | stderr: %s", err, stderr.String())
+// problemsAt judges a link by signal and packet loss and, for voice and
+// video domains, by call quality where it was measured; other domains go
+// by ping.
+func (m *Monitor) problemsAt(th thresholds, signal, ping, loss float64, call *metrics.CallQuality) []string {
+	var reasons []string
+	if signal > 0 && signal < float64(th.signal) {
+		reasons = append(reasons, ReasonWeakSignal)
+	}
+	if loss > th.lossPct {
+		reasons = append(reasons, ReasonPacketLoss)
+	}
+	if voiceDomain(m.Config.Domain) {
+		if call != nil && call.MOS < th.mos {
+			reasons = append(reasons, ReasonLowMOS)
+		}
+		return reasons
+	}
+	if ping > 0 && ping > th.pingMs {
+		reasons = append(reasons, ReasonHighPing)
 	}
+	return reasons
+}
 
-	res := wifi.ParsePingOutput(out.String())
-	if res == nil {
-		return nil, fmt.Errorf("could not parse ping output")
+// newMetric reports one adapter of device; res and tp are the link probe
+// and the latest throughput test, nil for adapters they did not run on.
+func newMetric(device string, status *wifi.WifiStatus, res *probe.Result, tp *speedtest.Result, score int, ts time.Time) *agentpb.NetworkMetric {
+	metric := &agentpb.NetworkMetric{
+		DeviceId:        device,
+		UserId:          status.ProfileName, // optional
+		Domain:          "laptop",           // optional
+		TimestampUnix:   ts.Unix(),
+		Ssid:            status.SSID,
+		InterfaceName:   status.InterfaceName,
+		SignalPercent:   int32(status.Signal),
+		ExperienceScore: int32(score),
+		Bssid:           status.BSSID,
+		ConnectionState: status.State,
+		RadioType:       status.RadioType,
+		Channel:         int32(status.Channel),
+		Band:            status.Band,
+		RxRateMbps:      float32(status.ReceiveRateMbps),
+		TxRateMbps:      float32(status.TransmitRateMbps),
+		Authentication:  status.Authentication,
+		Cipher:          status.Cipher,
+		RssiDbm:         int32(status.RSSI),
 	}
-	return res, nil
+	if res != nil {
+		metric.PacketLossPct = float32(res.LossPct)
+		if res.HasRTT() {
+			metric.AvgPingMs = int32(math.Round(res.AvgMs))
+			metric.JitterMs = int32(math.Round(res.JitterMs))
+		}
+	}
+	if tp != nil {
+		metric.DownMbps = float32(tp.DownMbps)
+		metric.UpMbps = float32(tp.UpMbps)
+	}
+	return metric
 }
 
-func extractSSIDs(output string) []wifi.WifiProfile {
-	var ssids []wifi.WifiProfile
+func addDNS(metric *agentpb.NetworkMetric, dns *probe.DNSSummary) {
+	if dns == nil {
+		return
+	}
+	metric.DnsLatencyMs = float32(dns.AvgLatencyMs)
+	metric.DnsQueries = int32(dns.Queries)
+	metric.DnsFailures = int32(dns.Failures)
+	metric.DnsNxdomain = int32(dns.NXDomain)
+	metric.DnsServfail = int32(dns.ServFail)
+	metric.DnsTimeouts = int32(dns.Timeouts)
+}
 
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+func addEndpoints(metric *agentpb.NetworkMetric, results []probe.EndpointResult) {
+	for _, r := range results {
+		metric.Endpoints = append(metric.Endpoints, &agentpb.EndpointResult{
+			Name:      r.Name,
+			Url:       r.URL,
+			Status:    int32(r.Status),
+			DnsMs:     float32(r.DNSMs),
+			ConnectMs: float32(r.ConnectMs),
+			TlsMs:     float32(r.TLSMs),
+			TtfbMs:    float32(r.TTFBMs),
+			TotalMs:   float32(r.TotalMs),
+			Ok:        r.OK,
+			Critical:  r.Critical,
+			Error:     r.Error,
+		})
+	}
+}
 
-		// Match: SSID X : NAME
-		if strings.HasPrefix(line, "SSID ") && strings.Contains(line, " : ") {
-			parts := strings.SplitN(line, " : ", 2)
-			if len(parts) == 2 {
-				name := strings.TrimSpace(parts[1])
-				if name != "" {
-					ssids = append(ssids, wifi.WifiProfile{
-						CleanName: name,
-						RawName:   name,
-					})
-				}
-			}
-		}
+func addPath(metric *agentpb.NetworkMetric, fault string, path *probe.PathReport) {
+	metric.FaultLocation = fault
+	if path != nil && path.Gateway != nil && path.Gateway.Received > 0 {
+		metric.GatewayPingMs = float32(path.Gateway.AvgMs)
 	}
+}
+
+// loadTesting reports whether a speedtest or bufferbloat test is loading
+// the link.
+func (m *Monitor) loadTesting() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.loadTestRunning
+}
 
-	return ssids
+func (m *Monitor) speedtestDue(now time.Time) bool {
+	if m.Speedtest == nil || m.Config.SpeedtestInterval <= 0 {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.loadTestRunning && !m.inExam(now) && now.Sub(m.lastSpeedtest) >= m.Config.SpeedtestInterval
 }
 
-func (m *Monitor) tryConnectVisibleNetworks() error {
-	output, err := exec.Command(
-		"netsh", "wlan", "show", "networks", "mode=bssid",
-	).Output()
-	if err != nil {
-		return err
+// RunSpeedtest measures throughput on the current link now. The result is
+// shown in the snapshot and sent with the metrics until the link changes.
+// It refuses to run during an exam window, even on demand.
+func (m *Monitor) RunSpeedtest(ctx context.Context) (*speedtest.Result, error) {
+	if m.Speedtest == nil {
+		return nil, errors.New("speedtest not configured")
 	}
 
-	visible := extractSSIDs(string(output))
+	m.mu.Lock()
+	now := m.clock().Now()
+	if m.inExam(now) {
+		m.mu.Unlock()
+		return nil, errInExam
+	}
+	if m.loadTestRunning {
+		m.mu.Unlock()
+		return nil, errors.New("a link test is already running")
+	}
+	m.loadTestRunning = true
+	m.lastSpeedtest = now
+	iface, ssid := m.snapshot.Interface, m.snapshot.SSID
+	m.mu.Unlock()
 
-	savedProfiles, err := m.Wifi.ListProfiles()
+	res, err := m.Speedtest.Run(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loadTestRunning = false
 	if err != nil {
-		return err
+		return nil, err
 	}
+	res.At = m.clock().Now()
+	res.Interface, res.SSID = iface, ssid
+	m.throughput = res
+	if m.snapshot.Interface == iface && m.snapshot.SSID == ssid {
+		m.snapshot.Throughput = res
+	}
+	return res, nil
+}
 
-	// Build lookup for saved profiles
-	savedByName := make(map[string]wifi.WifiProfile)
-	for _, p := range savedProfiles {
-		savedByName[p.CleanName] = p
+func (m *Monitor) bufferbloatDue(now time.Time) bool {
+	if m.Bufferbloat == nil || m.Config.BufferbloatInterval <= 0 {
+		return false
 	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.loadTestRunning && !m.inExam(now) && now.Sub(m.lastBufferbloat) >= m.Config.BufferbloatInterval
+}
 
-	log.Println("[agent] visible SSIDs:")
-	for _, v := range visible {
-		log.Println(" -", v.CleanName)
+// RunBufferbloat measures how much latency the current link adds under
+// load. It refuses to run during an exam window, even on demand.
+func (m *Monitor) RunBufferbloat(ctx context.Context) (*speedtest.BufferbloatResult, error) {
+	if m.Bufferbloat == nil {
+		return nil, errors.New("bufferbloat test not configured")
 	}
-	for _, v := range visible {
-		// Check if visible SSID has a saved profile
-		p, ok := savedByName[v.CleanName]
-		if !ok {
-			log.Println("[agent] visible SSID has no saved profile:", v.CleanName)
-			continue // visible but no credentials
-		}
 
-		// Skip current connection
-		if p.CleanName == m.snapshot.Profile {
-			log.Println("[agent] already connected to:", p.RawName)
-			continue
-		}
+	m.mu.Lock()
+	now := m.clock().Now()
+	if m.inExam(now) {
+		m.mu.Unlock()
+		return nil, errInExam
+	}
+	if m.loadTestRunning {
+		m.mu.Unlock()
+		return nil, errors.New("a link test is already running")
+	}
+	m.loadTestRunning = true
+	m.lastBufferbloat = now
+	iface, ssid := m.snapshot.Interface, m.snapshot.SSID
+	m.mu.Unlock()
 
-		log.Println("[agent] attempting connect to:", p.RawName)
+	res, err := m.Bufferbloat.Bufferbloat(ctx)
 
-		if err := m.Wifi.Connect(p); err != nil {
-			log.Println("[monitor] connect failed:", err)
-			continue
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loadTestRunning = false
+	if err != nil {
+		return nil, err
+	}
+	res.At = m.clock().Now()
+	res.Interface, res.SSID = iface, ssid
+	m.bufferbloat = res
+	if m.snapshot.Interface == iface && m.snapshot.SSID == ssid {
+		m.snapshot.Bufferbloat = res
+	}
+	return res, nil
+}
+
+var errInExam = errors.New("not loading the link during an exam")
+
+// inExam reports whether t falls in one of Config.ExamWindows.
+func (m *Monitor) inExam(t time.Time) bool {
+	for _, w := range m.Config.ExamWindows {
+		if w.Contains(t) {
+			return true
 		}
+	}
+	return false
+}
 
-		log.Println("[agent] connected to:", p.RawName)
-		return nil
+func addBufferbloat(metric *agentpb.NetworkMetric, b *speedtest.BufferbloatResult) {
+	if b == nil {
+		return
 	}
+	metric.BufferbloatIdleMs = float32(b.IdleMs)
+	metric.BufferbloatLoadedMs = float32(max(b.LoadedDownMs, b.LoadedUpMs))
+	metric.BufferbloatDeltaMs = float32(b.DeltaMs)
+	metric.BufferbloatGrade = b.Grade
+}
 
-	return nil
+// scoreInputs gathers what the primary link is scored on.
+func scoreInputs(st *wifi.WifiStatus, res *probe.Result, dns *probe.DNSSummary, tp *speedtest.Result) metrics.Inputs {
+	in := metrics.Inputs{Signal: st.Signal}
+	if res != nil && res.Sent > 0 {
+		in.Probed, in.RTTMs, in.JitterMs, in.LossPct = true, res.AvgMs, res.JitterMs, res.LossPct
+	}
+	if dns != nil {
+		in.DNSQueries, in.DNSFailures, in.DNSMs = dns.Queries, dns.Failures, dns.AvgLatencyMs
+	}
+	if tp != nil {
+		in.DownMbps, in.UpMbps = tp.DownMbps, tp.UpMbps
+	}
+	return in
+}
+
+func addBreakdown(metric *agentpb.NetworkMetric, b metrics.Breakdown) {
+	metric.ScoreProfile = b.Profile
+	for _, f := range b.Factors {
+		metric.ScoreFactors = append(metric.ScoreFactors, &agentpb.ScoreFactor{
+			Name:   f.Name,
+			Value:  float32(f.Value),
+			Score:  float32(f.Score),
+			Weight: float32(f.Weight),
+		})
+	}
 }
@@ -0,0 +1,198 @@
+package monitor
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"netshield/agent/internal/metrics"
+	"netshield/agent/internal/wifi"
+	agentpb "netshield/agent/proto"
+)
+
+// EventDegradationPredicted names a Prediction wherever it is reported.
+const EventDegradationPredicted = "degradation_predicted"
+
+// A trend is only trusted once it spans enough checks and the line
+// explains most of their spread; a few noisy samples fit any slope.
+const (
+	minTrendSamples = 5
+	minTrendR2      = 0.6
+)
+
+// Prediction is a degradation_predicted event: the primary link is still
+// healthy but its signal or RTT is heading for the threshold, e.g. as a
+// user walks away from the AP.
+type Prediction struct {
+	Event       string    `json:"event"`
+	Metric      string    `json:"metric"` // metrics.FactorSignal or metrics.FactorLatency
+	SSID        string    `json:"ssid"`
+	Interface   string    `json:"interface"`
+	Current     float64   `json:"current"` // fitted value now
+	Threshold   float64   `json:"threshold"`
+	SlopePerMin float64   `json:"slope_per_min"`
+	InSeconds   float64   `json:"seconds_to_threshold"`
+	R2          float64   `json:"r2"`
+	Since       time.Time `json:"since"` // when the trend was first seen
+}
+
+// trendSample is one check of the primary as the trend sees it; rtt is 0
+// when nothing answered the probe.
+type trendSample struct {
+	at          time.Time
+	signal, rtt float64
+}
+
+func (m *Monitor) trendWindow() time.Duration {
+	if m.Config.TrendWindow > 0 {
+		return m.Config.TrendWindow
+	}
+	return 2 * time.Minute
+}
+
+func (m *Monitor) predictHorizon() time.Duration {
+	if m.Config.PredictHorizon > 0 {
+		return m.Config.PredictHorizon
+	}
+	return 2 * time.Minute
+}
+
+func (m *Monitor) predictLeadTime() time.Duration {
+	if m.Config.PredictLeadTime > 0 {
+		return m.Config.PredictLeadTime
+	}
+	return 30 * time.Second
+}
+
+// remember adds a check to the model's trend history and drops what has
+// fallen out of TrendWindow. Caller holds m.mu.
+func (m *Monitor) remember(l *linkModel, s trendSample) {
+	l.history = append(l.history, s)
+	cut := 0
+	for cut < len(l.history) && s.at.Sub(l.history[cut].at) > m.trendWindow() {
+		cut++
+	}
+	l.history = l.history[cut:]
+}
+
+// predict fits a line through the primary's recent signal and RTT and
+// returns the one due to cross its enter threshold soonest within
+// PredictHorizon, or nil. Voice and video domains are not judged on ping,
+// so only their signal is followed. A link already degraded has nothing
+// left to predict.
+func (m *Monitor) predict(now time.Time) *Prediction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.link
+	if l == nil {
+		return nil
+	}
+
+	var best *Prediction
+	if !l.degraded {
+		th := m.enterThresholds()
+		trends := []*Prediction{
+			m.trend(l, metrics.FactorSignal, float64(th.signal), now, func(s trendSample) float64 { return s.signal }),
+		}
+		if !voiceDomain(m.Config.Domain) {
+			trends = append(trends, m.trend(l, metrics.FactorLatency, th.pingMs, now, func(s trendSample) float64 { return s.rtt }))
+		}
+		for _, p := range trends {
+			if p != nil && (best == nil || p.InSeconds < best.InSeconds) {
+				best = p
+			}
+		}
+	}
+
+	prev := l.predicted
+	switch {
+	case best == nil && prev != nil:
+		log.Printf("[monitor] %s no longer predicted to degrade", l.key)
+	case best != nil && prev != nil && prev.Metric == best.Metric:
+		best.Since = prev.Since
+	case best != nil:
+		log.Printf("[monitor] %s on %s: %s at %.0f heading for %.0f in %s",
+			EventDegradationPredicted, l.key, best.Metric, best.Current, best.Threshold,
+			time.Duration(best.InSeconds*float64(time.Second)).Round(time.Second))
+	}
+	l.predicted = best
+	if best == nil {
+		return nil
+	}
+	p := *best
+	return &p
+}
+
+// predicted returns the primary's open prediction without refitting the
+// trend, for checks taken while a load test owns the link.
+func (m *Monitor) predicted(status *wifi.WifiStatus) *Prediction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.link
+	if l == nil || l.key != status.InterfaceName+"/"+status.SSID || l.predicted == nil {
+		return nil
+	}
+	p := *l.predicted
+	return &p
+}
+
+// trend fits value over the history and returns a prediction when the
+// line reaches threshold within the horizon. Signal crosses going down,
+// RTT going up. Caller holds m.mu.
+func (m *Monitor) trend(l *linkModel, metric string, threshold float64, now time.Time, value func(trendSample) float64) *Prediction {
+	var xs, ys []float64
+	for _, s := range l.history {
+		if v := value(s); v > 0 {
+			xs = append(xs, s.at.Sub(now).Seconds()) // now is x = 0
+			ys = append(ys, v)
+		}
+	}
+	if len(xs) < minTrendSamples || threshold <= 0 {
+		return nil
+	}
+	fit := metrics.LinearFit(xs, ys)
+	if fit.R2 < minTrendR2 || fit.Slope == 0 {
+		return nil
+	}
+
+	current := fit.At(0)
+	falling := metric == metrics.FactorSignal
+	if falling != (fit.Slope < 0) {
+		return nil // moving away from the threshold
+	}
+	if (falling && current <= threshold) || (!falling && current >= threshold) {
+		return nil // already across; the smoothed values will catch up
+	}
+	in := (threshold - current) / fit.Slope
+	if in > m.predictHorizon().Seconds() {
+		return nil
+	}
+
+	return &Prediction{
+		Event:       EventDegradationPredicted,
+		Metric:      metric,
+		SSID:        l.ssid,
+		Interface:   l.iface,
+		Current:     round1(current),
+		Threshold:   threshold,
+		SlopePerMin: round1(fit.Slope * 60),
+		InSeconds:   round1(in),
+		R2:          math.Round(fit.R2*100) / 100,
+		Since:       now,
+	}
+}
+
+func addPrediction(metric *agentpb.NetworkMetric, p *Prediction) {
+	if p == nil {
+		return
+	}
+	metric.Prediction = &agentpb.DegradationPrediction{
+		Metric:             p.Metric,
+		Current:            float32(p.Current),
+		Threshold:          float32(p.Threshold),
+		SlopePerMin:        float32(p.SlopePerMin),
+		SecondsToThreshold: float32(p.InSeconds),
+		R2:                 float32(p.R2),
+		SinceUnix:          p.Since.Unix(),
+	}
+}
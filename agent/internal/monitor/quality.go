@@ -0,0 +1,254 @@
+package monitor
+
+import (
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"netshield/agent/internal/metrics"
+	"netshield/agent/internal/probe"
+	"netshield/agent/internal/wifi"
+)
+
+// Quality is the smoothed view of the primary link that degradation and
+// failover are decided on. Percentiles are over the last WindowSize
+// checks; the EWMAs weigh recent checks most.
+type Quality struct {
+	Samples    int     `json:"samples"`
+	SignalEWMA float64 `json:"signal_ewma"`
+	SignalP10  float64 `json:"signal_p10"` // the weak end of the window
+	RTTEWMA    float64 `json:"rtt_ewma_ms"`
+	RTTP50     float64 `json:"rtt_p50_ms"`
+	RTTP90     float64 `json:"rtt_p90_ms"`
+	JitterEWMA float64 `json:"jitter_ewma_ms"`
+	LossEWMA   float64 `json:"loss_ewma_pct"`
+	MOSEWMA    float64 `json:"mos_ewma,omitempty"` // voice domains only
+	ScoreEWMA  float64 `json:"score_ewma"`
+	Degraded   bool    `json:"degraded"`
+	// DegradedSince is set while Degraded, HealthySince otherwise; both
+	// are unset until the state first changes on this network.
+	DegradedSince *time.Time `json:"degraded_since,omitempty"`
+	HealthySince  *time.Time `json:"healthy_since,omitempty"`
+	// Pending is when the smoothed values crossed into the other state;
+	// the state flips once they have stayed there for MinDwell.
+	Pending *time.Time `json:"pending_since,omitempty"`
+}
+
+// thresholds are the limits a link is judged against.
+type thresholds struct {
+	signal  int     // percent; below is weak
+	pingMs  float64 // above is high
+	lossPct float64 // above is lossy
+	mos     float64 // below is low; voice domains only
+}
+
+// enterThresholds turn a healthy link degraded.
+func (m *Monitor) enterThresholds() thresholds {
+	minMOS := m.Config.MinMOS
+	if minMOS <= 0 {
+		minMOS = 3.6
+	}
+	maxLoss := m.Config.MaxLossPct
+	if maxLoss <= 0 {
+		maxLoss = 20
+	}
+	return thresholds{signal: m.Config.MinSignalPercent, pingMs: float64(m.Config.MaxAvgPingMs), lossPct: maxLoss, mos: minMOS}
+}
+
+// exitThresholds must be met before a degraded link counts as healthy
+// again, so a link hovering at the limit does not flap.
+func (m *Monitor) exitThresholds() thresholds {
+	th := m.enterThresholds()
+	if m.Config.ExitSignalPercent > 0 {
+		th.signal = m.Config.ExitSignalPercent
+	} else {
+		th.signal += 10
+	}
+	if m.Config.ExitAvgPingMs > 0 {
+		th.pingMs = float64(m.Config.ExitAvgPingMs)
+	} else {
+		th.pingMs *= 0.8
+	}
+	if m.Config.ExitLossPct > 0 {
+		th.lossPct = m.Config.ExitLossPct
+	} else {
+		th.lossPct /= 4
+	}
+	if m.Config.ExitMOS > 0 {
+		th.mos = m.Config.ExitMOS
+	} else {
+		th.mos += 0.2
+	}
+	return th
+}
+
+func (m *Monitor) minDwell() time.Duration {
+	if m.Config.MinDwell > 0 {
+		return m.Config.MinDwell
+	}
+	return 10 * time.Second
+}
+
+// linkModel smooths the primary link's samples and holds its state.
+type linkModel struct {
+	key                            string // interface and SSID it describes
+	iface, ssid                    string
+	signal, rtt, jitter, loss, mos metrics.Series
+	score                          metrics.Series
+	degraded                       bool
+	since                          time.Time     // when the current state began
+	pending                        time.Time     // when the smoothed values crossed over; zero if they have not
+	reasons                        []string      // why it is degraded
+	history                        []trendSample // the last TrendWindow of checks
+	predicted                      *Prediction   // open degradation_predicted event
+}
+
+func newLinkModel(key string, alpha float64, size int) *linkModel {
+	l := &linkModel{key: key}
+	for _, s := range []*metrics.Series{&l.signal, &l.rtt, &l.jitter, &l.loss, &l.mos, &l.score} {
+		s.Alpha, s.Size = alpha, size
+	}
+	return l
+}
+
+// observe adds a check of the primary link to its model and returns the
+// smoothed view and, while it is degraded, why. A different network or
+// adapter starts a fresh model.
+func (m *Monitor) observe(status *wifi.WifiStatus, res *probe.Result, call *metrics.CallQuality, score int, now time.Time) (*Quality, []string) {
+	key := status.InterfaceName + "/" + status.SSID
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.link
+	if l == nil || l.key != key {
+		l = newLinkModel(key, m.Config.SmoothingAlpha, m.Config.WindowSize)
+		l.iface, l.ssid = status.InterfaceName, status.SSID
+		m.link = l
+	}
+
+	l.signal.Add(float64(status.Signal))
+	l.score.Add(float64(score))
+	if res != nil && res.Sent > 0 {
+		l.loss.Add(res.LossPct)
+		if res.HasRTT() {
+			l.rtt.Add(res.AvgMs)
+			l.jitter.Add(res.JitterMs)
+		}
+	}
+	if call != nil {
+		l.mos.Add(call.MOS)
+	}
+	sample := trendSample{at: now, signal: float64(status.Signal)}
+	if res != nil && res.HasRTT() {
+		sample.rtt = res.AvgMs
+	}
+	m.remember(l, sample)
+
+	// A healthy link is judged by the enter thresholds, a degraded one by
+	// the stricter exit thresholds.
+	th := m.enterThresholds()
+	if l.degraded {
+		th = m.exitThresholds()
+	}
+	if bad := len(m.smoothedProblems(l, th)) > 0; bad != l.degraded {
+		m.dwell(l, now)
+	} else {
+		l.pending = time.Time{}
+	}
+	if l.degraded {
+		// Still bad by the enter thresholds says why; otherwise it is on
+		// its way back and the reasons it went bad for still stand.
+		if r := m.smoothedProblems(l, m.enterThresholds()); len(r) > 0 {
+			l.reasons = r
+		}
+	}
+	return m.view(l)
+}
+
+// held returns the smoothed view of the primary link without adding a
+// check to its model, for checks taken while a speedtest or bufferbloat
+// test loads the link. A link with no model yet has no view.
+func (m *Monitor) held(status *wifi.WifiStatus) (*Quality, []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.link
+	if l == nil || l.key != status.InterfaceName+"/"+status.SSID {
+		return nil, nil
+	}
+	return m.view(l)
+}
+
+// view is the model's smoothed values and, while it is degraded, why.
+// Caller holds m.mu.
+func (m *Monitor) view(l *linkModel) (*Quality, []string) {
+	q := &Quality{
+		Samples:    l.signal.Len(),
+		SignalEWMA: round1(l.signal.EWMA()),
+		SignalP10:  round1(l.signal.Percentile(10)),
+		RTTEWMA:    round1(l.rtt.EWMA()),
+		RTTP50:     round1(l.rtt.Percentile(50)),
+		RTTP90:     round1(l.rtt.Percentile(90)),
+		JitterEWMA: round1(l.jitter.EWMA()),
+		LossEWMA:   round1(l.loss.EWMA()),
+		ScoreEWMA:  round1(l.score.EWMA()),
+		Degraded:   l.degraded,
+	}
+	if voiceDomain(m.Config.Domain) {
+		q.MOSEWMA = math.Round(l.mos.EWMA()*100) / 100
+	}
+	if !l.since.IsZero() {
+		since := l.since
+		if l.degraded {
+			q.DegradedSince = &since
+		} else {
+			q.HealthySince = &since
+		}
+	}
+	if !l.pending.IsZero() {
+		pending := l.pending
+		q.Pending = &pending
+	}
+	if !l.degraded {
+		return q, nil
+	}
+	return q, slices.Clone(l.reasons)
+}
+
+// dwell flips the state once the smoothed values have stayed on the other
+// side for MinDwell. Caller holds m.mu.
+func (m *Monitor) dwell(l *linkModel, now time.Time) {
+	if l.pending.IsZero() {
+		l.pending = now
+	}
+	if now.Sub(l.pending) < m.minDwell() {
+		return
+	}
+	l.degraded, l.since, l.pending = !l.degraded, now, time.Time{}
+	if l.degraded {
+		l.reasons = m.smoothedProblems(l, m.enterThresholds())
+		log.Printf("[monitor] %s degraded by smoothed quality: %v", l.key, l.reasons)
+	} else {
+		l.reasons = nil
+		log.Printf("[monitor] %s healthy again", l.key)
+	}
+}
+
+// smoothedProblems judges a model's EWMAs against th.
+func (m *Monitor) smoothedProblems(l *linkModel, th thresholds) []string {
+	var call *metrics.CallQuality
+	if l.mos.Len() > 0 {
+		call = &metrics.CallQuality{MOS: l.mos.EWMA()}
+	}
+	var rtt float64
+	if l.rtt.Len() > 0 {
+		rtt = l.rtt.EWMA()
+	}
+	var loss float64
+	if l.loss.Len() > 0 {
+		loss = l.loss.EWMA()
+	}
+	return m.problemsAt(th, l.signal.EWMA(), rtt, loss, call)
+}
+
+func round1(v float64) float64 { return math.Round(v*10) / 10 }
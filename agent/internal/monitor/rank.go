@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"netshield/agent/internal/history"
+	"netshield/agent/internal/metrics"
+	"netshield/agent/internal/wifi"
+)
+
+// historyWeight is how many checks of history weigh as much as the live
+// signal; a network seen a handful of times is ranked mostly on signal.
+const historyWeight = 20
+
+// Ranking is one failover candidate and why it placed where it did.
+type Ranking struct {
+	SSID      string              `json:"ssid"`
+	BSSID     string              `json:"bssid,omitempty"` // strongest access point in the scan
+	Interface string              `json:"interface"`
+	Signal    int                 `json:"signal_percent"` // from the scan; 0 when not seen
+	History   history.Expectation `json:"history"`
+	Expected  float64             `json:"expected_score"` // 0-100
+	Why       string              `json:"why"`
+}
+
+// RankingReport is the order candidates were, or would be, tried in.
+type RankingReport struct {
+	At         time.Time `json:"at"`
+	From       string    `json:"from"`             // network being left
+	Chosen     string    `json:"chosen,omitempty"` // the one a failover settled on
+	Candidates []Ranking `json:"candidates"`
+}
+
+// seenAs returns the strongest BSSID the scan shows for ssid on iface.
+func seenAs(visible []wifi.VisibleNetwork, ssid, iface string) (signal int, bssid string) {
+	for _, v := range visible {
+		if strings.TrimSpace(v.SSID) != ssid || (iface != "" && v.InterfaceName != "" && v.InterfaceName != iface) {
+			continue
+		}
+		for _, b := range v.BSSIDs {
+			if b.Signal > signal {
+				signal, bssid = b.Signal, b.BSSID
+			}
+		}
+	}
+	return signal, bssid
+}
+
+// rank scores each candidate on what its live signal is worth, blended
+// with what history says of it at this time of day, and weighed by how
+// often connecting to it has worked. History cannot vouch for a network
+// that is out of range now: below MinSignalPercent the live signal caps
+// what is expected of it. With History set the candidates are
+// reordered best first; without it they keep their order and the report
+// only explains it.
+func (m *Monitor) rank(candidates []candidate, from string, now time.Time) *RankingReport {
+	report := &RankingReport{At: now, From: from}
+	for i := range candidates {
+		c := &candidates[i]
+		r := Ranking{
+			SSID:      c.profile.CleanName,
+			BSSID:     c.bssid,
+			Interface: c.profile.InterfaceName,
+			Signal:    c.signal,
+		}
+		live := float64(m.scorer().Score(metrics.Inputs{Signal: c.signal}).Score)
+		r.Expected, r.Why = live, fmt.Sprintf("signal %d%% is worth %.0f", c.signal, live)
+		if m.History != nil {
+			e := m.History.Expect(r.SSID, r.BSSID, now)
+			r.History = e
+			if e.Checks > 0 {
+				past := e.AvgScore * (1 - e.FailureRate)
+				w := float64(e.Checks) / float64(e.Checks+historyWeight)
+				r.Expected = w*past + (1-w)*live
+				r.Why += fmt.Sprintf("; %s history (%s): score %.0f over %d checks, %.0f%% degraded",
+					e.Source, e.Bucket, e.AvgScore, e.Checks, e.FailureRate*100)
+			} else {
+				r.Why += "; no history"
+			}
+			if e.ConnectAttempts > 0 {
+				r.Expected *= e.ConnectRate
+				r.Why += fmt.Sprintf("; %.0f%% of %d connects held", e.ConnectRate*100, e.ConnectAttempts)
+			}
+		}
+		if c.signal < m.Config.MinSignalPercent && r.Expected > live {
+			r.Expected = live
+			r.Why += "; capped by the weak signal"
+		}
+		r.Expected = round1(r.Expected)
+		c.expected = r.Expected
+		report.Candidates = append(report.Candidates, r)
+	}
+
+	if m.History != nil {
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].expected > candidates[j].expected })
+		sort.SliceStable(report.Candidates, func(i, j int) bool {
+			return report.Candidates[i].Expected > report.Candidates[j].Expected
+		})
+	}
+	return report
+}
+
+// Ranking returns the order the last failover tried networks in and the
+// one it chose, or nil before the first failover.
+func (m *Monitor) Ranking() *RankingReport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.ranking == nil {
+		return nil
+	}
+	r := *m.ranking
+	return &r
+}
+
+// RankNow scans and ranks the networks a failover would try right now.
+func (m *Monitor) RankNow(ctx context.Context) (*RankingReport, error) {
+	statuses, err := m.Wifi.GetInterfaceStatuses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get interface statuses: %w", err)
+	}
+	current := m.pickPrimary(statuses)
+	if current == nil {
+		return nil, fmt.Errorf("no active Wi-Fi interface found")
+	}
+	candidates, err := m.candidates(ctx, current)
+	if err != nil {
+		return nil, err
+	}
+	return m.rank(candidates, current.SSID, m.clock().Now()), nil
+}
+
+// rememberCheck records a check of the primary in History.
+func (m *Monitor) rememberCheck(status *wifi.WifiStatus, score int, degraded bool, now time.Time) {
+	if m.History == nil {
+		return
+	}
+	if err := m.History.Observe(status.SSID, status.BSSID, score, degraded, now); err != nil {
+		log.Println("[monitor] save history:", err)
+	}
+}
+
+// rememberConnect records whether joining a candidate worked in its
+// circuit breaker and in History, with the access point it landed on.
+func (m *Monitor) rememberConnect(ctx context.Context, c candidate, ok bool) {
+	m.tripBreaker(c.profile.CleanName, ok, m.clock().Now())
+	if m.History == nil {
+		return
+	}
+	bssid := ""
+	if ok {
+		if st, err := m.interfaceStatus(ctx, c.profile.InterfaceName); err == nil && st.SSID == c.profile.CleanName {
+			bssid = st.BSSID
+		}
+	}
+	if err := m.History.Connected(c.profile.CleanName, bssid, ok, m.clock().Now()); err != nil {
+		log.Println("[monitor] save history:", err)
+	}
+}
@@ -0,0 +1,121 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Connectivity states reported by a CaptiveChecker.
+const (
+	StateOnline        = "online"
+	StateCaptivePortal = "captive_portal"
+	StateOffline       = "offline"
+)
+
+// DefaultCaptiveURL answers 204 with an empty body when nothing intercepts it.
+const DefaultCaptiveURL = "http://connectivitycheck.gstatic.com/generate_204"
+
+// CaptiveResult is the outcome of one connectivity check.
+type CaptiveResult struct {
+	State     string `json:"state"`
+	Status    int    `json:"status,omitempty"`
+	PortalURL string `json:"portal_url,omitempty"` // where the portal redirected to
+	Detail    string `json:"detail,omitempty"`
+}
+
+// CaptiveChecker tells an open internet connection from a captive portal.
+type CaptiveChecker interface {
+	Detect(ctx context.Context) *CaptiveResult
+}
+
+// CaptiveDetector fetches a known URL over plain HTTP, where portals can
+// intercept it, and compares the answer with what the real server sends:
+// either status ExpectStatus with an empty body (the generate_204 style) or,
+// if ExpectBody is set, a 200 whose body starts with it (the Windows NCSI
+// "Microsoft Connect Test" style). A redirect to a login page, or a 200
+// with other content, means a portal is in the way. Error statuses say
+// the check itself failed, so they count as offline rather than a portal.
+type CaptiveDetector struct {
+	URL          string // default DefaultCaptiveURL
+	ExpectStatus int    // default 204
+	ExpectBody   string
+	Timeout      time.Duration // default 5s
+}
+
+func (d CaptiveDetector) Detect(ctx context.Context) *CaptiveResult {
+	url, expect, timeout := d.URL, d.ExpectStatus, d.Timeout
+	if url == "" {
+		url = DefaultCaptiveURL
+	}
+	if expect == 0 {
+		expect = http.StatusNoContent
+		if d.ExpectBody != "" {
+			expect = http.StatusOK
+		}
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return &CaptiveResult{State: StateOffline, Detail: err.Error()}
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+	client := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true}, // no proxy: portals sit on the path
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &CaptiveResult{State: StateOffline, Detail: err.Error()}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	res := &CaptiveResult{State: StateOffline, Status: resp.StatusCode}
+	content := strings.TrimSpace(string(body))
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		res.PortalURL = resp.Header.Get("Location")
+		if res.PortalURL == "" {
+			res.Detail = fmt.Sprintf("status %d without a Location", resp.StatusCode)
+			break
+		}
+		res.State, res.Detail = StateCaptivePortal, "redirected"
+	case resp.StatusCode == expect || resp.StatusCode == http.StatusOK:
+		genuine := content == ""
+		if d.ExpectBody != "" {
+			genuine = strings.HasPrefix(content, d.ExpectBody)
+		}
+		if !genuine {
+			// A portal serving its login page in place of the answer.
+			res.State, res.Detail = StateCaptivePortal, "content changed"
+			break
+		}
+		res.State = StateOnline
+	default:
+		res.Detail = fmt.Sprintf("status %d, want %d", resp.StatusCode, expect)
+	}
+	return res
+}
+
+// SimulatedCaptive reports a captive portal whenever Captive returns true.
+type SimulatedCaptive struct {
+	Captive func() bool
+}
+
+func (s SimulatedCaptive) Detect(context.Context) *CaptiveResult {
+	if s.Captive() {
+		return &CaptiveResult{State: StateCaptivePortal, Status: http.StatusFound, Detail: "simulated portal"}
+	}
+	return &CaptiveResult{State: StateOnline}
+}
@@ -0,0 +1,60 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCaptiveDetector(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		status   int
+		location string
+		body     string
+		expect   string // ExpectBody
+		state    string
+		portal   string
+	}{
+		{name: "generate_204", status: http.StatusNoContent, state: StateOnline},
+		{name: "redirect to login", status: http.StatusFound, location: "http://10.0.0.1/login?orig=gstatic", state: StateCaptivePortal, portal: "http://10.0.0.1/login?orig=gstatic"},
+		{name: "temporary redirect", status: http.StatusTemporaryRedirect, location: "https://wifi.example.edu/", state: StateCaptivePortal, portal: "https://wifi.example.edu/"},
+		{name: "redirect without location", status: http.StatusFound, state: StateOffline},
+		{name: "login page in place", status: http.StatusOK, body: "<html><title>Guest Wi-Fi</title></html>", state: StateCaptivePortal},
+		{name: "empty 200", status: http.StatusOK, state: StateOnline},
+		{name: "forbidden", status: http.StatusForbidden, body: "blocked by policy", state: StateOffline},
+		{name: "not found", status: http.StatusNotFound, state: StateOffline},
+		{name: "bad gateway", status: http.StatusBadGateway, body: "<html>502</html>", state: StateOffline},
+		{name: "unavailable", status: http.StatusServiceUnavailable, state: StateOffline},
+		{name: "ncsi", status: http.StatusOK, body: "Microsoft Connect Test", expect: "Microsoft Connect Test", state: StateOnline},
+		{name: "ncsi login page", status: http.StatusOK, body: "<html>Sign in</html>", expect: "Microsoft Connect Test", state: StateCaptivePortal},
+		{name: "ncsi no content", status: http.StatusNoContent, expect: "Microsoft Connect Test", state: StateOffline},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.location != "" {
+					w.Header().Set("Location", tc.location)
+				}
+				w.WriteHeader(tc.status)
+				io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			res := CaptiveDetector{URL: srv.URL, ExpectBody: tc.expect}.Detect(context.Background())
+			if res.State != tc.state || res.PortalURL != tc.portal || res.Status != tc.status {
+				t.Errorf("Detect = %+v, want %s at %q", res, tc.state, tc.portal)
+			}
+		})
+	}
+}
+
+func TestCaptiveDetectorUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	if res := (CaptiveDetector{URL: url}).Detect(context.Background()); res.State != StateOffline || res.Status != 0 {
+		t.Errorf("Detect = %+v, want offline", res)
+	}
+}
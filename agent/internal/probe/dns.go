@@ -0,0 +1,307 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Outcomes of a DNS query, as reported in DNSResult.Rcode.
+const (
+	RcodeNoError  = "NOERROR"
+	RcodeNXDomain = "NXDOMAIN"
+	RcodeServFail = "SERVFAIL"
+	RcodeTimeout  = "TIMEOUT"
+	RcodeError    = "ERROR" // refused, unreachable, malformed reply, ...
+)
+
+// DNSQuery is one lookup. Server is empty for the system resolver, or
+// "host[:port]" to ask that server directly, optionally prefixed with
+// "tcp://" or "udp://" (the default).
+type DNSQuery struct {
+	Name    string
+	Server  string
+	Timeout time.Duration // default 2s
+}
+
+// DNSResult is the outcome of one lookup.
+type DNSResult struct {
+	Name      string   `json:"name"`
+	Server    string   `json:"server"` // "system" for the OS resolver
+	Proto     string   `json:"proto"`
+	Rcode     string   `json:"rcode"`
+	LatencyMs float64  `json:"latency_ms"`
+	Addrs     []string `json:"addrs,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+func (r *DNSResult) Failed() bool { return r.Rcode != RcodeNoError }
+
+// DNSProber runs a DNS query; the result is never nil.
+type DNSProber interface {
+	Query(ctx context.Context, q DNSQuery) *DNSResult
+}
+
+// DNSClient queries the system resolver or a DNS server directly.
+type DNSClient struct{}
+
+func (DNSClient) Query(ctx context.Context, q DNSQuery) *DNSResult {
+	timeout := q.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if q.Server == "" || q.Server == "system" {
+		return lookupSystem(ctx, q.Name)
+	}
+
+	proto, server := "udp", q.Server
+	if p, rest, ok := strings.Cut(server, "://"); ok {
+		proto, server = p, rest
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return lookupDirect(ctx, proto, server, q.Name)
+}
+
+func lookupSystem(ctx context.Context, name string) *DNSResult {
+	res := &DNSResult{Name: name, Server: "system", Proto: "system"}
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, name)
+	res.LatencyMs = msSince(start)
+	if err != nil {
+		res.Rcode, res.Error = classifySystemError(ctx, err), err.Error()
+		return res
+	}
+	res.Rcode, res.Addrs = RcodeNoError, addrs
+	return res
+}
+
+// classifySystemError maps Go resolver errors onto rcodes. The pure Go
+// resolver reports SERVFAIL as "server misbehaving".
+func classifySystemError(ctx context.Context, err error) string {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return RcodeNXDomain
+	case errors.As(err, &dnsErr) && dnsErr.IsTimeout, ctx.Err() != nil:
+		return RcodeTimeout
+	case strings.Contains(err.Error(), "server misbehaving"):
+		return RcodeServFail
+	}
+	return RcodeError
+}
+
+func lookupDirect(ctx context.Context, proto, server, name string) *DNSResult {
+	res := &DNSResult{Name: name, Server: server, Proto: proto}
+	fail := func(err error) *DNSResult {
+		res.Rcode, res.Error = RcodeError, err.Error()
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() || ctx.Err() != nil {
+			res.Rcode = RcodeTimeout
+		}
+		return res
+	}
+
+	qname, err := dnsmessage.NewName(dnsName(name))
+	if err != nil {
+		return fail(err)
+	}
+	id := uint16(rand.Intn(1 << 16))
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return fail(err)
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}); err != nil {
+		return fail(err)
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return fail(err)
+	}
+
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, proto, server)
+	if err != nil {
+		return fail(err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var reply []byte
+	if proto == "tcp" {
+		reply, err = exchangeTCP(conn, msg)
+	} else {
+		reply, err = exchangeUDP(conn, msg, id)
+	}
+	res.LatencyMs = msSince(start)
+	if err != nil {
+		return fail(err)
+	}
+
+	var p dnsmessage.Parser
+	h, err := p.Start(reply)
+	if err != nil {
+		return fail(err)
+	}
+	switch h.RCode {
+	case dnsmessage.RCodeSuccess:
+		res.Rcode = RcodeNoError
+	case dnsmessage.RCodeNameError:
+		res.Rcode = RcodeNXDomain
+		return res
+	case dnsmessage.RCodeServerFailure:
+		res.Rcode = RcodeServFail
+		return res
+	default:
+		res.Rcode, res.Error = RcodeError, h.RCode.String()
+		return res
+	}
+
+	if err := p.SkipAllQuestions(); err != nil {
+		return fail(err)
+	}
+	for {
+		ah, err := p.AnswerHeader()
+		if err != nil {
+			break // ErrSectionDone or a truncated reply; keep what we have
+		}
+		if ah.Type != dnsmessage.TypeA {
+			if err := p.SkipAnswer(); err != nil {
+				break
+			}
+			continue
+		}
+		a, err := p.AResource()
+		if err != nil {
+			break
+		}
+		res.Addrs = append(res.Addrs, net.IP(a.A[:]).String())
+	}
+	return res
+}
+
+func exchangeUDP(conn net.Conn, msg []byte, id uint16) ([]byte, error) {
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Ignore stray replies to earlier queries.
+		if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+			return buf[:n], nil
+		}
+	}
+}
+
+func exchangeTCP(conn net.Conn, msg []byte) ([]byte, error) {
+	framed := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(framed, uint16(len(msg)))
+	copy(framed[2:], msg)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func dnsName(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func msSince(t time.Time) float64 {
+	return float64(time.Since(t)) / float64(time.Millisecond)
+}
+
+// DNSSummary aggregates the lookups of one check.
+type DNSSummary struct {
+	Queries      int         `json:"queries"`
+	Failures     int         `json:"failures"`
+	NXDomain     int         `json:"nxdomain"`
+	ServFail     int         `json:"servfail"`
+	Timeouts     int         `json:"timeouts"`
+	AvgLatencyMs float64     `json:"avg_latency_ms"` // over successful lookups
+	Results      []DNSResult `json:"results"`
+}
+
+// Summarize counts outcomes by rcode.
+func Summarize(results []DNSResult) *DNSSummary {
+	s := &DNSSummary{Queries: len(results), Results: results}
+	var total float64
+	for _, r := range results {
+		switch r.Rcode {
+		case RcodeNoError:
+			total += r.LatencyMs
+			continue
+		case RcodeNXDomain:
+			s.NXDomain++
+		case RcodeServFail:
+			s.ServFail++
+		case RcodeTimeout:
+			s.Timeouts++
+		}
+		s.Failures++
+	}
+	if ok := s.Queries - s.Failures; ok > 0 {
+		s.AvgLatencyMs = total / float64(ok)
+	}
+	return s
+}
+
+// Degraded reports whether name resolution is broken for applications:
+// the system resolver failed, or no server answered at all. A failing
+// direct server alone is not enough, since many networks block outside DNS.
+func (s *DNSSummary) Degraded() bool {
+	if s == nil || s.Queries == 0 {
+		return false
+	}
+	if s.Failures == s.Queries {
+		return true
+	}
+	for _, r := range s.Results {
+		if r.Proto == "system" && r.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// String is a one-line description for logs.
+func (r *DNSResult) String() string {
+	if r.Failed() && r.Error != "" {
+		return fmt.Sprintf("%s via %s/%s: %s (%s)", r.Name, r.Server, r.Proto, r.Rcode, r.Error)
+	}
+	if r.Failed() {
+		return fmt.Sprintf("%s via %s/%s: %s", r.Name, r.Server, r.Proto, r.Rcode)
+	}
+	return fmt.Sprintf("%s via %s/%s: %.0fms", r.Name, r.Server, r.Proto, r.LatencyMs)
+}
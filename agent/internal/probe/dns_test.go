@@ -0,0 +1,78 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestDNSSummary(t *testing.T) {
+	system := func(rcode string, ms float64) DNSResult {
+		return DNSResult{Name: "example.com", Server: "system", Proto: "system", Rcode: rcode, LatencyMs: ms}
+	}
+	direct := func(server, rcode string, ms float64) DNSResult {
+		return DNSResult{Name: "example.com", Server: server, Proto: "udp", Rcode: rcode, LatencyMs: ms}
+	}
+	for _, tc := range []struct {
+		name     string
+		results  []DNSResult
+		want     DNSSummary // Results is not compared
+		degraded bool
+	}{
+		{"no queries", nil, DNSSummary{}, false},
+		{"all answered", []DNSResult{system(RcodeNoError, 12), direct("1.1.1.1:53", RcodeNoError, 20)},
+			DNSSummary{Queries: 2, AvgLatencyMs: 16}, false},
+		// Many networks block outside resolvers; the system one still works.
+		{"outside DNS blocked", []DNSResult{system(RcodeNoError, 9), direct("8.8.8.8:53", RcodeTimeout, 2000), direct("1.1.1.1:53", RcodeError, 0.4)},
+			DNSSummary{Queries: 3, Failures: 2, Timeouts: 1, AvgLatencyMs: 9}, false},
+		{"system resolver failing", []DNSResult{system(RcodeServFail, 35), direct("1.1.1.1:53", RcodeNoError, 18)},
+			DNSSummary{Queries: 2, Failures: 1, ServFail: 1, AvgLatencyMs: 18}, true},
+		{"system name missing", []DNSResult{system(RcodeNXDomain, 4), direct("1.1.1.1:53", RcodeNoError, 18)},
+			DNSSummary{Queries: 2, Failures: 1, NXDomain: 1, AvgLatencyMs: 18}, true},
+		{"nothing answers", []DNSResult{direct("8.8.8.8:53", RcodeTimeout, 2000), direct("1.1.1.1:53", RcodeTimeout, 2000)},
+			DNSSummary{Queries: 2, Failures: 2, Timeouts: 2}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := Summarize(tc.results)
+			got := *s
+			got.Results = nil
+			if got.Queries != tc.want.Queries || got.Failures != tc.want.Failures || got.NXDomain != tc.want.NXDomain ||
+				got.ServFail != tc.want.ServFail || got.Timeouts != tc.want.Timeouts || got.AvgLatencyMs != tc.want.AvgLatencyMs {
+				t.Errorf("Summarize = %+v, want %+v", got, tc.want)
+			}
+			if s.Degraded() != tc.degraded {
+				t.Errorf("Degraded = %v, want %v", s.Degraded(), tc.degraded)
+			}
+		})
+	}
+
+	var none *DNSSummary
+	if none.Degraded() {
+		t.Error("a check without DNS queries is degraded")
+	}
+}
+
+// The errors are the ones Go's resolver returns for each outcome.
+func TestClassifySystemError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{&net.DNSError{Err: "no such host", Name: "nosuch.example", IsNotFound: true}, RcodeNXDomain},
+		{&net.DNSError{Err: "server misbehaving", Name: "example.com", Server: "127.0.0.53:53"}, RcodeServFail},
+		{&net.DNSError{Err: "i/o timeout", Name: "example.com", Server: "192.168.1.1:53", IsTimeout: true}, RcodeTimeout},
+		{&net.DNSError{Err: "connection refused", Name: "example.com", Server: "192.168.1.1:53"}, RcodeError},
+		{errors.New("dial udp: network is unreachable"), RcodeError},
+	} {
+		if got := classifySystemError(context.Background(), tc.err); got != tc.want {
+			t.Errorf("%v: %s, want %s", tc.err, got, tc.want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := classifySystemError(ctx, &net.DNSError{Err: "operation was canceled", Name: "example.com"}); got != RcodeTimeout {
+		t.Errorf("lookup cut off by ctx: %s, want %s", got, RcodeTimeout)
+	}
+}
@@ -0,0 +1,75 @@
+package probe
+
+import "net"
+
+// Where along the path a problem starts, as reported in PathReport.Fault.
+const (
+	FaultUnknown   = ""           // the path could not be probed
+	FaultNone      = "none"       // every tier is healthy
+	FaultLocalLink = "local_link" // the Wi-Fi link to the access point
+	FaultLAN       = "lan"        // past the gateway, inside the local network
+	FaultUpstream  = "upstream"   // the campus uplink or ISP
+)
+
+// PathReport probes the path tier by tier, nearest first: the default
+// gateway, the configured resolvers, then the public target. A tier that
+// could not be probed is nil.
+type PathReport struct {
+	Gateway   *Result   `json:"gateway,omitempty"`
+	Resolvers []*Result `json:"resolvers,omitempty"`
+	Public    *Result   `json:"public,omitempty"`
+	// Application-level failures seen on the same check.
+	ResolveFailed bool   `json:"resolve_failed"`
+	AppFailed     bool   `json:"app_failed"`
+	Fault         string `json:"fault"`
+}
+
+// Localize sets and returns the fault: the nearest tier that is
+// unreachable, loses packets or is slower than maxMs. Resolvers on private
+// addresses are inside the local network; public ones sit beyond the
+// uplink and are judged with the public target. Without a gateway reading
+// nothing can be said about the link, so the fault stays unknown.
+func (p *PathReport) Localize(maxMs float64) string {
+	p.Fault = p.localize(maxMs)
+	return p.Fault
+}
+
+func (p *PathReport) localize(maxMs float64) string {
+	if p.Gateway == nil {
+		return FaultUnknown
+	}
+	publicBad := hopBad(p.Public, maxMs)
+	// Many gateways drop ICMP to themselves; silence only counts against
+	// the link when nothing further out answered either.
+	gatewaySilent := p.Gateway.Received == 0 && p.Public != nil && p.Public.Received > 0
+	if !gatewaySilent && hopBad(p.Gateway, maxMs) {
+		return FaultLocalLink
+	}
+
+	var lanResolvers, lanBad int
+	for _, r := range p.Resolvers {
+		if ip := net.ParseIP(stripZone(r.Target)); ip == nil || !ip.IsPrivate() {
+			continue
+		}
+		lanResolvers++
+		if hopBad(r, maxMs) {
+			lanBad++
+		}
+	}
+	if lanBad > 0 || p.ResolveFailed && lanResolvers > 0 && lanResolvers == len(p.Resolvers) {
+		return FaultLAN
+	}
+	if publicBad || p.ResolveFailed || p.AppFailed {
+		return FaultUpstream
+	}
+	return FaultNone
+}
+
+// hopBad reports whether a probed hop is unreachable, lossy or slow.
+// A hop that was not probed is not held against the path.
+func hopBad(r *Result, maxMs float64) bool {
+	if r == nil {
+		return false
+	}
+	return r.Received == 0 || r.LossPct >= 20 || maxMs > 0 && r.AvgMs > maxMs
+}
@@ -0,0 +1,146 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Endpoint is an application URL the user depends on, e.g. the exam portal.
+type Endpoint struct {
+	Name         string
+	URL          string
+	ExpectStatus int           // 0 accepts any 2xx or 3xx
+	Critical     bool          // unreachable means the link is unusable
+	Timeout      time.Duration // default 10s
+}
+
+// EndpointResult breaks one request down with net/http/httptrace. Phases
+// that did not happen (no DNS for an IP URL, no TLS for http) stay 0.
+type EndpointResult struct {
+	Name      string  `json:"name"`
+	URL       string  `json:"url"`
+	Status    int     `json:"status"`
+	DNSMs     float64 `json:"dns_ms"`
+	ConnectMs float64 `json:"connect_ms"`
+	TLSMs     float64 `json:"tls_ms"`
+	TTFBMs    float64 `json:"ttfb_ms"`
+	TotalMs   float64 `json:"total_ms"`
+	OK        bool    `json:"ok"`
+	Critical  bool    `json:"critical"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// EndpointProber checks an endpoint; the result is never nil.
+type EndpointProber interface {
+	Check(ctx context.Context, e Endpoint) *EndpointResult
+}
+
+// HTTPProber requests each endpoint on a fresh connection, so every check
+// pays for DNS, TCP and TLS the way a first visit would. Redirects are not
+// followed: a login redirect is reported as the 3xx it is.
+type HTTPProber struct{}
+
+func (HTTPProber) Check(ctx context.Context, e Endpoint) *EndpointResult {
+	res := &EndpointResult{Name: e.Name, URL: e.URL, Critical: e.Critical}
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Dial callbacks can run concurrently (happy eyeballs).
+	var mu sync.Mutex
+	var dnsStart, connStart, tlsStart time.Time
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			dnsStart = time.Now()
+			mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mu.Lock()
+			res.DNSMs = msSince(dnsStart)
+			mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			mu.Lock()
+			if connStart.IsZero() {
+				connStart = time.Now()
+			}
+			mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			mu.Lock()
+			if err == nil && res.ConnectMs == 0 {
+				res.ConnectMs = msSince(connStart)
+			}
+			mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			tlsStart = time.Now()
+			mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mu.Lock()
+			res.TLSMs = msSince(tlsStart)
+			mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			res.TTFBMs = msSince(start)
+			mu.Unlock()
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, e.URL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if res.Name == "" {
+		res.Name = req.URL.Host
+	}
+	req.Header.Set("Cache-Control", "no-cache")
+
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		mu.Lock()
+		res.TotalMs = msSince(start)
+		res.Error = err.Error()
+		mu.Unlock()
+		return res
+	}
+	_, err = io.Copy(io.Discard, io.LimitReader(resp.Body, 256<<10))
+	resp.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	res.TotalMs = msSince(start)
+	res.Status = resp.StatusCode
+	switch {
+	case err != nil:
+		res.Error = "read body: " + err.Error()
+	case e.ExpectStatus != 0 && resp.StatusCode != e.ExpectStatus:
+		res.Error = fmt.Sprintf("status %d, want %d", resp.StatusCode, e.ExpectStatus)
+	case e.ExpectStatus == 0 && resp.StatusCode >= 400:
+		res.Error = fmt.Sprintf("status %d", resp.StatusCode)
+	default:
+		res.OK = true
+	}
+	return res
+}
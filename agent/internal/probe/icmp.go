@@ -0,0 +1,122 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ICMPProber sends ICMP echo requests over an unprivileged datagram socket
+// ("ping socket"), so no root or raw-socket capability is needed. On Linux
+// the user's group must be inside net.ipv4.ping_group_range; where the
+// socket cannot be opened (or on Windows) Probe fails and Default falls
+// back to the system ping.
+type ICMPProber struct{}
+
+func (ICMPProber) Probe(ctx context.Context, t Target) (*Result, error) {
+	t = t.withDefaults()
+	t.Method = MethodICMP
+
+	ip, err := resolve(ctx, t.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	network, laddr, proto := "udp4", "0.0.0.0", 1 // IANA protocol number, ICMP
+	var echo, reply icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, laddr, proto = "udp6", "::", 58 // ICMPv6
+		echo, reply = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	c, err := icmp.ListenPacket(network, laddr)
+	if err != nil {
+		return nil, fmt.Errorf("icmp %s: open socket: %w", t.Host, err)
+	}
+	defer c.Close()
+
+	dst := &net.UDPAddr{IP: ip}
+	// The kernel rewrites the ID to the socket's port; matching on Seq is enough.
+	id := os.Getpid() & 0xffff
+	buf := make([]byte, 1500)
+
+	var rtts []time.Duration
+	sent := 0
+	for seq := 0; seq < t.Count; seq++ {
+		if seq > 0 {
+			if err := wait(ctx, t.Interval); err != nil {
+				break
+			}
+		}
+
+		msg := icmp.Message{Type: echo, Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("netshield")}}
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		if _, err := c.WriteTo(b, dst); err != nil {
+			return nil, fmt.Errorf("icmp %s: send: %w", t.Host, err)
+		}
+		sent++
+
+		deadline := start.Add(t.Timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		if rtt, ok := readEchoReply(c, buf, proto, reply, seq, start, deadline); ok {
+			rtts = append(rtts, rtt)
+		}
+	}
+	return newResult(t, sent, rtts), nil
+}
+
+// readEchoReply waits for the reply to seq, discarding anything else.
+func readEchoReply(c *icmp.PacketConn, buf []byte, proto int, reply icmp.Type, seq int, start, deadline time.Time) (time.Duration, bool) {
+	if err := c.SetReadDeadline(deadline); err != nil {
+		return 0, false
+	}
+	for {
+		n, _, err := c.ReadFrom(buf)
+		if err != nil {
+			return 0, false // timeout: packet lost
+		}
+		rtt := time.Since(start)
+
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || msg.Type != reply {
+			continue
+		}
+		if e, ok := msg.Body.(*icmp.Echo); ok && e.Seq == seq {
+			return rtt, true
+		}
+	}
+}
+
+// resolve returns the first address for host, preferring IPv4.
+func resolve(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			return a.IP, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("resolve " + host + ": no addresses")
+	}
+	return addrs[0].IP, nil
+}
@@ -0,0 +1,188 @@
+// Package probe measures round-trip latency to a target with one of several
+// methods: native ICMP echo, the OS ping command, or TCP connect. Every
+// method sends a short burst and reports the same Result.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"netshield/agent/internal/wifi"
+)
+
+const (
+	MethodICMP = "icmp" // unprivileged ICMP echo, falling back to system ping
+	MethodPing = "ping" // the OS ping command
+	MethodTCP  = "tcp"  // TCP handshake time, for networks that drop ICMP
+)
+
+// Target is one host to probe and how.
+type Target struct {
+	Host     string
+	Method   string        // MethodICMP (default), MethodPing or MethodTCP
+	Port     int           // MethodTCP only; default 443
+	Count    int           // packets per burst; default 3
+	Interval time.Duration // gap between packets; default 200ms
+	Timeout  time.Duration // per-packet wait; default 2s
+}
+
+func (t Target) withDefaults() Target {
+	if t.Method == "" {
+		t.Method = MethodICMP
+	}
+	if t.Port == 0 {
+		t.Port = 443
+	}
+	if t.Count <= 0 {
+		t.Count = 3
+	}
+	if t.Interval <= 0 {
+		t.Interval = 200 * time.Millisecond
+	}
+	if t.Timeout <= 0 {
+		t.Timeout = 2 * time.Second
+	}
+	return t
+}
+
+// Result summarises one burst. RTTsMs holds one entry per reply, in the
+// order the replies arrived; lost packets are only counted in LossPct.
+type Result struct {
+	Target   string    `json:"target"`
+	Method   string    `json:"method"`
+	Sent     int       `json:"sent"`
+	Received int       `json:"received"`
+	RTTsMs   []float64 `json:"rtts_ms"`
+	MinMs    float64   `json:"min_ms"`
+	AvgMs    float64   `json:"avg_ms"`
+	MaxMs    float64   `json:"max_ms"`
+	StdDevMs float64   `json:"stddev_ms"`
+	JitterMs float64   `json:"jitter_ms"`
+	LossPct  float64   `json:"loss_pct"`
+}
+
+// HasRTT reports whether the burst measured a round-trip time. A reply
+// count with neither per-reply times nor a nonzero average carries no
+// latency and must not be read as 0 ms.
+func (r *Result) HasRTT() bool {
+	return r.Received > 0 && (len(r.RTTsMs) > 0 || r.AvgMs > 0)
+}
+
+// Prober sends a burst to a target. It returns an error only when the
+// probe could not run at all (bad host, no permission for the socket);
+// a burst with every packet lost is a Result with 100% loss.
+type Prober interface {
+	Probe(ctx context.Context, t Target) (*Result, error)
+}
+
+// ByMethod routes each target to the prober registered for its Method.
+type ByMethod map[string]Prober
+
+func (b ByMethod) Probe(ctx context.Context, t Target) (*Result, error) {
+	t = t.withDefaults()
+	p, ok := b[t.Method]
+	if !ok {
+		return nil, fmt.Errorf("probe %s: unknown method %q", t.Host, t.Method)
+	}
+	return p.Probe(ctx, t)
+}
+
+// Fallback tries each prober in turn until one is able to run.
+type Fallback []Prober
+
+func (f Fallback) Probe(ctx context.Context, t Target) (*Result, error) {
+	var err error
+	for _, p := range f {
+		var res *Result
+		if res, err = p.Probe(ctx, t); err == nil {
+			return res, nil
+		}
+	}
+	return nil, err
+}
+
+// Default returns the standard set of probers; system ping commands go
+// through runner so they can be recorded and replayed.
+func Default(runner wifi.CommandRunner) ByMethod {
+	sys := SystemProber{Runner: runner}
+	return ByMethod{
+		MethodICMP: Fallback{ICMPProber{}, sys},
+		MethodPing: sys,
+		MethodTCP:  TCPProber{},
+	}
+}
+
+// Recorded returns probers for a session being captured: every method
+// runs the system ping through runner, since the ICMP socket and TCP
+// connects bypass it and replay can only play back commands.
+func Recorded(runner wifi.CommandRunner) ByMethod {
+	sys := SystemProber{Runner: runner}
+	return ByMethod{MethodICMP: sys, MethodPing: sys, MethodTCP: sys}
+}
+
+// newResult computes the burst statistics from the replies received.
+func newResult(t Target, sent int, rtts []time.Duration) *Result {
+	ms := make([]float64, len(rtts))
+	for i, d := range rtts {
+		ms[i] = float64(d) / float64(time.Millisecond)
+	}
+	return resultFromMs(t, sent, ms)
+}
+
+func resultFromMs(t Target, sent int, ms []float64) *Result {
+	res := &Result{Target: t.Host, Method: t.Method, Sent: sent, Received: len(ms), RTTsMs: ms}
+	if sent > 0 {
+		res.LossPct = float64(sent-len(ms)) / float64(sent) * 100
+	}
+	if len(ms) == 0 {
+		return res
+	}
+
+	res.MinMs, res.MaxMs = ms[0], ms[0]
+	var sum float64
+	for _, v := range ms {
+		sum += v
+		res.MinMs = math.Min(res.MinMs, v)
+		res.MaxMs = math.Max(res.MaxMs, v)
+	}
+	res.AvgMs = sum / float64(len(ms))
+
+	var sq float64
+	for _, v := range ms {
+		sq += (v - res.AvgMs) * (v - res.AvgMs)
+	}
+	res.StdDevMs = math.Sqrt(sq / float64(len(ms)))
+	res.JitterMs = Jitter(ms)
+	return res
+}
+
+// Jitter is the RFC 3550 (section 6.4.1) interarrival jitter over a burst:
+// J += (|D| - J) / 16, where D is the change in transit time between
+// consecutive replies. With a round trip the send times cancel out, so D is
+// the difference of consecutive RTTs. J starts at the first |D| rather than
+// 0, since a burst is far shorter than the stream the estimator assumes.
+func Jitter(rttsMs []float64) float64 {
+	if len(rttsMs) < 2 {
+		return 0
+	}
+	j := math.Abs(rttsMs[1] - rttsMs[0])
+	for i := 2; i < len(rttsMs); i++ {
+		d := math.Abs(rttsMs[i] - rttsMs[i-1])
+		j += (d - j) / 16
+	}
+	return j
+}
+
+// wait sleeps for d unless ctx is done first.
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,250 @@
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"netshield/agent/internal/wifi"
+)
+
+// Hops are the first hops of the default route: the gateway the Wi-Fi
+// link leads to and the resolvers the OS was configured with.
+type Hops struct {
+	Gateway    string   `json:"gateway"`
+	DNSServers []string `json:"dns_servers"`
+}
+
+// Discoverer finds the default gateway and DNS servers, giving up once
+// ctx is done.
+type Discoverer interface {
+	Discover(ctx context.Context) (*Hops, error)
+}
+
+// SystemDiscoverer reads them from the OS: /proc/net/route and resolv.conf
+// on Linux, `route print` and `ipconfig /all` on Windows, and
+// `route -n get default` with resolv.conf elsewhere. Commands go through
+// Runner (nil runs them directly).
+type SystemDiscoverer struct {
+	Runner wifi.CommandRunner
+	OS     string // empty uses runtime.GOOS
+}
+
+var (
+	procRoutePath = "/proc/net/route"
+	// systemd-resolved points resolv.conf at its local stub; the second
+	// file lists the servers the stub forwards to.
+	resolvConfPaths = []string{"/etc/resolv.conf", "/run/systemd/resolve/resolv.conf"}
+)
+
+func (d SystemDiscoverer) Discover(ctx context.Context) (*Hops, error) {
+	runner := d.Runner
+	if runner == nil {
+		runner = wifi.ExecRunner{}
+	}
+	goos := d.OS
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+
+	hops := &Hops{}
+	switch goos {
+	case "windows":
+		out, err := runner.Run(ctx, "route", "print", "-4", "0.0.0.0")
+		if err != nil {
+			return nil, err
+		}
+		hops.Gateway = parseRoutePrint(out)
+		if out, err := runner.Run(ctx, "ipconfig", "/all"); err == nil {
+			hops.DNSServers = parseIPConfigDNS(out, hops.Gateway)
+		}
+	case "linux":
+		b, err := os.ReadFile(procRoutePath)
+		if err != nil {
+			return nil, err
+		}
+		hops.Gateway = parseProcRoute(string(b))
+		hops.DNSServers = readResolvConf()
+	default:
+		out, err := runner.Run(ctx, "route", "-n", "get", "default")
+		if err != nil {
+			return nil, err
+		}
+		hops.Gateway = parseRouteGet(out)
+		hops.DNSServers = readResolvConf()
+	}
+	if hops.Gateway == "" {
+		return hops, errors.New("no default gateway")
+	}
+	return hops, nil
+}
+
+// parseProcRoute returns the gateway of the default route with the lowest
+// metric. Addresses in /proc/net/route are little-endian hex.
+//
+//	Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask ...
+//	wlan0	00000000	0101A8C0	0003	0	0	600	00000000 ...
+func parseProcRoute(s string) string {
+	const rtfGateway = 0x2
+	best, bestMetric := "", -1
+	for _, line := range strings.Split(s, "\n")[1:] {
+		f := strings.Fields(line)
+		if len(f) < 8 || f[1] != "00000000" || f[7] != "00000000" {
+			continue
+		}
+		flags, err := strconv.ParseUint(f[3], 16, 32)
+		if err != nil || flags&rtfGateway == 0 {
+			continue
+		}
+		metric, _ := strconv.Atoi(f[6])
+		raw, err := hex.DecodeString(f[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		if bestMetric < 0 || metric < bestMetric {
+			ip := make(net.IP, 4)
+			binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+			best, bestMetric = ip.String(), metric
+		}
+	}
+	return best
+}
+
+// readResolvConf returns the first non-loopback nameservers found.
+func readResolvConf() []string {
+	for _, path := range resolvConfPaths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if servers := parseResolvConf(string(b)); len(servers) > 0 {
+			return servers
+		}
+	}
+	return nil
+}
+
+func parseResolvConf(s string) []string {
+	var servers []string
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 || f[0] != "nameserver" {
+			continue
+		}
+		if ip := net.ParseIP(stripZone(f[1])); ip != nil && !ip.IsLoopback() {
+			servers = append(servers, f[1])
+		}
+	}
+	return servers
+}
+
+// parseRoutePrint returns the gateway of the 0.0.0.0/0 route with the
+// lowest metric. The table is the same in every Windows language:
+//
+//	Network Destination        Netmask          Gateway       Interface  Metric
+//	          0.0.0.0          0.0.0.0      192.168.1.1    192.168.1.23     35
+func parseRoutePrint(s string) string {
+	best, bestMetric := "", -1
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 5 || f[0] != "0.0.0.0" || f[1] != "0.0.0.0" || net.ParseIP(f[2]) == nil {
+			continue // also skips "On-link" routes
+		}
+		metric, err := strconv.Atoi(f[4])
+		if err != nil {
+			continue
+		}
+		if bestMetric < 0 || metric < bestMetric {
+			best, bestMetric = f[2], metric
+		}
+	}
+	return best
+}
+
+// parseIPConfigDNS returns the DNS servers of the adapter whose default
+// gateway is gateway, or of every adapter if none matches. Labels are
+// localized ("DNS Servers", "DNS-Server", "Serveurs DNS"), so any label
+// containing "DNS" with an address for a value counts; further servers
+// follow on indented lines of their own.
+//
+//	Wireless LAN adapter Wi-Fi:
+//	   Default Gateway . . . . . . . . . : 192.168.1.1
+//	   DNS Servers . . . . . . . . . . . : 192.168.1.1
+//	                                       8.8.8.8
+func parseIPConfigDNS(s, gateway string) []string {
+	type adapter struct {
+		gateway bool
+		dns     []string
+	}
+	var adapters []*adapter
+	var cur *adapter
+	inDNS := false
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r", ""), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			cur = &adapter{}
+			adapters = append(adapters, cur)
+			inDNS = false
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		label, value, ok := strings.Cut(line, " : ")
+		if !ok {
+			// A continuation line: another value of the previous label.
+			v := strings.TrimSpace(line)
+			switch {
+			case inDNS && net.ParseIP(stripZone(v)) != nil:
+				cur.dns = append(cur.dns, v)
+			case !inDNS && gateway != "" && v == gateway:
+				cur.gateway = true
+			}
+			continue
+		}
+		value = strings.TrimSpace(value)
+		inDNS = strings.Contains(label, "DNS") && net.ParseIP(stripZone(value)) != nil
+		switch {
+		case inDNS:
+			cur.dns = append(cur.dns, value)
+		case gateway != "" && value == gateway:
+			cur.gateway = true
+		}
+	}
+
+	var all []string
+	for _, a := range adapters {
+		if a.gateway && len(a.dns) > 0 {
+			return a.dns
+		}
+		all = append(all, a.dns...)
+	}
+	return all
+}
+
+// parseRouteGet reads the "gateway: 192.168.1.1" line of BSD/macOS
+// `route -n get default`.
+func parseRouteGet(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if label, value, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && label == "gateway" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// stripZone drops an IPv6 zone ("fe80::1%12") so the address parses.
+func stripZone(s string) string {
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
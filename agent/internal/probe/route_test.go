@@ -0,0 +1,48 @@
+package probe
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseProcRoute(t *testing.T) {
+	// tun0's default route has the lowest metric but no gateway.
+	table := fixture(t, filepath.Join("route", "proc-route.txt"))
+	if got := parseProcRoute(table); got != "192.168.1.1" {
+		t.Errorf("parseProcRoute = %q, want the wlan0 gateway with the lower metric", got)
+	}
+	header, _, _ := strings.Cut(table, "\n")
+	if got := parseProcRoute(header); got != "" {
+		t.Errorf("parseProcRoute(header) = %q", got)
+	}
+}
+
+func TestParseRoutePrint(t *testing.T) {
+	// The persistent route repeats the gateway with "Default" for a metric.
+	if got := parseRoutePrint(fixture(t, filepath.Join("route", "route-print.txt"))); got != "192.168.1.1" {
+		t.Errorf("parseRoutePrint = %q, want the Wi-Fi gateway with the lower metric", got)
+	}
+}
+
+func TestParseIPConfigDNS(t *testing.T) {
+	for _, tc := range []struct {
+		file    string
+		gateway string
+		want    []string
+	}{
+		// The Wi-Fi adapter lists its IPv4 gateway after the link-local one.
+		{"ipconfig-en.txt", "192.168.1.1", []string{"192.168.1.1", "8.8.8.8", "fe80::1%12"}},
+		{"ipconfig-en.txt", "10.0.0.1", []string{"10.0.0.2"}},
+		{"ipconfig-en.txt", "", []string{"10.0.0.2", "192.168.1.1", "8.8.8.8", "fe80::1%12"}},
+		{"ipconfig-en.txt", "172.16.0.1", []string{"10.0.0.2", "192.168.1.1", "8.8.8.8", "fe80::1%12"}},
+		{"ipconfig-de.txt", "192.168.178.1", []string{"192.168.178.1"}},
+		{"ipconfig-fr.txt", "192.168.1.254", []string{"192.168.1.254", "1.1.1.1"}},
+	} {
+		got := parseIPConfigDNS(fixture(t, filepath.Join("route", tc.file)), tc.gateway)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s via %q: %q, want %q", tc.file, tc.gateway, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package probe
+
+import (
+	"context"
+	"time"
+)
+
+// Simulated answers probes from RTT, called once per packet, e.g. with a
+// scenario's ping curve. An error from RTT counts as a lost packet. It does
+// not sleep between packets, so it is safe to use with a virtual clock.
+type Simulated struct {
+	RTT func(host string) (time.Duration, error)
+}
+
+func (s Simulated) Probe(ctx context.Context, t Target) (*Result, error) {
+	t = t.withDefaults()
+
+	var rtts []time.Duration
+	for i := 0; i < t.Count; i++ {
+		if rtt, err := s.RTT(t.Host); err == nil {
+			rtts = append(rtts, rtt)
+		}
+	}
+	return newResult(t, t.Count, rtts), nil
+}
@@ -0,0 +1,164 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"netshield/agent/internal/wifi"
+)
+
+// SystemProber runs the OS ping command through Runner (nil runs it
+// directly) and parses Windows, iputils or busybox output.
+type SystemProber struct {
+	Runner wifi.CommandRunner
+	// OS selects the ping syntax; empty uses runtime.GOOS. Replays set it
+	// to the OS the capture was recorded on.
+	OS string
+}
+
+func (p SystemProber) Probe(ctx context.Context, t Target) (*Result, error) {
+	t = t.withDefaults()
+	t.Method = MethodPing
+
+	runner := p.Runner
+	if runner == nil {
+		runner = wifi.ExecRunner{}
+	}
+	goos := p.OS
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+
+	// The arguments depend on the target alone, so a replay asks for the
+	// same command line the capture recorded.
+	count := strconv.Itoa(t.Count)
+	var args []string
+	switch goos {
+	case "windows":
+		// -w is the per-reply timeout in milliseconds.
+		args = []string{"-n", count, "-w", strconv.FormatInt(t.Timeout.Milliseconds(), 10), t.Host}
+	case "linux":
+		// -W is the per-reply timeout in seconds for iputils and busybox.
+		args = []string{"-c", count, "-W", strconv.Itoa(max(1, int(t.Timeout.Seconds()))), t.Host}
+	default:
+		// BSD and macOS take -W in milliseconds.
+		args = []string{"-c", count, "-W", strconv.FormatInt(t.Timeout.Milliseconds(), 10), t.Host}
+	}
+
+	// ping exits non-zero when replies are lost; the output still counts.
+	// The runner kills it at ctx's deadline, before it prints a summary
+	// if the burst outlasts the deadline.
+	out, err := runner.Run(ctx, "ping", args...)
+	res := ParsePing(out)
+	if res == nil && errors.Is(err, context.DeadlineExceeded) {
+		res = &Result{} // killed before any reply came back
+	}
+	if res == nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("could not parse ping output")
+	}
+	if res.Sent == 0 {
+		// No packet count was printed. A bare round-trip summary means
+		// every packet answered; otherwise ping was cut short and the
+		// packets it did not report are lost.
+		if len(res.RTTsMs) == 0 && res.AvgMs > 0 {
+			res.Sent, res.Received = t.Count, t.Count
+		} else {
+			res = resultFromMs(t, max(t.Count, len(res.RTTsMs)), res.RTTsMs)
+		}
+	}
+	res.Target = t.Host
+	res.Method = MethodPing
+	return res, nil
+}
+
+var (
+	// Reply lines carry a TTL in every language and format:
+	//   Reply from 8.8.8.8: bytes=32 time=13ms TTL=117        (Windows)
+	//   Antwort von 8.8.8.8: Bytes=32 Zeit<1ms TTL=117        (Windows, German)
+	//   64 bytes from 8.8.8.8: icmp_seq=1 ttl=117 time=13.2 ms (iputils)
+	//   64 bytes from 8.8.8.8: seq=0 ttl=117 time=13.245 ms    (busybox)
+	replyTimeRe = regexp.MustCompile(`[=<]\s*(\d+(?:[.,]\d+)?)\s*(?:ms|мс|毫秒)`)
+	// 3 packets transmitted, 3 received, 0% packet loss          (iputils)
+	// 3 packets transmitted, 3 packets received, 0% packet loss  (busybox)
+	unixPacketsRe = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	// rtt min/avg/max/mdev = 12.1/13.2/15.3/1.2 ms               (iputils)
+	// round-trip min/avg/max = 12.1/13.2/15.3 ms                 (busybox)
+	unixSummaryRe = regexp.MustCompile(`=\s*([\d.]+)/([\d.]+)/([\d.]+)(?:/([\d.]+))?\s*ms`)
+	// " = 4" in Windows' "Packets: Sent = 4, Received = 4, Lost = 0".
+	windowsValueRe = regexp.MustCompile(`\s=\s*(\d+)`)
+)
+
+// ParsePing parses the output of the Windows, iputils or busybox ping into
+// a Result, or returns nil if out is not ping output. Per-reply times are
+// preferred; the summary line is used when replies cannot be read.
+func ParsePing(out string) *Result {
+	var rtts []float64
+	sent, received := -1, -1
+
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(strings.ToLower(line), "ttl") {
+			if m := replyTimeRe.FindStringSubmatch(line); m != nil {
+				if v, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64); err == nil {
+					rtts = append(rtts, v)
+				}
+			}
+			continue
+		}
+		if m := unixPacketsRe.FindStringSubmatch(line); m != nil {
+			sent, _ = strconv.Atoi(m[1])
+			received, _ = strconv.Atoi(m[2])
+			continue
+		}
+		// The first Windows line with three " = N" values is the packet
+		// count (sent, received, lost); the round-trip line follows it.
+		if sent < 0 {
+			if m := windowsValueRe.FindAllStringSubmatch(line, -1); len(m) == 3 {
+				sent, _ = strconv.Atoi(m[0][1])
+				received, _ = strconv.Atoi(m[1][1])
+			}
+		}
+	}
+
+	if len(rtts) > 0 {
+		// Without a packet count Sent stays 0 and the caller fills it in.
+		if sent < 0 {
+			sent = 0
+		} else if sent < len(rtts) {
+			sent = len(rtts)
+		}
+		return resultFromMs(Target{}, sent, rtts)
+	}
+
+	// No reply lines: fall back to the summary.
+	res := &Result{}
+	if m := unixSummaryRe.FindStringSubmatch(out); m != nil {
+		res.MinMs, _ = strconv.ParseFloat(m[1], 64)
+		res.AvgMs, _ = strconv.ParseFloat(m[2], 64)
+		res.MaxMs, _ = strconv.ParseFloat(m[3], 64)
+		res.StdDevMs, _ = strconv.ParseFloat(m[4], 64)
+	} else if avg := wifi.ParsePingOutput(out); avg != nil && received != 0 {
+		res.MinMs, res.AvgMs, res.MaxMs = float64(avg.AvgMs), float64(avg.AvgMs), float64(avg.AvgMs)
+	} else if sent < 0 {
+		return nil
+	} else {
+		// Windows counts "Destination host unreachable" as a reply; with
+		// no reply times and no round-trip summary nothing came back.
+		received = 0
+	}
+	// Without a packet count Sent stays 0 and the caller fills it in.
+	if sent >= 0 {
+		res.Sent, res.Received = sent, received
+		if sent > 0 {
+			res.LossPct = float64(sent-received) / float64(sent) * 100
+		}
+	}
+	return res
+}
@@ -0,0 +1,155 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePingWindows(t *testing.T) {
+	out := `
+Pinging 8.8.8.8 with 32 bytes of data:
+Reply from 8.8.8.8: bytes=32 time=14ms TTL=117
+Reply from 8.8.8.8: bytes=32 time=12ms TTL=117
+Request timed out.
+Reply from 8.8.8.8: bytes=32 time<1ms TTL=117
+
+Ping statistics for 8.8.8.8:
+    Packets: Sent = 4, Received = 3, Lost = 1 (25% loss),
+Approximate round trip times in milli-seconds:
+    Minimum = 1ms, Maximum = 14ms, Average = 9ms
+`
+	res := ParsePing(out)
+	if res == nil || res.Sent != 4 || res.Received != 3 || res.LossPct != 25 || !res.HasRTT() {
+		t.Fatalf("ParsePing = %+v", res)
+	}
+	if res.MinMs != 1 || res.MaxMs != 14 {
+		t.Errorf("min/max = %v/%v", res.MinMs, res.MaxMs)
+	}
+}
+
+// Windows counts an unreachable answer from the gateway as received.
+func TestParsePingWindowsUnreachable(t *testing.T) {
+	out := `
+Pinging 8.8.8.8 with 32 bytes of data:
+Reply from 192.168.1.1: Destination host unreachable.
+Reply from 192.168.1.1: Destination host unreachable.
+Reply from 192.168.1.1: Destination host unreachable.
+
+Ping statistics for 8.8.8.8:
+    Packets: Sent = 3, Received = 3, Lost = 0 (0% loss),
+`
+	res := ParsePing(out)
+	if res == nil {
+		t.Fatal("ParsePing = nil")
+	}
+	if res.Sent != 3 || res.Received != 0 || res.LossPct != 100 || res.HasRTT() {
+		t.Errorf("ParsePing = %+v, want every packet lost", res)
+	}
+}
+
+func TestParsePingUnix(t *testing.T) {
+	out := `PING 1.1.1.1 (1.1.1.1) 56(84) bytes of data.
+64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=10.4 ms
+64 bytes from 1.1.1.1: icmp_seq=3 ttl=57 time=11.6 ms
+
+--- 1.1.1.1 ping statistics ---
+3 packets transmitted, 2 received, 33.3333% packet loss, time 2003ms
+rtt min/avg/max/mdev = 10.400/11.000/11.600/0.600 ms
+`
+	res := ParsePing(out)
+	if res == nil || res.Sent != 3 || res.Received != 2 || res.AvgMs != 11 || !res.HasRTT() {
+		t.Fatalf("ParsePing = %+v", res)
+	}
+
+	// ping -q prints only the summary.
+	quiet := `3 packets transmitted, 3 received, 0% packet loss, time 2003ms
+rtt min/avg/max/mdev = 10.400/11.000/11.600/0.600 ms
+`
+	if res := ParsePing(quiet); res == nil || res.Received != 3 || res.AvgMs != 11 || !res.HasRTT() {
+		t.Errorf("ParsePing(quiet) = %+v", res)
+	}
+}
+
+// pingRunner answers every command with out and err and remembers the
+// arguments it was called with.
+type pingRunner struct {
+	out  string
+	err  error
+	args [][]string
+}
+
+func (r *pingRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	r.args = append(r.args, args)
+	return r.out, r.err
+}
+
+var killed = fmt.Errorf("ping: %w", context.DeadlineExceeded)
+
+// A burst that outlasts the deadline is killed before ping prints its
+// summary; the replies it never reported are lost.
+func TestSystemProberKilled(t *testing.T) {
+	target := Target{Host: "1.1.1.1", Count: 5, Timeout: 2 * time.Second}
+	for _, tc := range []struct {
+		name     string
+		out      string
+		received int
+	}{
+		{"no output", "", 0},
+		{"header only", "PING 1.1.1.1 (1.1.1.1) 56(84) bytes of data.\n", 0},
+		{"two replies", `PING 1.1.1.1 (1.1.1.1) 56(84) bytes of data.
+64 bytes from 1.1.1.1: icmp_seq=1 ttl=57 time=10.4 ms
+64 bytes from 1.1.1.1: icmp_seq=2 ttl=57 time=11.6 ms
+`, 2},
+		{"windows timeouts", `
+Pinging 1.1.1.1 with 32 bytes of data:
+Request timed out.
+Request timed out.
+`, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := SystemProber{Runner: &pingRunner{out: tc.out, err: killed}, OS: "linux"}
+			res, err := p.Probe(context.Background(), target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := float64(5-tc.received) / 5 * 100
+			if res.Sent != 5 || res.Received != tc.received || res.LossPct != want {
+				t.Errorf("sent %d received %d loss %v%%, want 5/%d/%v%%", res.Sent, res.Received, res.LossPct, tc.received, want)
+			}
+		})
+	}
+
+	// Not a deadline: ping could not run at all.
+	p := SystemProber{Runner: &pingRunner{err: errors.New("exec: \"ping\": executable file not found")}}
+	if _, err := p.Probe(context.Background(), target); err == nil {
+		t.Error("Probe succeeded without ping")
+	}
+}
+
+// The command line depends on the target only, so a replay matches the
+// capture however much time was left on the deadline.
+func TestSystemProberArgs(t *testing.T) {
+	target := Target{Host: "8.8.8.8", Count: 4, Timeout: 1500 * time.Millisecond}
+	for goos, want := range map[string]string{
+		"windows": "-n 4 -w 1500 8.8.8.8",
+		"linux":   "-c 4 -W 1 8.8.8.8",
+		"darwin":  "-c 4 -W 1500 8.8.8.8",
+	} {
+		r := &pingRunner{out: "3 packets transmitted, 3 received, 0% packet loss\n"}
+		p := SystemProber{Runner: r, OS: goos}
+		for _, d := range []time.Duration{time.Second, 5 * time.Second} {
+			ctx, cancel := context.WithTimeout(context.Background(), d)
+			p.Probe(ctx, target)
+			cancel()
+		}
+		for _, args := range r.args {
+			if got := strings.Join(args, " "); got != want {
+				t.Errorf("%s: ping %s, want %s", goos, got, want)
+			}
+		}
+	}
+}
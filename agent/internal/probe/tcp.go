@@ -0,0 +1,45 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+)
+
+// TCPProber times TCP handshakes to Host:Port. It works on networks that
+// filter ICMP, at the cost of measuring the server's accept path as well.
+type TCPProber struct{}
+
+func (TCPProber) Probe(ctx context.Context, t Target) (*Result, error) {
+	t = t.withDefaults()
+	t.Method = MethodTCP
+
+	// Resolve once so the handshake times do not include DNS.
+	ip, err := resolve(ctx, t.Host)
+	if err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(t.Port))
+
+	var rtts []time.Duration
+	sent := 0
+	for i := 0; i < t.Count; i++ {
+		if i > 0 {
+			if err := wait(ctx, t.Interval); err != nil {
+				break
+			}
+		}
+
+		d := net.Dialer{Timeout: t.Timeout}
+		start := time.Now()
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		rtt := time.Since(start)
+		sent++
+		if err == nil {
+			conn.Close()
+			rtts = append(rtts, rtt)
+		}
+	}
+	return newResult(t, sent, rtts), nil
+}
@@ -0,0 +1,193 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"netshield/agent/internal/wifi"
+)
+
+// TraceOptions bound a traceroute.
+type TraceOptions struct {
+	MaxHops int           // default 30
+	Count   int           // probes per hop; default 3
+	Timeout time.Duration // wait per hop; default 1s
+}
+
+func (o TraceOptions) withDefaults() TraceOptions {
+	if o.MaxHops <= 0 {
+		o.MaxHops = 30
+	}
+	if o.Count <= 0 {
+		o.Count = 3
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = time.Second
+	}
+	return o
+}
+
+// Hop is one TTL of a traceroute, summarised the way mtr does.
+type Hop struct {
+	TTL      int     `json:"ttl"`
+	Addr     string  `json:"addr,omitempty"` // empty when nothing answered
+	Sent     int     `json:"sent"`
+	Received int     `json:"received"`
+	LossPct  float64 `json:"loss_pct"`
+	AvgMs    float64 `json:"avg_ms"`
+	BestMs   float64 `json:"best_ms"`
+	WorstMs  float64 `json:"worst_ms"`
+}
+
+// Trace is the path to Target, nearest hop first.
+type Trace struct {
+	Target  string `json:"target"`
+	Method  string `json:"method"` // "udp" (native) or "traceroute"/"tracert"
+	Reached bool   `json:"reached"`
+	Hops    []Hop  `json:"hops"`
+}
+
+// Tracer runs a traceroute. Like Prober it fails only when the trace
+// could not run at all.
+type Tracer interface {
+	Trace(ctx context.Context, host string, o TraceOptions) (*Trace, error)
+}
+
+// TraceFallback tries each tracer in turn until one is able to run.
+type TraceFallback []Tracer
+
+func (f TraceFallback) Trace(ctx context.Context, host string, o TraceOptions) (*Trace, error) {
+	err := fmt.Errorf("trace %s: no tracer", host)
+	for _, t := range f {
+		var tr *Trace
+		if tr, err = t.Trace(ctx, host, o); err == nil {
+			return tr, nil
+		}
+	}
+	return nil, err
+}
+
+// DefaultTracer traces natively where the OS allows it without privileges
+// (Linux) and falls back to the system traceroute or tracert, which goes
+// through runner so it can be recorded and replayed.
+func DefaultTracer(runner wifi.CommandRunner) Tracer {
+	return TraceFallback{UDPTracer{}, SystemTracer{Runner: runner}}
+}
+
+// newHop summarises the replies one TTL got.
+func newHop(ttl, sent int, addr string, rttsMs []float64) Hop {
+	h := Hop{TTL: ttl, Addr: addr, Sent: sent, Received: len(rttsMs)}
+	if sent > 0 {
+		h.LossPct = float64(sent-len(rttsMs)) / float64(sent) * 100
+	}
+	if len(rttsMs) == 0 {
+		return h
+	}
+	h.BestMs, h.WorstMs = rttsMs[0], rttsMs[0]
+	var sum float64
+	for _, v := range rttsMs {
+		sum += v
+		h.BestMs = math.Min(h.BestMs, v)
+		h.WorstMs = math.Max(h.WorstMs, v)
+	}
+	h.AvgMs = sum / float64(len(rttsMs))
+	return h
+}
+
+// SystemTracer runs `tracert -d` on Windows and `traceroute -n` elsewhere.
+type SystemTracer struct {
+	Runner wifi.CommandRunner
+	OS     string // empty uses runtime.GOOS
+}
+
+func (s SystemTracer) Trace(ctx context.Context, host string, o TraceOptions) (*Trace, error) {
+	o = o.withDefaults()
+	runner := s.Runner
+	if runner == nil {
+		runner = wifi.ExecRunner{}
+	}
+	goos := s.OS
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+
+	// Resolve here so the hops can be compared with the target.
+	ip, err := resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	target := ip.String()
+
+	name, args := "traceroute", []string{"-n", "-q", strconv.Itoa(o.Count), "-w", strconv.Itoa(int(math.Ceil(o.Timeout.Seconds()))), "-m", strconv.Itoa(o.MaxHops), target}
+	if goos == "windows" {
+		// tracert always sends three probes per hop.
+		name, args = "tracert", []string{"-d", "-h", strconv.Itoa(o.MaxHops), "-w", strconv.Itoa(int(o.Timeout.Milliseconds())), target}
+	}
+	out, err := runner.Run(ctx, name, args...)
+	hops := ParseTraceroute(out)
+	if len(hops) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("could not parse %s output", name)
+	}
+	tr := &Trace{Target: target, Method: name, Hops: hops}
+	last := hops[len(hops)-1]
+	tr.Reached = last.Addr == target && last.Received > 0
+	return tr, nil
+}
+
+// ParseTraceroute reads Linux/BSD traceroute and Windows tracert output.
+// Every hop line starts with the TTL, and the rest is probes ("*" for a
+// lost one, "12.3 ms" or "<1 ms" for a reply) and addresses, in either
+// order; anything else, like "Request timed out." or "!H", is skipped.
+//
+//	1  192.168.1.1  1.123 ms  0.981 ms  1.002 ms          (traceroute -n)
+//	2  * * *
+//	 1    <1 ms    <1 ms     1 ms  192.168.1.1            (tracert -d)
+//	 2     *        *        *     Request timed out.
+func ParseTraceroute(out string) []Hop {
+	var hops []Hop
+	for _, line := range strings.Split(strings.ReplaceAll(out, "\r", ""), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		ttl, err := strconv.Atoi(f[0])
+		if err != nil || ttl <= 0 {
+			continue
+		}
+		var addr string
+		var rtts []float64
+		sent := 0
+		for i := 1; i < len(f); i++ {
+			tok := f[i]
+			switch {
+			case tok == "*":
+				sent++
+			case net.ParseIP(strings.Trim(tok, "()[]")) != nil:
+				if addr == "" {
+					addr = strings.Trim(tok, "()[]")
+				}
+			case i+1 < len(f) && f[i+1] == "ms":
+				v, err := strconv.ParseFloat(strings.TrimPrefix(strings.ReplaceAll(tok, ",", "."), "<"), 64)
+				if err == nil {
+					rtts = append(rtts, v)
+					sent++
+				}
+				i++
+			}
+		}
+		if sent == 0 {
+			continue // a header or footer that happened to start with a number
+		}
+		hops = append(hops, newHop(ttl, sent, addr, rtts))
+	}
+	return hops
+}
@@ -0,0 +1,178 @@
+//go:build linux
+
+package probe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// UDPTracer sends UDP probes with rising TTLs from an ordinary datagram
+// socket and reads the ICMP errors they provoke from the socket's error
+// queue (IP_RECVERR), the way tracepath does, so it needs no privileges.
+// IPv4 only; IPv6 targets fail so TraceFallback moves on.
+type UDPTracer struct{}
+
+const (
+	tracePortBase    = 33434 // the traditional traceroute port range
+	soEEOriginICMP   = 2     // SO_EE_ORIGIN_ICMP
+	icmpDestUnreach  = 3
+	icmpPortUnreach  = 3 // code of icmpDestUnreach sent by the target itself
+	icmpTimeExceeded = 11
+)
+
+func (UDPTracer) Trace(ctx context.Context, host string, o TraceOptions) (*Trace, error) {
+	o = o.withDefaults()
+	ip, err := resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("trace %s: native tracer is IPv4 only", host)
+	}
+
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("trace %s: open socket: %w", host, err)
+	}
+	defer unix.Close(fd)
+	if err := unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_RECVERR, 1); err != nil {
+		return nil, fmt.Errorf("trace %s: IP_RECVERR: %w", host, err)
+	}
+	dst := unix.SockaddrInet4{}
+	copy(dst.Addr[:], ip4)
+
+	tr := &Trace{Target: ip4.String(), Method: "udp"}
+	silent := 0
+	seq := uint16(0)
+	for ttl := 1; ttl <= o.MaxHops && ctx.Err() == nil; ttl++ {
+		if err := unix.SetsockoptInt(fd, unix.IPPROTO_IP, unix.IP_TTL, ttl); err != nil {
+			return nil, fmt.Errorf("trace %s: set TTL: %w", host, err)
+		}
+
+		// Send the hop's probes together, each to its own port and
+		// carrying its sequence number, then collect what comes back.
+		sentAt := make(map[uint16]time.Time, o.Count)
+		for i := 0; i < o.Count; i++ {
+			seq++
+			dst.Port = tracePortBase + int(seq%1000)
+			payload := make([]byte, 8)
+			binary.BigEndian.PutUint16(payload, seq)
+			sentAt[seq] = time.Now()
+			// A queued error from an earlier probe may surface here; it is
+			// read from the error queue like the others.
+			err := unix.Sendto(fd, payload, 0, &dst)
+			if err != nil && !errors.Is(err, unix.EHOSTUNREACH) && !errors.Is(err, unix.ECONNREFUSED) {
+				return nil, fmt.Errorf("trace %s: send: %w", host, err)
+			}
+		}
+
+		addr, rtts, reached := collectHop(ctx, fd, sentAt, o.Timeout)
+		tr.Hops = append(tr.Hops, newHop(ttl, o.Count, addr, rtts))
+		if reached {
+			tr.Reached = true
+			break
+		}
+		// Past five silent hops in a row the rest is unlikely to answer.
+		silent++
+		if len(rtts) > 0 {
+			silent = 0
+		}
+		if silent >= 5 {
+			break
+		}
+	}
+	return tr, nil
+}
+
+// collectHop reads ICMP errors for the probes in sentAt until each has
+// one or timeout passes. reached is set by a port unreachable from the
+// target itself.
+func collectHop(ctx context.Context, fd int, sentAt map[uint16]time.Time, timeout time.Duration) (addr string, rtts []float64, reached bool) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	buf := make([]byte, 512)
+	oob := make([]byte, 512)
+	for len(sentAt) > 0 {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return
+		}
+		// POLLERR is reported whenever the error queue is not empty.
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		if n, err := unix.Poll(fds, int(wait.Milliseconds())+1); err != nil && !errors.Is(err, unix.EINTR) || n == 0 {
+			continue
+		}
+		if fds[0].Revents&unix.POLLERR == 0 {
+			// A real UDP answer from the target: it is reachable.
+			if n, _, _, _, err := unix.Recvmsg(fd, buf, nil, unix.MSG_DONTWAIT); err == nil && n >= 2 {
+				if at, ok := sentAt[binary.BigEndian.Uint16(buf)]; ok {
+					rtts = append(rtts, msSince(at))
+				}
+				reached = true
+			}
+			return
+		}
+
+		n, oobn, _, _, err := unix.Recvmsg(fd, buf, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+		if err != nil {
+			continue
+		}
+		// The error queue returns the probe's own payload.
+		if n < 2 {
+			continue
+		}
+		at, ok := sentAt[binary.BigEndian.Uint16(buf)]
+		if !ok {
+			continue // a late reply for an earlier hop
+		}
+		from, icmpType, code, ok := parseRecvErr(oob[:oobn])
+		if !ok {
+			continue
+		}
+		delete(sentAt, binary.BigEndian.Uint16(buf))
+		rtts = append(rtts, msSince(at))
+		if addr == "" {
+			addr = from
+		}
+		if icmpType == icmpDestUnreach && code == icmpPortUnreach {
+			reached = true
+		}
+	}
+	return
+}
+
+// parseRecvErr decodes the IP_RECVERR control message: a struct
+// sock_extended_err followed by the sockaddr_in of the router that sent
+// the ICMP error.
+func parseRecvErr(oob []byte) (from string, icmpType, code uint8, ok bool) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	for _, m := range msgs {
+		if m.Header.Level != unix.IPPROTO_IP || m.Header.Type != unix.IP_RECVERR {
+			continue
+		}
+		const eeSize = 16 // sizeof(struct sock_extended_err)
+		if len(m.Data) < eeSize+8 || m.Data[4] != soEEOriginICMP {
+			continue
+		}
+		icmpType, code = m.Data[5], m.Data[6]
+		if icmpType != icmpTimeExceeded && icmpType != icmpDestUnreach {
+			continue
+		}
+		// sockaddr_in: family (2 bytes), port (2 bytes), address (4 bytes).
+		return net.IP(m.Data[eeSize+4 : eeSize+8]).String(), icmpType, code, true
+	}
+	return "", 0, 0, false
+}
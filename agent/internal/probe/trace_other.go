@@ -0,0 +1,16 @@
+//go:build !linux
+
+package probe
+
+import (
+	"context"
+	"errors"
+)
+
+// UDPTracer needs the Linux error queue; elsewhere it always fails so
+// TraceFallback moves on to the system command.
+type UDPTracer struct{}
+
+func (UDPTracer) Trace(context.Context, string, TraceOptions) (*Trace, error) {
+	return nil, errors.New("native traceroute is only supported on Linux")
+}
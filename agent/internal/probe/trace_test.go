@@ -0,0 +1,69 @@
+package probe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// Each testdata/trace file is the output of `traceroute -n` or
+// `tracert -d` as the agent runs them.
+func TestParseTraceroute(t *testing.T) {
+	for _, tc := range []struct {
+		file string
+		want []Hop // TTL, Addr, Sent, Received, BestMs and WorstMs are compared
+	}{
+		{"linux.txt", []Hop{
+			{TTL: 1, Addr: "192.168.1.1", Sent: 3, Received: 3, BestMs: 0.981, WorstMs: 1.123},
+			{TTL: 2, Addr: "10.20.0.1", Sent: 3, Received: 3, BestMs: 8.38, WorstMs: 9.117},
+			{TTL: 3, Sent: 3},
+			// Two routers answered; the hop is named after the first.
+			{TTL: 4, Addr: "72.14.215.85", Sent: 3, Received: 2, BestMs: 12.605, WorstMs: 12.901},
+			{TTL: 5, Addr: "8.8.8.8", Sent: 3, Received: 3, BestMs: 12.877, WorstMs: 13.214},
+		}},
+		{"unreachable.txt", []Hop{
+			{TTL: 1, Addr: "192.168.1.1", Sent: 3, Received: 3, BestMs: 0.79, WorstMs: 0.873},
+			{TTL: 2, Addr: "192.168.1.1", Sent: 3, Received: 3, BestMs: 3061.361, WorstMs: 3061.402},
+		}},
+		{"darwin.txt", []Hop{
+			{TTL: 1, Addr: "192.168.0.1", Sent: 3, Received: 3, BestMs: 1.877, WorstMs: 2.345},
+			{TTL: 2, Addr: "100.64.0.1", Sent: 3, Received: 2, BestMs: 9.87, WorstMs: 10.114},
+			{TTL: 3, Addr: "1.1.1.1", Sent: 3, Received: 3, BestMs: 11.048, WorstMs: 11.532},
+		}},
+		{"tracert-en.txt", []Hop{
+			{TTL: 1, Addr: "192.168.1.1", Sent: 3, Received: 3, BestMs: 1, WorstMs: 1},
+			{TTL: 2, Addr: "10.20.0.1", Sent: 3, Received: 3, BestMs: 7, WorstMs: 9},
+			{TTL: 3, Sent: 3},
+			{TTL: 4, Addr: "72.14.215.85", Sent: 3, Received: 2, BestMs: 12, WorstMs: 13},
+			{TTL: 5, Addr: "8.8.8.8", Sent: 3, Received: 3, BestMs: 12, WorstMs: 13},
+		}},
+		{"tracert-de.txt", []Hop{
+			{TTL: 1, Addr: "192.168.178.1", Sent: 3, Received: 3, BestMs: 1, WorstMs: 1},
+			{TTL: 2, Sent: 3},
+			{TTL: 3, Addr: "8.8.8.8", Sent: 3, Received: 3, BestMs: 10, WorstMs: 11},
+		}},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			got := ParseTraceroute(fixture(t, filepath.Join("trace", tc.file)))
+			if len(got) != len(tc.want) {
+				t.Fatalf("%d hops, want %d: %+v", len(got), len(tc.want), got)
+			}
+			for i, w := range tc.want {
+				g := got[i]
+				if g.TTL != w.TTL || g.Addr != w.Addr || g.Sent != w.Sent || g.Received != w.Received ||
+					g.BestMs != w.BestMs || g.WorstMs != w.WorstMs {
+					t.Errorf("hop %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
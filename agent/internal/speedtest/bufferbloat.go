@@ -0,0 +1,234 @@
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BufferbloatResult compares round trips on an idle link with round trips
+// while it is saturated in each direction. Latencies are medians.
+type BufferbloatResult struct {
+	IdleMs       float64   `json:"idle_ms"`
+	LoadedDownMs float64   `json:"loaded_down_ms"`
+	LoadedUpMs   float64   `json:"loaded_up_ms"`
+	DeltaMs      float64   `json:"delta_ms"` // worse direction minus idle
+	Grade        string    `json:"grade"`
+	At           time.Time `json:"at"`
+	Interface    string    `json:"interface"`
+	SSID         string    `json:"ssid"`
+}
+
+// BloatGrade grades the latency a link adds under load, on the scale
+// popularised by the Waveform and DSLReports tests.
+func BloatGrade(deltaMs float64) string {
+	switch {
+	case deltaMs < 5:
+		return "A+"
+	case deltaMs < 30:
+		return "A"
+	case deltaMs < 60:
+		return "B"
+	case deltaMs < 200:
+		return "C"
+	case deltaMs < 400:
+		return "D"
+	}
+	return "F"
+}
+
+const (
+	bloatStreams  = 4                      // parallel transfers per direction
+	bloatRampUp   = time.Second            // let queues fill before sampling
+	bloatInterval = 200 * time.Millisecond // between latency samples
+	bloatIdle     = 10                     // idle samples
+)
+
+// Bufferbloat measures round trips to the server idle, then while
+// bloatStreams downloads and then uploads saturate the link for
+// MaxDuration each. Round trips are TCP handshakes with the server, so
+// nothing beyond the speedtest endpoints is needed.
+func (t *Tester) Bufferbloat(ctx context.Context) (*BufferbloatResult, error) {
+	if t.BaseURL == "" {
+		return nil, errors.New("bufferbloat: no server URL configured")
+	}
+	addr, err := hostPort(t.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("bufferbloat: %w", err)
+	}
+
+	var idle []float64
+	for i := 0; i < bloatIdle; i++ {
+		if rtt, err := connectRTT(ctx, addr); err == nil {
+			idle = append(idle, rtt)
+		}
+		if err := sleep(ctx, bloatInterval); err != nil {
+			return nil, err
+		}
+	}
+	if len(idle) == 0 {
+		return nil, fmt.Errorf("bufferbloat: %s did not answer", addr)
+	}
+
+	down, err := t.underLoad(ctx, addr, t.saturateDown)
+	if err != nil {
+		return nil, fmt.Errorf("bufferbloat download: %w", err)
+	}
+	up, err := t.underLoad(ctx, addr, t.saturateUp)
+	if err != nil {
+		return nil, fmt.Errorf("bufferbloat upload: %w", err)
+	}
+
+	res := &BufferbloatResult{IdleMs: median(idle), LoadedDownMs: down, LoadedUpMs: up}
+	res.DeltaMs = max(res.LoadedDownMs, res.LoadedUpMs) - res.IdleMs
+	if res.DeltaMs < 0 {
+		res.DeltaMs = 0
+	}
+	res.Grade = BloatGrade(res.DeltaMs)
+	return res, nil
+}
+
+// underLoad runs bloatStreams copies of saturate for MaxDuration and
+// returns the median round trip sampled meanwhile. A handshake that times
+// out counts as the whole wait, since that is what an application sees.
+func (t *Tester) underLoad(ctx context.Context, addr string, saturate func(context.Context)) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.maxDuration())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < bloatStreams; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			saturate(ctx)
+		}()
+	}
+	defer wg.Wait()
+
+	if err := sleep(ctx, bloatRampUp); err != nil {
+		return 0, errors.New("link test ended before sampling")
+	}
+	var loaded []float64
+	for ctx.Err() == nil {
+		start := time.Now()
+		if rtt, err := connectRTT(ctx, addr); err == nil {
+			loaded = append(loaded, rtt)
+		} else if ctx.Err() == nil {
+			loaded = append(loaded, msSince(start))
+		}
+		sleep(ctx, bloatInterval)
+	}
+	if len(loaded) == 0 {
+		return 0, errors.New("no latency samples under load")
+	}
+	return median(loaded), nil
+}
+
+// saturateDown downloads back to back until ctx is done.
+func (t *Tester) saturateDown(ctx context.Context) {
+	u := fmt.Sprintf("%s/speedtest/download?bytes=%d", strings.TrimRight(t.BaseURL, "/"), t.maxBytes())
+	for ctx.Err() == nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return
+		}
+		resp, err := t.client().Do(req)
+		if err != nil {
+			sleep(ctx, bloatInterval) // do not spin on a refused connection
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// saturateUp uploads back to back until ctx is done.
+func (t *Tester) saturateUp(ctx context.Context) {
+	u := strings.TrimRight(t.BaseURL, "/") + "/speedtest/upload"
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for ctx.Err() == nil {
+		deadline, _ := ctx.Deadline()
+		body := &payload{remaining: t.maxBytes(), deadline: deadline, rng: rng}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		resp, err := t.client().Do(req)
+		if err != nil {
+			sleep(ctx, bloatInterval)
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// connectRTT times one TCP handshake, in milliseconds.
+func connectRTT(ctx context.Context, addr string) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	rtt := msSince(start)
+	conn.Close()
+	return rtt, nil
+}
+
+// hostPort resolves the host once so lookups are not timed as latency.
+func hostPort(base string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	ips, err := net.LookupHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ips[0], port), nil
+}
+
+func median(v []float64) float64 {
+	s := append([]float64(nil), v...)
+	sort.Float64s(s)
+	if n := len(s); n%2 == 0 {
+		return (s[n/2-1] + s[n/2]) / 2
+	}
+	return s[len(s)/2]
+}
+
+func msSince(t time.Time) float64 {
+	return float64(time.Since(t)) / float64(time.Millisecond)
+}
+
+// sleep waits for d unless ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
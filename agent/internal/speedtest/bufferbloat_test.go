@@ -0,0 +1,28 @@
+package speedtest
+
+import "testing"
+
+func TestBloatGrade(t *testing.T) {
+	for _, tc := range []struct {
+		deltaMs float64
+		want    string
+	}{
+		{-3, "A+"}, // loaded pings came back faster than idle ones
+		{0, "A+"},
+		{4.9, "A+"},
+		{5, "A"},
+		{29.9, "A"},
+		{30, "B"},
+		{59.9, "B"},
+		{60, "C"},
+		{199.9, "C"},
+		{200, "D"},
+		{399.9, "D"},
+		{400, "F"},
+		{2500, "F"},
+	} {
+		if got := BloatGrade(tc.deltaMs); got != tc.want {
+			t.Errorf("BloatGrade(%v) = %s, want %s", tc.deltaMs, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package speedtest measures download and upload throughput against the
+// shieldserver's /speedtest endpoints. Each direction stops at MaxBytes or
+// MaxDuration, whichever comes first, so a test on a slow link stays short.
+package speedtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tester runs throughput tests against BaseURL (e.g. "http://server:8082").
+type Tester struct {
+	BaseURL     string
+	MaxBytes    int64         // per direction; default 25 MB
+	MaxDuration time.Duration // per direction; default 8s
+	Client      *http.Client  // nil uses http.DefaultClient
+}
+
+// Result is one download+upload measurement.
+type Result struct {
+	DownMbps  float64   `json:"down_mbps"`
+	UpMbps    float64   `json:"up_mbps"`
+	DownBytes int64     `json:"down_bytes"`
+	UpBytes   int64     `json:"up_bytes"`
+	At        time.Time `json:"at"`
+	// The link the test ran on, so a result is not reported for another network.
+	Interface string `json:"interface"`
+	SSID      string `json:"ssid"`
+}
+
+func (t *Tester) maxBytes() int64 {
+	if t.MaxBytes <= 0 {
+		return 25 << 20
+	}
+	return t.MaxBytes
+}
+
+func (t *Tester) maxDuration() time.Duration {
+	if t.MaxDuration <= 0 {
+		return 8 * time.Second
+	}
+	return t.MaxDuration
+}
+
+func (t *Tester) client() *http.Client {
+	if t.Client == nil {
+		return http.DefaultClient
+	}
+	return t.Client
+}
+
+// Run measures download then upload.
+func (t *Tester) Run(ctx context.Context) (*Result, error) {
+	if t.BaseURL == "" {
+		return nil, errors.New("speedtest: no server URL configured")
+	}
+	down, downBytes, err := t.download(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("speedtest download: %w", err)
+	}
+	up, upBytes, err := t.upload(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("speedtest upload: %w", err)
+	}
+	return &Result{DownMbps: down, UpMbps: up, DownBytes: downBytes, UpBytes: upBytes}, nil
+}
+
+func (t *Tester) download(ctx context.Context) (float64, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.maxDuration())
+	defer cancel()
+
+	url := fmt.Sprintf("%s/speedtest/download?bytes=%d", strings.TrimRight(t.BaseURL, "/"), t.maxBytes())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := time.Now()
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	// Hitting the time limit mid-body is the early stop, not a failure.
+	n, err := io.Copy(io.Discard, resp.Body)
+	elapsed := time.Since(start)
+	if err != nil && ctx.Err() == nil {
+		return 0, 0, err
+	}
+	return mbps(n, elapsed), n, nil
+}
+
+func (t *Tester) upload(ctx context.Context) (float64, int64, error) {
+	body := &payload{
+		remaining: t.maxBytes(),
+		deadline:  time.Now().Add(t.maxDuration()),
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	url := strings.TrimRight(t.BaseURL, "/") + "/speedtest/upload"
+	ctx, cancel := context.WithTimeout(ctx, t.maxDuration()+5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	start := time.Now()
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	elapsed := time.Since(start)
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("server returned %s", resp.Status)
+	}
+	return mbps(body.sent, elapsed), body.sent, nil
+}
+
+func mbps(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) * 8 / d.Seconds() / 1e6
+}
+
+// payload is an upload body that ends after remaining bytes or at deadline.
+type payload struct {
+	remaining int64
+	deadline  time.Time
+	sent      int64
+	rng       *rand.Rand
+}
+
+func (p *payload) Read(b []byte) (int, error) {
+	if p.remaining <= 0 || time.Now().After(p.deadline) {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > p.remaining {
+		b = b[:p.remaining]
+	}
+	// Random bytes, so compression on the path cannot inflate the result.
+	p.rng.Read(b)
+	p.remaining -= int64(len(b))
+	p.sent += int64(len(b))
+	return len(b), nil
+}
@@ -0,0 +1,86 @@
+package wifi
+
+import (
+	"context"
+	"fmt"
+)
+
+// LinuxManager implements Manager using NetworkManager's `nmcli` on Linux.
+type LinuxManager struct {
+	Runner CommandRunner // nil runs nmcli directly
+}
+
+// runNmcli executes an nmcli command in terse mode and returns its output.
+func (l LinuxManager) runNmcli(ctx context.Context, args ...string) (string, error) {
+	out, err := runnerOrExec(l.Runner).Run(ctx, "nmcli", append([]string{"-t"}, args...)...)
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// ListProfiles parses `nmcli -t -f NAME,TYPE connection show`.
+func (l LinuxManager) ListProfiles(ctx context.Context) ([]WifiProfile, error) {
+	out, err := l.runNmcli(ctx, "-f", "NAME,TYPE", "connection", "show")
+	if err != nil {
+		return nil, err
+	}
+	return ParseNmcliProfiles(out), nil
+}
+
+func (l LinuxManager) GetCurrentStatus(ctx context.Context) (*WifiStatus, error) {
+	devOut, wifiOut, err := l.statusOutputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := ParseNmcliStatus(devOut, wifiOut)
+	if status == nil {
+		return nil, fmt.Errorf("no active Wi-Fi interface found")
+	}
+	return status, nil
+}
+
+// GetInterfaceStatuses returns every Wi-Fi device NetworkManager knows about.
+func (l LinuxManager) GetInterfaceStatuses(ctx context.Context) ([]*WifiStatus, error) {
+	devOut, wifiOut, err := l.statusOutputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseNmcliInterfaces(devOut, wifiOut), nil
+}
+
+func (l LinuxManager) statusOutputs(ctx context.Context) (string, string, error) {
+	devOut, err := l.runNmcli(ctx, "-f", "DEVICE,TYPE,STATE,CONNECTION", "device", "status")
+	if err != nil {
+		return "", "", err
+	}
+	// --rescan no: report the cached scan instead of blocking on a new one.
+	wifiOut, err := l.runNmcli(ctx, "-f", "IN-USE,SSID,BSSID,SIGNAL,CHAN,FREQ,RATE,SECURITY,DEVICE",
+		"device", "wifi", "list", "--rescan", "no")
+	if err != nil {
+		return "", "", err
+	}
+	return devOut, wifiOut, nil
+}
+
+// nmcliConnectWait caps how long `nmcli connection up` waits for the
+// activation; left alone it waits up to 90 seconds.
+const nmcliConnectWait = "30"
+
+func (l LinuxManager) Connect(ctx context.Context, profile WifiProfile) error {
+	args := []string{"--wait", nmcliConnectWait, "connection", "up", "id", profile.RawName}
+	if profile.InterfaceName != "" {
+		args = append(args, "ifname", profile.InterfaceName)
+	}
+	_, err := l.runNmcli(ctx, args...)
+	return err
+}
+
+// ScanNetworks parses `nmcli -t -f SSID,BSSID,SIGNAL,CHAN,FREQ,SECURITY,DEVICE device wifi list`.
+func (l LinuxManager) ScanNetworks(ctx context.Context) ([]VisibleNetwork, error) {
+	out, err := l.runNmcli(ctx, "-f", "SSID,BSSID,SIGNAL,CHAN,FREQ,SECURITY,DEVICE", "device", "wifi", "list")
+	if err != nil {
+		return nil, err
+	}
+	return ParseNmcliNetworks(out), nil
+}
@@ -0,0 +1,517 @@
+package wifi
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// netsh translates its labels into the Windows display language, so the
+// parsers look every label up here instead of matching English text.
+// Anything not in the table falls back to parseInterfacesByPosition.
+
+type netshField int
+
+const (
+	fieldUnknown netshField = iota
+	fieldName
+	fieldState
+	fieldSSID
+	fieldBSSID
+	fieldNetworkType
+	fieldRadioType
+	fieldAuthentication
+	fieldCipher
+	fieldBand
+	fieldChannel
+	fieldReceiveRate
+	fieldTransmitRate
+	fieldSignal
+	fieldRSSI
+	fieldProfile
+	fieldInterfaceName
+	fieldBasicRates
+	fieldOtherRates
+)
+
+// netshLocale holds the labels one display language uses. Field labels are
+// lower case with any "(Mbps)"-style unit suffix removed.
+type netshLocale struct {
+	Lang           string
+	AllUserProfile string
+	// "Profiles on interface Wi-Fi:" is split around the interface name.
+	ProfilesPrefix, ProfilesSuffix string
+	Average                        string // ping round-trip average
+	Fields                         map[string]netshField
+}
+
+var netshLocales = []netshLocale{
+	{
+		Lang:           "en",
+		AllUserProfile: "All User Profile",
+		ProfilesPrefix: "Profiles on interface ",
+		ProfilesSuffix: ":",
+		Average:        "Average",
+		Fields: map[string]netshField{
+			"name":           fieldName,
+			"state":          fieldState,
+			"ssid":           fieldSSID,
+			"bssid":          fieldBSSID,
+			"ap bssid":       fieldBSSID,
+			"network type":   fieldNetworkType,
+			"radio type":     fieldRadioType,
+			"authentication": fieldAuthentication,
+			"cipher":         fieldCipher,
+			"encryption":     fieldCipher,
+			"band":           fieldBand,
+			"channel":        fieldChannel,
+			"receive rate":   fieldReceiveRate,
+			"transmit rate":  fieldTransmitRate,
+			"signal":         fieldSignal,
+			"rssi":           fieldRSSI,
+			"profile":        fieldProfile,
+			"interface name": fieldInterfaceName,
+			"basic rates":    fieldBasicRates,
+			"other rates":    fieldOtherRates,
+		},
+	},
+	{
+		Lang:           "de",
+		AllUserProfile: "Profil für alle Benutzer",
+		ProfilesPrefix: "Profile auf Schnittstelle ",
+		ProfilesSuffix: ":",
+		Average:        "Mittelwert",
+		Fields: map[string]netshField{
+			"name":               fieldName,
+			"status":             fieldState,
+			"ssid":               fieldSSID,
+			"bssid":              fieldBSSID,
+			"ap-bssid":           fieldBSSID,
+			"ap bssid":           fieldBSSID,
+			"netzwerktyp":        fieldNetworkType,
+			"funktyp":            fieldRadioType,
+			"authentifizierung":  fieldAuthentication,
+			"verschlüsselung":    fieldCipher,
+			"band":               fieldBand,
+			"kanal":              fieldChannel,
+			"empfangsrate":       fieldReceiveRate,
+			"übertragungsrate":   fieldTransmitRate,
+			"signal":             fieldSignal,
+			"rssi":               fieldRSSI,
+			"profil":             fieldProfile,
+			"schnittstellenname": fieldInterfaceName,
+			"basisraten":         fieldBasicRates,
+			"andere raten":       fieldOtherRates,
+		},
+	},
+	{
+		Lang:           "fr",
+		AllUserProfile: "Profil Tous les utilisateurs",
+		ProfilesPrefix: "Profils sur l'interface ",
+		ProfilesSuffix: ":",
+		Average:        "Moyenne",
+		Fields: map[string]netshField{
+			"nom":                fieldName,
+			"état":               fieldState,
+			"ssid":               fieldSSID,
+			"bssid":              fieldBSSID,
+			"bssid de l'ap":      fieldBSSID,
+			"type de réseau":     fieldNetworkType,
+			"type de radio":      fieldRadioType,
+			"authentification":   fieldAuthentication,
+			"chiffrement":        fieldCipher,
+			"bande":              fieldBand,
+			"canal":              fieldChannel,
+			"réception":          fieldReceiveRate,
+			"transmission":       fieldTransmitRate,
+			"signal":             fieldSignal,
+			"rssi":               fieldRSSI,
+			"profil":             fieldProfile,
+			"nom de l'interface": fieldInterfaceName,
+			"taux de base":       fieldBasicRates,
+			"autres taux":        fieldOtherRates,
+		},
+	},
+	{
+		Lang:           "es",
+		AllUserProfile: "Perfil de todos los usuarios",
+		ProfilesPrefix: "Perfiles en la interfaz ",
+		ProfilesSuffix: ":",
+		Average:        "Media",
+		Fields: map[string]netshField{
+			"nombre":                   fieldName,
+			"estado":                   fieldState,
+			"ssid":                     fieldSSID,
+			"bssid":                    fieldBSSID,
+			"bssid de ap":              fieldBSSID,
+			"tipo de red":              fieldNetworkType,
+			"tipo de radio":            fieldRadioType,
+			"autenticación":            fieldAuthentication,
+			"cifrado":                  fieldCipher,
+			"banda":                    fieldBand,
+			"canal":                    fieldChannel,
+			"velocidad de recepción":   fieldReceiveRate,
+			"velocidad de transmisión": fieldTransmitRate,
+			"señal":                    fieldSignal,
+			"rssi":                     fieldRSSI,
+			"perfil":                   fieldProfile,
+			"nombre de interfaz":       fieldInterfaceName,
+			"velocidades básicas":      fieldBasicRates,
+			"otras velocidades":        fieldOtherRates,
+		},
+	},
+	{
+		Lang:           "it",
+		AllUserProfile: "Profilo Tutti gli utenti",
+		ProfilesPrefix: "Profili nell'interfaccia ",
+		ProfilesSuffix: ":",
+		Average:        "Medio",
+		Fields: map[string]netshField{
+			"nome":                    fieldName,
+			"stato":                   fieldState,
+			"ssid":                    fieldSSID,
+			"bssid":                   fieldBSSID,
+			"bssid ap":                fieldBSSID,
+			"tipo di rete":            fieldNetworkType,
+			"tipo frequenza radio":    fieldRadioType,
+			"tipo di frequenza radio": fieldRadioType,
+			"autenticazione":          fieldAuthentication,
+			"crittografia":            fieldCipher,
+			"banda":                   fieldBand,
+			"canale":                  fieldChannel,
+			"velocità ricezione":      fieldReceiveRate,
+			"velocità trasmissione":   fieldTransmitRate,
+			"segnale":                 fieldSignal,
+			"rssi":                    fieldRSSI,
+			"profilo":                 fieldProfile,
+			"nome interfaccia":        fieldInterfaceName,
+			"velocità di base":        fieldBasicRates,
+			"altre velocità":          fieldOtherRates,
+		},
+	},
+	{
+		Lang:           "pt",
+		AllUserProfile: "Perfil de Todos os Usuários",
+		ProfilesPrefix: "Perfis na interface ",
+		ProfilesSuffix: ":",
+		Average:        "Média",
+		Fields: map[string]netshField{
+			"nome":                fieldName,
+			"estado":              fieldState,
+			"ssid":                fieldSSID,
+			"bssid":               fieldBSSID,
+			"bssid do ap":         fieldBSSID,
+			"tipo de rede":        fieldNetworkType,
+			"tipo de rádio":       fieldRadioType,
+			"autenticação":        fieldAuthentication,
+			"codificação":         fieldCipher,
+			"criptografia":        fieldCipher,
+			"banda":               fieldBand,
+			"canal":               fieldChannel,
+			"taxa de recepção":    fieldReceiveRate,
+			"taxa de transmissão": fieldTransmitRate,
+			"sinal":               fieldSignal,
+			"rssi":                fieldRSSI,
+			"perfil":              fieldProfile,
+			"nome da interface":   fieldInterfaceName,
+			"taxas básicas":       fieldBasicRates,
+			"outras taxas":        fieldOtherRates,
+		},
+	},
+	{
+		Lang:           "ru",
+		AllUserProfile: "Все профили пользователей",
+		ProfilesPrefix: "Профили интерфейса ",
+		ProfilesSuffix: ":",
+		Average:        "Среднее",
+		Fields: map[string]netshField{
+			"имя":                 fieldName,
+			"состояние":           fieldState,
+			"ssid":                fieldSSID,
+			"bssid":               fieldBSSID,
+			"bssid точки доступа": fieldBSSID,
+			"тип сети":            fieldNetworkType,
+			"тип радио":           fieldRadioType,
+			"проверка подлинности": fieldAuthentication,
+			"шифр":              fieldCipher,
+			"шифрование":        fieldCipher,
+			"диапазон":          fieldBand,
+			"канал":             fieldChannel,
+			"скорость приема":   fieldReceiveRate,
+			"скорость приёма":   fieldReceiveRate,
+			"скорость передачи": fieldTransmitRate,
+			"сигнал":            fieldSignal,
+			"rssi":              fieldRSSI,
+			"профиль":           fieldProfile,
+			"имя интерфейса":    fieldInterfaceName,
+			"базовая скорость":  fieldBasicRates,
+			"другие скорости":   fieldOtherRates,
+		},
+	},
+	{
+		Lang:           "ja",
+		AllUserProfile: "すべてのユーザー プロファイル",
+		ProfilesPrefix: "インターフェイス ",
+		ProfilesSuffix: " のプロファイル:",
+		Average:        "平均",
+		Fields: map[string]netshField{
+			"名前":        fieldName,
+			"状態":        fieldState,
+			"ssid":      fieldSSID,
+			"bssid":     fieldBSSID,
+			"ap bssid":  fieldBSSID,
+			"ネットワークの種類": fieldNetworkType,
+			"無線の種類":     fieldRadioType,
+			"認証":        fieldAuthentication,
+			"暗号":        fieldCipher,
+			"暗号化":       fieldCipher,
+			"バンド":       fieldBand,
+			"チャネル":      fieldChannel,
+			"受信速度":      fieldReceiveRate,
+			"送信速度":      fieldTransmitRate,
+			"シグナル":      fieldSignal,
+			"rssi":      fieldRSSI,
+			"プロファイル":    fieldProfile,
+			"インターフェイス名": fieldInterfaceName,
+			"基本レート":     fieldBasicRates,
+			"他のレート":     fieldOtherRates,
+		},
+	},
+	{
+		Lang:           "zh",
+		AllUserProfile: "所有用户配置文件",
+		ProfilesPrefix: "接口 ",
+		ProfilesSuffix: " 上的配置文件:",
+		Average:        "平均",
+		Fields: map[string]netshField{
+			"名称":       fieldName,
+			"状态":       fieldState,
+			"ssid":     fieldSSID,
+			"bssid":    fieldBSSID,
+			"ap bssid": fieldBSSID,
+			"网络类型":     fieldNetworkType,
+			"无线电类型":    fieldRadioType,
+			"身份验证":     fieldAuthentication,
+			"密码":       fieldCipher,
+			"加密":       fieldCipher,
+			"频带":       fieldBand,
+			"信道":       fieldChannel,
+			"通道":       fieldChannel,
+			"接收速率":     fieldReceiveRate,
+			"传输速率":     fieldTransmitRate,
+			"信号":       fieldSignal,
+			"rssi":     fieldRSSI,
+			"配置文件":     fieldProfile,
+			"接口名称":     fieldInterfaceName,
+			"基本速率":     fieldBasicRates,
+			"其他速率":     fieldOtherRates,
+		},
+	},
+}
+
+var netshFields = func() map[string]netshField {
+	all := make(map[string]netshField)
+	for _, l := range netshLocales {
+		for label, f := range l.Fields {
+			all[label] = f
+		}
+	}
+	return all
+}()
+
+// lookupField maps a label such as "Receive rate (Mbps)", "BSSID 2" or
+// "Señal" to its field.
+func lookupField(label string) netshField {
+	key := strings.ToLower(strings.TrimSpace(label))
+	if i := strings.IndexAny(key, "(（"); i > 0 {
+		key = strings.TrimSpace(key[:i])
+	}
+	if f, ok := netshFields[key]; ok {
+		return f
+	}
+	// Numbered entries in `show networks`: "SSID 1", "BSSID 2".
+	trimmed := strings.TrimRightFunc(key, unicode.IsDigit)
+	if trimmed != key {
+		return netshFields[strings.TrimSpace(trimmed)]
+	}
+	return fieldUnknown
+}
+
+// splitLabel splits "Label : value" into its trimmed halves.
+func splitLabel(line string) (label, value string, ok bool) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
+
+// isAllUserProfile reports whether label is "All User Profile" in any
+// known language.
+func isAllUserProfile(label string) bool {
+	for _, l := range netshLocales {
+		if strings.EqualFold(label, l.AllUserProfile) {
+			return true
+		}
+	}
+	return false
+}
+
+// profilesHeader extracts the interface name from a localized
+// "Profiles on interface Wi-Fi:" header.
+func profilesHeader(line string) (string, bool) {
+	for _, l := range netshLocales {
+		if strings.HasPrefix(line, l.ProfilesPrefix) && strings.HasSuffix(line, l.ProfilesSuffix) {
+			name := strings.TrimSuffix(strings.TrimPrefix(line, l.ProfilesPrefix), l.ProfilesSuffix)
+			return strings.TrimSpace(name), true
+		}
+	}
+	return "", false
+}
+
+var (
+	guidRe    = regexp.MustCompile(`^\{?[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\}?$`)
+	macRe     = regexp.MustCompile(`^[0-9A-Fa-f]{2}([:-][0-9A-Fa-f]{2}){5}$`)
+	percentRe = regexp.MustCompile(`^(\d{1,3})\s*%$`)
+	bandRe    = regexp.MustCompile(`^\d+(\.\d+)?\s*GHz$`)
+)
+
+// parseInterfacesByPosition reads `netsh wlan show interfaces` in a display
+// language the table does not know. The labels are ignored; the layout is
+// the same in every language, so fields are found from anchors whose values
+// look the same everywhere:
+//
+//	Name, Description, GUID, Physical address, ..., SSID, BSSID, ...,
+//	Receive rate, Transmit rate, Signal (NN%), [Rssi], Profile, ...
+//
+// Each block starts two lines above its GUID; SSID is the line before the
+// first MAC address after the physical address; the two rates precede the
+// signal percentage and the profile follows it.
+func parseInterfacesByPosition(output string) []*WifiStatus {
+	var vals []string
+	for _, line := range strings.Split(output, "\n") {
+		if _, v, ok := splitLabel(strings.TrimSpace(line)); ok {
+			vals = append(vals, v)
+		}
+	}
+
+	var starts []int
+	for i, v := range vals {
+		if i >= 2 && guidRe.MatchString(v) {
+			starts = append(starts, i-2)
+		}
+	}
+
+	var statuses []*WifiStatus
+	for b, start := range starts {
+		end := len(vals)
+		if b+1 < len(starts) {
+			end = starts[b+1]
+		}
+		statuses = append(statuses, statusFromValues(vals[start:end]))
+	}
+	return statuses
+}
+
+// statusFromValues fills a WifiStatus from one interface block's values;
+// block[0] is the name and block[2] the GUID.
+func statusFromValues(block []string) *WifiStatus {
+	st := &WifiStatus{InterfaceName: block[0]}
+
+	bssidAt := -1
+	for i := 4; i < len(block); i++ { // block[3] is the adapter's own MAC
+		if macRe.MatchString(block[i]) {
+			bssidAt = i
+			break
+		}
+	}
+	if bssidAt < 0 {
+		return st // not associated
+	}
+	st.BSSID = block[bssidAt]
+	st.SSID = block[bssidAt-1]
+
+	signalAt := -1
+	for i := bssidAt + 1; i < len(block); i++ {
+		if m := percentRe.FindStringSubmatch(block[i]); m != nil {
+			st.Signal, _ = strconv.Atoi(m[1])
+			signalAt = i
+			break
+		}
+	}
+
+	ratesAt := len(block)
+	if signalAt >= bssidAt+3 {
+		rx, errRx := strconv.ParseFloat(block[signalAt-2], 64)
+		tx, errTx := strconv.ParseFloat(block[signalAt-1], 64)
+		if errRx == nil && errTx == nil {
+			st.ReceiveRateMbps, st.TransmitRateMbps = rx, tx
+			ratesAt = signalAt - 2
+		}
+	}
+
+	for i := bssidAt + 1; i < ratesAt; i++ {
+		v := block[i]
+		switch {
+		case strings.HasPrefix(v, "802.11"):
+			st.RadioType = v
+		case bandRe.MatchString(v):
+			st.Band = normalizeBand(v)
+		case st.Channel == 0:
+			if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 233 {
+				st.Channel = n
+			}
+		}
+	}
+
+	if signalAt >= 0 {
+		for i := signalAt + 1; i < len(block); i++ {
+			if n, err := strconv.Atoi(block[i]); err == nil {
+				if n < 0 {
+					st.RSSI = n
+				}
+				continue
+			}
+			st.ProfileName = block[i]
+			break
+		}
+	}
+	return st
+}
+
+var (
+	averageRe = func() *regexp.Regexp {
+		words := make([]string, 0, len(netshLocales))
+		seen := make(map[string]bool)
+		for _, l := range netshLocales {
+			if !seen[l.Average] {
+				seen[l.Average] = true
+				words = append(words, regexp.QuoteMeta(l.Average))
+			}
+		}
+		return regexp.MustCompile(`(?:` + strings.Join(words, "|") + `)\s*=\s*(\d+)\s*\p{L}*`)
+	}()
+	// " = 12ms" in the summary line; the per-reply "time=12ms" has no spaces.
+	summaryValueRe = regexp.MustCompile(`\s=\s*(\d+)`)
+)
+
+// pingAverageByPosition finds the Windows round-trip summary in any language:
+// the last line with exactly three " = N" values (minimum, maximum, average)
+// and no percentage. The packets line (sent, received, lost) comes before it.
+func pingAverageByPosition(out string) (int, bool) {
+	avg, found := 0, false
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "%") {
+			continue
+		}
+		m := summaryValueRe.FindAllStringSubmatch(line, -1)
+		if len(m) != 3 {
+			continue
+		}
+		if v, err := strconv.Atoi(m[2][1]); err == nil {
+			avg, found = v, true
+		}
+	}
+	return avg, found
+}
@@ -0,0 +1,127 @@
+package wifi
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Each testdata/netsh/<lang> holds netsh and ping output captured on a
+// Windows install in that display language, all of the same two networks.
+var netshLanguages = []struct {
+	lang  string
+	iface string // adapter name, localized by Windows
+	state string
+}{
+	{"en", "Wi-Fi", "connected"},
+	{"de", "WLAN", "Verbunden"},
+	{"fr", "Wi-Fi", "connecté"},
+	{"es", "Wi-Fi", "conectado"},
+	{"it", "Wi-Fi", "connessa"},
+	{"pt", "Wi-Fi", "conectado"},
+	{"ru", "Беспроводная сеть", "подключено"},
+	{"ja", "Wi-Fi", "接続されました"},
+	{"zh", "WLAN", "已连接"},
+}
+
+func TestNetshLocales(t *testing.T) {
+	for _, lang := range netshLanguages {
+		t.Run(lang.lang, func(t *testing.T) {
+			dir := filepath.Join("netsh", lang.lang)
+
+			st := ParseCurrentStatus(fixture(t, filepath.Join(dir, "interfaces.txt")))
+			if st == nil {
+				t.Fatal("no connected interface")
+			}
+			checkCampusStatus(t, st, lang.iface)
+			if st.State != lang.state || st.Authentication != "WPA2-Enterprise" || st.Cipher != "CCMP" {
+				t.Errorf("state %q security %s/%s", st.State, st.Authentication, st.Cipher)
+			}
+
+			checkNetworks(t, ParseNetworks(fixture(t, filepath.Join(dir, "networks.txt"))), lang.iface)
+
+			profiles := ParseProfiles(fixture(t, filepath.Join(dir, "profiles.txt")))
+			want := []string{"Campus-5G", "Café Gäste", "Hotspot: Phone"}
+			if len(profiles) != len(want) {
+				t.Fatalf("profiles = %+v, want %q", profiles, want)
+			}
+			for i, p := range profiles {
+				if p.CleanName != want[i] || p.InterfaceName != lang.iface {
+					t.Errorf("profile %d = %q on %q, want %q on %q", i, p.CleanName, p.InterfaceName, want[i], lang.iface)
+				}
+			}
+
+			if p := ParsePingOutput(fixture(t, filepath.Join(dir, "ping.txt"))); p == nil || p.AvgMs != 13 {
+				t.Errorf("ping average = %+v, want 13", p)
+			}
+		})
+	}
+}
+
+// Polish is not in netshLocales, so its output is read by field position.
+func TestNetshUnknownLocale(t *testing.T) {
+	st := ParseCurrentStatus(fixture(t, "netsh/pl/interfaces.txt"))
+	if st == nil {
+		t.Fatal("no connected interface")
+	}
+	checkCampusStatus(t, st, "Wi-Fi")
+
+	if p := ParsePingOutput(fixture(t, "netsh/pl/ping.txt")); p == nil || p.AvgMs != 13 {
+		t.Errorf("ping average = %+v, want 13", p)
+	}
+}
+
+func checkCampusStatus(t *testing.T, st *WifiStatus, iface string) {
+	t.Helper()
+	if st.InterfaceName != iface {
+		t.Errorf("interface %q, want %q", st.InterfaceName, iface)
+	}
+	if st.SSID != "Campus-5G" || st.ProfileName != "Campus-5G" || st.BSSID != "7c:21:0e:12:34:56" {
+		t.Errorf("SSID %q profile %q BSSID %q", st.SSID, st.ProfileName, st.BSSID)
+	}
+	if st.Signal != 91 || st.RSSI != -52 {
+		t.Errorf("signal %d%% (%d dBm), want 91%% (-52 dBm)", st.Signal, st.RSSI)
+	}
+	if st.Band != Band5GHz || st.Channel != 44 || st.RadioType != "802.11ax" {
+		t.Errorf("band %q channel %d radio %q", st.Band, st.Channel, st.RadioType)
+	}
+	if st.ReceiveRateMbps != 866.7 || st.TransmitRateMbps != 573.5 {
+		t.Errorf("rates %v/%v", st.ReceiveRateMbps, st.TransmitRateMbps)
+	}
+}
+
+func checkNetworks(t *testing.T, networks []VisibleNetwork, iface string) {
+	t.Helper()
+	if len(networks) != 2 {
+		t.Fatalf("got %d networks, want 2: %+v", len(networks), networks)
+	}
+
+	campus := networks[0]
+	if campus.SSID != "Campus-5G" || campus.InterfaceName != iface {
+		t.Errorf("network 1 = %q on %q", campus.SSID, campus.InterfaceName)
+	}
+	if campus.Authentication != "WPA2-Enterprise" || campus.Encryption != "CCMP" {
+		t.Errorf("Campus-5G security %s/%s", campus.Authentication, campus.Encryption)
+	}
+	if len(campus.BSSIDs) != 2 {
+		t.Fatalf("Campus-5G BSSIDs = %+v", campus.BSSIDs)
+	}
+	b := campus.BSSIDs[0]
+	if b.BSSID != "7c:21:0e:12:34:56" || b.Signal != 91 || b.Band != Band5GHz || b.Channel != 44 || b.RadioType != "802.11ax" {
+		t.Errorf("Campus-5G BSSID 1 = %+v", b)
+	}
+	if len(b.BasicRates) != 3 || len(b.OtherRates) != 5 || b.OtherRates[4] != 54 {
+		t.Errorf("Campus-5G BSSID 1 rates %v / %v", b.BasicRates, b.OtherRates)
+	}
+	// No band line: it comes from the channel.
+	if b := campus.BSSIDs[1]; b.Signal != 48 || b.Band != Band24GHz || b.Channel != 6 || b.BasicRates[2] != 5.5 {
+		t.Errorf("Campus-5G BSSID 2 = %+v", b)
+	}
+
+	guest := networks[1]
+	if guest.SSID != "Café Gäste" || guest.Authentication != "Open" || guest.BestSignal() != 30 {
+		t.Errorf("network 2 = %+v", guest)
+	}
+	if len(guest.BSSIDs) != 1 || guest.BSSIDs[0].Band != Band24GHz || guest.BSSIDs[0].Channel != 11 {
+		t.Errorf("Café Gäste BSSIDs = %+v", guest.BSSIDs)
+	}
+}
@@ -1,51 +1,95 @@
 package wifi
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"os/exec"
 	"strings"
 )
 
 // saved Wi-Fi profile on Windows.
 type WifiProfile struct {
-	RawName   string
-	CleanName string
+	RawName       string
+	CleanName     string
+	InterfaceName string // adapter to connect on; empty lets the OS choose
 }
 
 // WifiStatus - the current connection info.
 type WifiStatus struct {
-	InterfaceName string
-	SSID          string
-	ProfileName   string
-	Signal        int // percentage 0-100
+	InterfaceName    string
+	State            string // e.g. "connected"
+	SSID             string
+	BSSID            string
+	ProfileName      string
+	RadioType        string // e.g. "802.11ax"
+	Authentication   string
+	Cipher           string
+	Channel          int
+	Band             string // "2.4 GHz", "5 GHz" or "6 GHz"
+	ReceiveRateMbps  float64
+	TransmitRateMbps float64
+	Signal           int // percentage 0-100
+	RSSI             int // dBm, approximate when the backend only reports percent
 }
 
+// BSSIDInfo - one access point radio advertising an SSID.
+type BSSIDInfo struct {
+	BSSID      string
+	Signal     int // percentage 0-100
+	RadioType  string
+	Band       string
+	Channel    int
+	BasicRates []float64 // Mbps
+	OtherRates []float64 // Mbps
+}
+
+// VisibleNetwork - an SSID seen in the last scan, with every BSSID serving it.
+type VisibleNetwork struct {
+	InterfaceName  string
+	SSID           string
+	NetworkType    string
+	Authentication string
+	Encryption     string
+	BSSIDs         []BSSIDInfo
+}
+
+// BestSignal returns the strongest signal across the network's BSSIDs.
+func (v VisibleNetwork) BestSignal() int {
+	best := 0
+	for _, b := range v.BSSIDs {
+		if b.Signal > best {
+			best = b.Signal
+		}
+	}
+	return best
+}
+
+// Manager reads and drives the Wi-Fi adapters. Every call gives up once
+// ctx is done, so stopping the agent never waits on a slow connect.
 type Manager interface {
-	ListProfiles() ([]WifiProfile, error)
-	GetCurrentStatus() (*WifiStatus, error)
-	Connect(profile WifiProfile) error
+	ListProfiles(ctx context.Context) ([]WifiProfile, error)
+	GetCurrentStatus(ctx context.Context) (*WifiStatus, error)
+	GetInterfaceStatuses(ctx context.Context) ([]*WifiStatus, error)
+	Connect(ctx context.Context, profile WifiProfile) error
+	ScanNetworks(ctx context.Context) ([]VisibleNetwork, error)
 }
 
 // WindowsManager implements Manager using `netsh` on Windows.
-type WindowsManager struct{}
+type WindowsManager struct {
+	Runner CommandRunner // nil runs netsh directly
+}
 
 // runNetsh executes a netsh command and returns its output as string.
-func (w WindowsManager) runNetsh(args ...string) (string, error) {
-	cmd := exec.Command("netsh", args...)
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("netsh %v failed: %v | stderr: %s", args, err, stderr.String())
+func (w WindowsManager) runNetsh(ctx context.Context, args ...string) (string, error) {
+	out, err := runnerOrExec(w.Runner).Run(ctx, "netsh", args...)
+	if err != nil {
+		return "", err
 	}
-	return out.String(), nil
+	return out, nil
 }
 
 // ListProfiles parses `netsh wlan show profiles`.
-func (w WindowsManager) ListProfiles() ([]WifiProfile, error) {
-	out, err := w.runNetsh("wlan", "show", "profiles")
+func (w WindowsManager) ListProfiles(ctx context.Context) ([]WifiProfile, error) {
+	out, err := w.runNetsh(ctx, "wlan", "show", "profiles")
 	if err != nil {
 		return nil, err
 	}
@@ -53,8 +97,8 @@ func (w WindowsManager) ListProfiles() ([]WifiProfile, error) {
 }
 
 
-func (w WindowsManager) GetCurrentStatus() (*WifiStatus, error) {
-	out, err := w.runNetsh("wlan", "show", "interfaces")
+func (w WindowsManager) GetCurrentStatus(ctx context.Context) (*WifiStatus, error) {
+	out, err := w.runNetsh(ctx, "wlan", "show", "interfaces")
 	if err != nil {
 		return nil, err
 	}
@@ -65,12 +109,33 @@ func (w WindowsManager) GetCurrentStatus() (*WifiStatus, error) {
 	return status, nil
 }
 
-func (w WindowsManager) Connect(profile WifiProfile) error {
+// GetInterfaceStatuses returns every wireless adapter, connected or not.
+func (w WindowsManager) GetInterfaceStatuses(ctx context.Context) ([]*WifiStatus, error) {
+	out, err := w.runNetsh(ctx, "wlan", "show", "interfaces")
+	if err != nil {
+		return nil, err
+	}
+	return ParseInterfaces(out), nil
+}
+
+func (w WindowsManager) Connect(ctx context.Context, profile WifiProfile) error {
 	args := []string{"wlan", "connect", "name=" + profile.RawName}
-	_, err := w.runNetsh(args...)
+	if profile.InterfaceName != "" {
+		args = append(args, "interface="+profile.InterfaceName)
+	}
+	_, err := w.runNetsh(ctx, args...)
 	return err
 }
 
+// ScanNetworks parses `netsh wlan show networks mode=bssid`.
+func (w WindowsManager) ScanNetworks(ctx context.Context) ([]VisibleNetwork, error) {
+	out, err := w.runNetsh(ctx, "wlan", "show", "networks", "mode=bssid")
+	if err != nil {
+		return nil, err
+	}
+	return ParseNetworks(out), nil
+}
+
 func FindProfileByCleanName(profiles []WifiProfile, name string) *WifiProfile {
 	for _, p := range profiles {
 		if strings.TrimSpace(p.CleanName) == strings.TrimSpace(name) {
@@ -0,0 +1,172 @@
+package wifi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// splitTerse splits one line of `nmcli -t` output into fields.
+// nmcli escapes ':' and '\' inside values with a backslash.
+func splitTerse(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(fields, cur.String())
+}
+
+// isNmcliWifiType matches the connection/device type column for Wi-Fi.
+func isNmcliWifiType(t string) bool {
+	return t == "802-11-wireless" || t == "wifi"
+}
+
+// ParseNmcliProfiles parses `nmcli -t -f NAME,TYPE connection show` output.
+func ParseNmcliProfiles(output string) []WifiProfile {
+	var profiles []WifiProfile
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		fields := splitTerse(line)
+		if len(fields) < 2 || !isNmcliWifiType(fields[1]) {
+			continue
+		}
+		profiles = append(profiles, WifiProfile{
+			RawName:   fields[0],
+			CleanName: strings.TrimSpace(fields[0]),
+		})
+	}
+	return profiles
+}
+
+// ParseNmcliStatus returns the first connected Wi-Fi device; see ParseNmcliInterfaces.
+func ParseNmcliStatus(deviceOut, wifiOut string) *WifiStatus {
+	for _, status := range ParseNmcliInterfaces(deviceOut, wifiOut) {
+		if status.SSID != "" {
+			return status
+		}
+	}
+	return nil
+}
+
+// ParseNmcliInterfaces combines `nmcli -t -f DEVICE,TYPE,STATE,CONNECTION device status`
+// and `nmcli -t -f IN-USE,SSID,BSSID,SIGNAL,CHAN,FREQ,RATE,SECURITY,DEVICE device wifi list`
+// output into one status per Wi-Fi device.
+func ParseNmcliInterfaces(deviceOut, wifiOut string) []*WifiStatus {
+	var statuses []*WifiStatus
+	byDevice := make(map[string]*WifiStatus)
+
+	for _, line := range strings.Split(deviceOut, "\n") {
+		line = strings.TrimRight(line, "\r")
+		fields := splitTerse(line)
+		if len(fields) < 4 || !isNmcliWifiType(fields[1]) {
+			continue
+		}
+		// DEVICE:TYPE:STATE:CONNECTION -> wlan0:wifi:connected:HomeNet
+		status := &WifiStatus{InterfaceName: fields[0], State: fields[2]}
+		if fields[2] == "connected" {
+			status.ProfileName = fields[3]
+		}
+		statuses = append(statuses, status)
+		byDevice[fields[0]] = status
+	}
+
+	for _, line := range strings.Split(wifiOut, "\n") {
+		line = strings.TrimRight(line, "\r")
+		fields := splitTerse(line)
+		if len(fields) < 9 || fields[0] != "*" {
+			continue
+		}
+		// IN-USE:SSID:BSSID:SIGNAL:CHAN:FREQ:RATE:SECURITY:DEVICE
+		// *:HomeNet:AA\:BB\:CC\:DD\:EE\:FF:78:36:5180 MHz:540 Mbit/s:WPA2:wlan0
+		status, ok := byDevice[fields[8]]
+		if !ok || status.State != "connected" {
+			continue
+		}
+		status.SSID = fields[1]
+		status.BSSID = strings.ToLower(fields[2])
+		if v, err := strconv.Atoi(strings.TrimSpace(fields[3])); err == nil {
+			status.Signal = v
+		}
+		status.Channel, _ = strconv.Atoi(fields[4])
+		status.Band = BandFromFrequency(leadingInt(fields[5]))
+		// nmcli only reports one (receive) bitrate.
+		status.ReceiveRateMbps = float64(leadingInt(fields[6]))
+		status.Authentication = fields[7]
+	}
+
+	for _, status := range statuses {
+		status.fillDerived()
+	}
+	return statuses
+}
+
+// leadingInt parses the number at the start of values like "5180 MHz".
+func leadingInt(val string) int {
+	f := strings.Fields(val)
+	if len(f) == 0 {
+		return 0
+	}
+	v, _ := strconv.Atoi(f[0])
+	return v
+}
+
+// ParseNmcliNetworks parses
+// `nmcli -t -f SSID,BSSID,SIGNAL,CHAN,FREQ,SECURITY,DEVICE device wifi list` output,
+// grouping BSSIDs under their SSID per device. Hidden networks have no SSID
+// to group by, so each hidden BSSID is a network of its own.
+func ParseNmcliNetworks(output string) []VisibleNetwork {
+	var networks []VisibleNetwork
+	index := make(map[string]int)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		fields := splitTerse(line)
+		if len(fields) < 7 {
+			continue
+		}
+		// SSID:BSSID:SIGNAL:CHAN:FREQ:SECURITY:DEVICE
+		// HomeNet:AA\:BB\:CC\:DD\:EE\:FF:78:36:5180 MHz:WPA2:wlan0
+		ssid, device := fields[0], fields[6]
+		if ssid == "--" {
+			ssid = "" // hidden; nmcli prints "--" outside terse mode
+		}
+		key := device + "\x00" + ssid
+		if ssid == "" {
+			key += "\x00" + strings.ToLower(fields[1])
+		}
+		i, ok := index[key]
+		if !ok {
+			auth := fields[5]
+			if auth == "" || auth == "--" {
+				auth = "Open"
+			}
+			networks = append(networks, VisibleNetwork{InterfaceName: device, SSID: ssid, Authentication: auth})
+			i = len(networks) - 1
+			index[key] = i
+		}
+
+		b := BSSIDInfo{BSSID: strings.ToLower(fields[1])}
+		b.Signal, _ = strconv.Atoi(fields[2])
+		b.Channel, _ = strconv.Atoi(fields[3])
+		b.Band = BandFromFrequency(leadingInt(fields[4]))
+		networks[i].BSSIDs = append(networks[i].BSSIDs, b)
+	}
+	return networks
+}
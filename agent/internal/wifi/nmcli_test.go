@@ -0,0 +1,157 @@
+package wifi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixture reads a captured command output from testdata.
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseNmcliProfiles(t *testing.T) {
+	got := ParseNmcliProfiles(fixture(t, "nmcli/connection-show.txt"))
+	want := []WifiProfile{
+		{RawName: "HomeNet", CleanName: "HomeNet"},
+		{RawName: "Cafe: Guest", CleanName: "Cafe: Guest"},
+		{RawName: " Lab-5G ", CleanName: "Lab-5G"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d profiles %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("profile %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseNmcliInterfaces(t *testing.T) {
+	got := ParseNmcliInterfaces(fixture(t, "nmcli/device-status.txt"), fixture(t, "nmcli/wifi-list-inuse.txt"))
+	if len(got) != 2 {
+		t.Fatalf("got %d interfaces, want wlan0 and wlan1", len(got))
+	}
+
+	w0 := got[0]
+	if w0.InterfaceName != "wlan0" || w0.State != "connected" {
+		t.Errorf("wlan0 = %s %s", w0.InterfaceName, w0.State)
+	}
+	if w0.SSID != "Cafe: Guest" || w0.ProfileName != "Cafe: Guest" {
+		t.Errorf("wlan0 SSID %q profile %q, want the escaped colon kept", w0.SSID, w0.ProfileName)
+	}
+	if w0.BSSID != "aa:bb:cc:dd:ee:02" {
+		t.Errorf("wlan0 BSSID = %q", w0.BSSID)
+	}
+	if w0.Signal != 78 || w0.RSSI != -61 {
+		t.Errorf("wlan0 signal %d%% (%d dBm), want 78%% (-61 dBm)", w0.Signal, w0.RSSI)
+	}
+	if w0.Channel != 36 || w0.Band != Band5GHz || w0.ReceiveRateMbps != 540 {
+		t.Errorf("wlan0 channel %d band %q rate %v", w0.Channel, w0.Band, w0.ReceiveRateMbps)
+	}
+	if w0.Authentication != "WPA2 WPA3" {
+		t.Errorf("wlan0 authentication = %q", w0.Authentication)
+	}
+
+	w1 := got[1]
+	if w1.InterfaceName != "wlan1" || w1.State != "disconnected" || w1.SSID != "" || w1.ProfileName != "" {
+		t.Errorf("wlan1 = %+v, want disconnected with no network", *w1)
+	}
+
+	if s := ParseNmcliStatus(fixture(t, "nmcli/device-status.txt"), fixture(t, "nmcli/wifi-list-inuse.txt")); s == nil || s.InterfaceName != "wlan0" {
+		t.Errorf("ParseNmcliStatus = %+v, want wlan0", s)
+	}
+}
+
+func TestParseNmcliStatusDisconnected(t *testing.T) {
+	dev := "wlan0:wifi:disconnected:--\n"
+	if s := ParseNmcliStatus(dev, fixture(t, "nmcli/wifi-list-empty.txt")); s != nil {
+		t.Errorf("ParseNmcliStatus = %+v, want nil", s)
+	}
+}
+
+func TestParseNmcliNetworks(t *testing.T) {
+	got := ParseNmcliNetworks(fixture(t, "nmcli/wifi-list.txt"))
+
+	type key struct{ iface, ssid string }
+	bssids := make(map[key][]string)
+	var hidden []VisibleNetwork
+	for _, n := range got {
+		if n.SSID == "" {
+			hidden = append(hidden, n)
+			continue
+		}
+		k := key{n.InterfaceName, n.SSID}
+		if _, dup := bssids[k]; dup {
+			t.Errorf("%s on %s listed twice", n.SSID, n.InterfaceName)
+		}
+		for _, b := range n.BSSIDs {
+			bssids[k] = append(bssids[k], b.BSSID)
+		}
+	}
+
+	home := bssids[key{"wlan0", "HomeNet"}]
+	if len(home) != 2 || home[0] != "aa:bb:cc:dd:ee:01" || home[1] != "aa:bb:cc:dd:ee:11" {
+		t.Errorf("HomeNet on wlan0 BSSIDs = %v, want both access points", home)
+	}
+	if b := bssids[key{"wlan1", "HomeNet"}]; len(b) != 1 {
+		t.Errorf("HomeNet on wlan1 BSSIDs = %v, want it kept apart from wlan0", b)
+	}
+	if b := bssids[key{"wlan0", "Cafe: Guest"}]; len(b) != 1 {
+		t.Errorf("Cafe: Guest BSSIDs = %v", b)
+	}
+
+	if len(hidden) != 2 {
+		t.Fatalf("got %d hidden networks, want one per hidden BSSID", len(hidden))
+	}
+	for _, n := range hidden {
+		if len(n.BSSIDs) != 1 {
+			t.Errorf("hidden network %+v groups %d BSSIDs", n, len(n.BSSIDs))
+		}
+	}
+
+	for _, n := range got {
+		switch n.SSID {
+		case "Cafe: Guest":
+			if n.Authentication != "Open" {
+				t.Errorf("Cafe: Guest authentication = %q, want Open", n.Authentication)
+			}
+		case "Lab-6G":
+			if b := n.BSSIDs[0]; b.Band != Band6GHz || b.Channel != 37 || b.Signal != 66 {
+				t.Errorf("Lab-6G BSSID = %+v", b)
+			}
+		case "HomeNet":
+			if n.InterfaceName == "wlan0" && (n.BSSIDs[1].Band != Band5GHz || n.BestSignal() != 71) {
+				t.Errorf("HomeNet 5 GHz BSSID = %+v, best %d", n.BSSIDs[1], n.BestSignal())
+			}
+		}
+	}
+}
+
+func TestParseNmcliNetworksEmpty(t *testing.T) {
+	if got := ParseNmcliNetworks(fixture(t, "nmcli/wifi-list-empty.txt")); len(got) != 0 {
+		t.Errorf("empty scan gave %+v", got)
+	}
+	if got := ParseNmcliNetworks("\n"); len(got) != 0 {
+		t.Errorf("blank scan gave %+v", got)
+	}
+}
+
+func TestSplitTerse(t *testing.T) {
+	got := splitTerse(`a\:b:c\\d::e`)
+	want := []string{"a:b", `c\d`, "", "e"}
+	if len(got) != len(want) {
+		t.Fatalf("splitTerse = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("field %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -2,76 +2,224 @@ package wifi
 
 import (
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-// ParseProfiles parses `netsh wlan show profiles` output.
+// ParseProfiles parses `netsh wlan show profiles` output in any display
+// language in netshLocales. If none of the labels are recognised, every
+// "label : value" line under a dashed section heading is taken as a profile.
 func ParseProfiles(output string) []WifiProfile {
-	var profiles []WifiProfile
+	var profiles, positional []WifiProfile
+	var iface string
+	inSection := false
 	lines := strings.Split(output, "\n")
 
 	for _, line := range lines {
-		if strings.Contains(line, "All User Profile") {
-			line = strings.TrimRight(line, "\r\n")
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) != 2 {
-				continue
-			}
+		line = strings.TrimRight(line, "\r\n")
+		t := strings.TrimSpace(line)
+		// Profiles on interface Wi-Fi 2:
+		if name, ok := profilesHeader(t); ok {
+			iface = name
+			continue
+		}
+		if t != "" && strings.Trim(t, "-") == "" {
+			inSection = true // ----------- under "User profiles"
+			continue
+		}
 
-			raw := parts[1]
-			if len(raw) > 0 && raw[0] == ' ' {
-				raw = raw[1:]
-			}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		raw := parts[1]
+		if len(raw) > 0 && raw[0] == ' ' {
+			raw = raw[1:]
+		}
+		p := WifiProfile{
+			RawName:       raw,
+			CleanName:     strings.TrimSpace(raw),
+			InterfaceName: iface,
+		}
 
-			profiles = append(profiles, WifiProfile{
-				RawName:   raw,                 
-				CleanName: strings.TrimSpace(raw),
-			})
+		if isAllUserProfile(strings.TrimSpace(parts[0])) {
+			profiles = append(profiles, p)
+		} else if inSection && strings.HasPrefix(line, " ") && p.CleanName != "" {
+			positional = append(positional, p)
 		}
 	}
+	if len(profiles) == 0 {
+		return positional
+	}
 	return profiles
 }
 
 
+// ParseCurrentStatus returns the first connected interface in
+// `netsh wlan show interfaces` output.
 func ParseCurrentStatus(output string) *WifiStatus {
-	lines := strings.Split(output, "\n")
-	status := &WifiStatus{}
+	for _, status := range ParseInterfaces(output) {
+		if status.SSID != "" {
+			return status
+		}
+	}
+	return nil
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+// ParseInterfaces parses every interface block of `netsh wlan show interfaces`,
+// including disconnected adapters. Labels are looked up in netshLocales; when
+// no block is recognised the output is parsed by field position instead.
+func ParseInterfaces(output string) []*WifiStatus {
+	var statuses []*WifiStatus
+	var status *WifiStatus
+
+	for _, line := range strings.Split(output, "\n") {
+		label, val, ok := splitLabel(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+		field := lookupField(label)
 
-		switch {
-		case strings.HasPrefix(line, "Name") && status.InterfaceName == "":
+		if field == fieldName {
 			// Name                   : Wi-Fi
-			status.InterfaceName = afterColon(line)
-		case strings.HasPrefix(line, "SSID") && !strings.Contains(line, "BSSID"):
-			status.SSID = afterColon(line)
-		case strings.HasPrefix(line, "Profile"):
-			status.ProfileName = afterColon(line)
-		case strings.HasPrefix(line, "Signal"):
+			status = &WifiStatus{InterfaceName: val}
+			statuses = append(statuses, status)
+			continue
+		}
+		if status == nil {
+			continue
+		}
+
+		switch field {
+		case fieldSSID:
+			status.SSID = val
+		case fieldBSSID:
+			// AP BSSID               : aa:bb:cc:dd:ee:ff
+			status.BSSID = val
+		case fieldState:
+			status.State = val
+		case fieldRadioType:
+			status.RadioType = val
+		case fieldAuthentication:
+			status.Authentication = val
+		case fieldCipher:
+			status.Cipher = val
+		case fieldBand:
+			status.Band = normalizeBand(val)
+		case fieldChannel:
+			if v, err := strconv.Atoi(val); err == nil {
+				status.Channel = v
+			}
+		case fieldReceiveRate:
+			// Receive rate (Mbps)    : 866.7
+			if v, err := strconv.ParseFloat(val, 64); err == nil {
+				status.ReceiveRateMbps = v
+			}
+		case fieldTransmitRate:
+			if v, err := strconv.ParseFloat(val, 64); err == nil {
+				status.TransmitRateMbps = v
+			}
+		case fieldProfile:
+			status.ProfileName = val
+		case fieldSignal:
 			// Signal                 : 91%
-			raw := afterColon(line)
-			raw = strings.TrimSpace(strings.TrimSuffix(raw, "%"))
+			raw := strings.TrimSpace(strings.TrimSuffix(val, "%"))
 			if v, err := strconv.Atoi(raw); err == nil {
 				status.Signal = v
 			}
+		case fieldRSSI:
+			// Rssi                   : -58   (Windows 11 24H2+)
+			if v, err := strconv.Atoi(val); err == nil {
+				status.RSSI = v
+			}
 		}
 	}
 
-	if status.InterfaceName == "" || status.SSID == "" {
-		return nil
+	if len(statuses) == 0 {
+		statuses = parseInterfacesByPosition(output)
 	}
-	return status
+	for _, s := range statuses {
+		s.fillDerived()
+	}
+	return statuses
 }
 
-func afterColon(line string) string {
-	parts := strings.SplitN(line, ":", 2)
-	if len(parts) != 2 {
-		return ""
+// ParseNetworks parses `netsh wlan show networks mode=bssid` output.
+func ParseNetworks(output string) []VisibleNetwork {
+	var networks []VisibleNetwork
+	var iface string
+	var cur *VisibleNetwork
+	var bss *BSSIDInfo
+
+	for _, line := range strings.Split(output, "\n") {
+		label, val, ok := splitLabel(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+
+		switch field := lookupField(label); {
+		case field == fieldInterfaceName:
+			iface = val
+		case field == fieldSSID:
+			// SSID 1 : HomeNet
+			networks = append(networks, VisibleNetwork{InterfaceName: iface, SSID: val})
+			cur = &networks[len(networks)-1]
+			bss = nil
+		case cur == nil:
+			continue
+		case field == fieldNetworkType:
+			cur.NetworkType = val
+		case field == fieldAuthentication:
+			cur.Authentication = val
+		case field == fieldCipher:
+			cur.Encryption = val
+		case field == fieldBSSID:
+			// BSSID 1 : aa:bb:cc:dd:ee:ff
+			cur.BSSIDs = append(cur.BSSIDs, BSSIDInfo{BSSID: val})
+			bss = &cur.BSSIDs[len(cur.BSSIDs)-1]
+		case bss == nil:
+			continue
+		case field == fieldSignal:
+			if v, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(val, "%"))); err == nil {
+				bss.Signal = v
+			}
+		case field == fieldRadioType:
+			bss.RadioType = val
+		case field == fieldBand:
+			bss.Band = val
+		case field == fieldChannel:
+			if v, err := strconv.Atoi(val); err == nil {
+				bss.Channel = v
+			}
+		case field == fieldBasicRates:
+			bss.BasicRates = parseRates(val)
+		case field == fieldOtherRates:
+			bss.OtherRates = parseRates(val)
+		}
 	}
-	return strings.TrimSpace(parts[1])
+
+	for i := range networks {
+		for j := range networks[i].BSSIDs {
+			b := &networks[i].BSSIDs[j]
+			if band := normalizeBand(b.Band); band != "" {
+				b.Band = band
+			} else {
+				b.Band = BandFromChannel(b.Channel)
+			}
+		}
+	}
+	return networks
+}
+
+// parseRates parses a space separated rate list such as "1 2 5.5 11".
+func parseRates(val string) []float64 {
+	var rates []float64
+	for _, f := range strings.Fields(val) {
+		if v, err := strconv.ParseFloat(f, 64); err == nil {
+			rates = append(rates, v)
+		}
+	}
+	return rates
 }
 
 
@@ -79,8 +227,8 @@ func DebugStatus(s *WifiStatus) string {
 	if s == nil {
 		return "no wifi status"
 	}
-	return fmt.Sprintf("Interface=%s, SSID=%s, Profile=%s, Signal=%d%%",
-		s.InterfaceName, s.SSID, s.ProfileName, s.Signal)
+	return fmt.Sprintf("Interface=%s, SSID=%s, BSSID=%s, Profile=%s, Signal=%d%% (%d dBm), Band=%s, Channel=%d",
+		s.InterfaceName, s.SSID, s.BSSID, s.ProfileName, s.Signal, s.RSSI, s.Band, s.Channel)
 }
 
 // SimplePingResult holds ping statistics.
@@ -88,19 +236,20 @@ type SimplePingResult struct {
 	AvgMs int
 }
 
-// ParsePingOutput parses `ping -n 3 8.8.8.8` output for average time.
+// ParsePingOutput parses `ping -n 3 8.8.8.8` output for average time,
+// in any display language.
 func ParsePingOutput(out string) *SimplePingResult {
 	// Windows format example:
 	// Approximate round trip times in milli-seconds:
 	//     Minimum = 12ms, Maximum = 15ms, Average = 13ms
-	re := regexp.MustCompile(`Average\s*=\s*(\d+)ms`)
-	m := re.FindStringSubmatch(out)
-	if len(m) != 2 {
-		return nil
+	// German: Minimum = 12ms, Maximum = 15ms, Mittelwert = 13ms
+	if m := averageRe.FindStringSubmatch(out); len(m) == 2 {
+		if v, err := strconv.Atoi(m[1]); err == nil {
+			return &SimplePingResult{AvgMs: v}
+		}
 	}
-	v, err := strconv.Atoi(m[1])
-	if err != nil {
-		return nil
+	if v, ok := pingAverageByPosition(out); ok {
+		return &SimplePingResult{AvgMs: v}
 	}
-	return &SimplePingResult{AvgMs: v}
+	return nil
 }
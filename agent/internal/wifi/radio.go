@@ -0,0 +1,91 @@
+package wifi
+
+import "strings"
+
+// ChannelFromFrequency converts a centre frequency in MHz to its
+// 802.11 channel number, or 0 when the frequency is not a Wi-Fi channel.
+func ChannelFromFrequency(mhz int) int {
+	switch {
+	case mhz == 2484:
+		return 14
+	case mhz >= 2412 && mhz < 2484:
+		return (mhz - 2407) / 5
+	case mhz >= 5160 && mhz <= 5885:
+		return (mhz - 5000) / 5
+	case mhz == 5935:
+		return 2
+	case mhz >= 5955 && mhz <= 7115:
+		return (mhz - 5950) / 5
+	}
+	return 0
+}
+
+const (
+	Band24GHz = "2.4 GHz"
+	Band5GHz  = "5 GHz"
+	Band6GHz  = "6 GHz"
+)
+
+// BandFromFrequency maps a centre frequency in MHz to its band.
+func BandFromFrequency(mhz int) string {
+	switch {
+	case mhz >= 2400 && mhz < 2500:
+		return Band24GHz
+	case mhz >= 5150 && mhz < 5925:
+		return Band5GHz
+	case mhz >= 5925 && mhz <= 7125:
+		return Band6GHz
+	}
+	return ""
+}
+
+// BandFromChannel guesses the band from a channel number. 6 GHz reuses
+// low channel numbers, so this is only used when the backend reports
+// neither band nor frequency; channels above 177 are 6 GHz only.
+func BandFromChannel(channel int) string {
+	switch {
+	case channel >= 1 && channel <= 14:
+		return Band24GHz
+	case channel >= 32 && channel <= 177:
+		return Band5GHz
+	case channel > 177 && channel <= 233:
+		return Band6GHz
+	}
+	return ""
+}
+
+// normalizeBand turns netsh's "5 GHz" / nmcli's "5GHz" style labels into
+// the Band* constants.
+func normalizeBand(raw string) string {
+	switch strings.ReplaceAll(strings.TrimSpace(raw), " ", "") {
+	case "2.4GHz":
+		return Band24GHz
+	case "5GHz":
+		return Band5GHz
+	case "6GHz":
+		return Band6GHz
+	}
+	return ""
+}
+
+// RSSIFromSignal approximates dBm from the 0-100 quality scale; the
+// inverse of SignalFromRSSI.
+func RSSIFromSignal(signal int) int {
+	if signal <= 0 {
+		return -100
+	}
+	if signal >= 100 {
+		return -50
+	}
+	return signal/2 - 100
+}
+
+// fillDerived sets Band and RSSI when the backend did not report them.
+func (s *WifiStatus) fillDerived() {
+	if s.Band == "" {
+		s.Band = BandFromChannel(s.Channel)
+	}
+	if s.RSSI == 0 && s.Signal > 0 {
+		s.RSSI = RSSIFromSignal(s.Signal)
+	}
+}
@@ -0,0 +1,42 @@
+package wifi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// CommandRunner runs an external command and returns its stdout. The
+// managers and the system pinger go through it so that command output
+// can be recorded and replayed. The command is killed once ctx is done.
+type CommandRunner interface {
+	Run(ctx context.Context, name string, args ...string) (string, error)
+}
+
+// ExecRunner runs commands for real.
+type ExecRunner struct{}
+
+// Run returns what the command printed even when it fails or is killed,
+// since e.g. ping exits non-zero when replies are lost.
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return out.String(), fmt.Errorf("%s %v: %w", name, args, ctx.Err())
+		}
+		return out.String(), fmt.Errorf("%s %v failed: %v | stderr: %s", name, args, err, stderr.String())
+	}
+	return out.String(), nil
+}
+
+func runnerOrExec(r CommandRunner) CommandRunner {
+	if r == nil {
+		return ExecRunner{}
+	}
+	return r
+}
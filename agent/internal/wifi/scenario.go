@@ -0,0 +1,107 @@
+package wifi
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario scripts what a SimulatedManager reports over time.
+//
+//	check_interval: 5s
+//	interfaces: [sim0]
+//	start: {sim0: HomeNet}
+//	networks:
+//	  - ssid: HomeNet
+//	    signal: [{at: 0s, value: 85}, {at: 60s, value: 25}]
+//	    ping:   [{at: 0s, value: 20}, {at: 60s, value: 300}]
+//	    jitter: [{at: 30s, value: 0}, {at: 60s, value: 40}]
+//	    loss:   [{at: 45s, value: 0}, {at: 60s, value: 10}]
+//	  - ssid: Backup
+//	    signal: [{at: 0s, value: 70}]
+//	    connect: {fail_first: 1, latency: 3s}
+type Scenario struct {
+	CheckInterval time.Duration     `yaml:"check_interval"`
+	Interfaces    []string          `yaml:"interfaces"`
+	Start         map[string]string `yaml:"start"` // interface -> SSID
+	Networks      []SimNetwork      `yaml:"networks"`
+}
+
+// SimNetwork is one scripted SSID.
+type SimNetwork struct {
+	SSID           string     `yaml:"ssid"`
+	BSSID          string     `yaml:"bssid"`
+	Channel        int        `yaml:"channel"`
+	RadioType      string     `yaml:"radio_type"`
+	Authentication string     `yaml:"authentication"`
+	Saved          *bool      `yaml:"saved"` // default true
+	Signal         Curve      `yaml:"signal"`
+	Ping           Curve      `yaml:"ping"`    // ms; a value <= 0 means the ping fails
+	Jitter         Curve      `yaml:"jitter"`  // ms; each reply varies by up to this much
+	Loss           Curve      `yaml:"loss"`    // percent of replies lost
+	Captive        bool       `yaml:"captive"` // a login portal intercepts web traffic
+	Connect        SimConnect `yaml:"connect"`
+}
+
+// SimConnect scripts how Connect behaves for a network.
+type SimConnect struct {
+	Fail      bool          `yaml:"fail"`       // every attempt fails
+	FailFirst int           `yaml:"fail_first"` // the first N attempts fail
+	Latency   time.Duration `yaml:"latency"`    // time spent associating
+}
+
+// CurvePoint is a value at an offset from the start of the scenario.
+type CurvePoint struct {
+	At    time.Duration `yaml:"at"`
+	Value float64       `yaml:"value"`
+}
+
+// Curve is a piecewise-linear function of scenario time.
+type Curve []CurvePoint
+
+// At interpolates the curve at t, holding the first/last value outside it.
+func (c Curve) At(t time.Duration) float64 {
+	if len(c) == 0 {
+		return 0
+	}
+	if t <= c[0].At {
+		return c[0].Value
+	}
+	for i := 1; i < len(c); i++ {
+		if t <= c[i].At {
+			a, b := c[i-1], c[i]
+			frac := float64(t-a.At) / float64(b.At-a.At)
+			return a.Value + frac*(b.Value-a.Value)
+		}
+	}
+	return c[len(c)-1].Value
+}
+
+// LoadScenario reads a YAML scenario file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse scenario %s: %v", path, err)
+	}
+	if len(s.Interfaces) == 0 {
+		s.Interfaces = []string{"sim0"}
+	}
+	for i := range s.Networks {
+		n := &s.Networks[i]
+		if n.SSID == "" {
+			return nil, fmt.Errorf("scenario %s: network %d has no ssid", path, i)
+		}
+		sort.SliceStable(n.Signal, func(a, b int) bool { return n.Signal[a].At < n.Signal[b].At })
+		sort.SliceStable(n.Ping, func(a, b int) bool { return n.Ping[a].At < n.Ping[b].At })
+		sort.SliceStable(n.Jitter, func(a, b int) bool { return n.Jitter[a].At < n.Jitter[b].At })
+		sort.SliceStable(n.Loss, func(a, b int) bool { return n.Loss[a].At < n.Loss[b].At })
+	}
+	return &s, nil
+}
@@ -0,0 +1,253 @@
+package wifi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SimulatedManager implements Manager from a Scenario, for demos and
+// deterministic tests. Scenario time is measured from construction using
+// Now, so passing a virtual clock's Now makes it fully reproducible.
+type SimulatedManager struct {
+	Scenario *Scenario
+	Now      func() time.Time
+
+	mu       sync.Mutex
+	start    time.Time
+	assoc    map[string]string    // interface -> SSID
+	pending  map[string]time.Time // interface -> association completes at
+	attempts map[string]int       // SSID -> connect attempts so far
+	connects []string             // "iface:ssid:ok|fail", in order
+	rng      *rand.Rand           // fixed seed, so jitter and loss replay the same
+}
+
+func NewSimulatedManager(s *Scenario, now func() time.Time) *SimulatedManager {
+	if now == nil {
+		now = time.Now
+	}
+	m := &SimulatedManager{
+		Scenario: s,
+		Now:      now,
+		start:    now(),
+		assoc:    make(map[string]string),
+		pending:  make(map[string]time.Time),
+		attempts: make(map[string]int),
+		rng:      rand.New(rand.NewSource(1)),
+	}
+	for iface, ssid := range s.Start {
+		m.assoc[iface] = ssid
+	}
+	return m
+}
+
+// elapsed returns scenario time; callers hold m.mu.
+func (m *SimulatedManager) elapsed() time.Duration {
+	return m.Now().Sub(m.start)
+}
+
+func (m *SimulatedManager) network(ssid string) *SimNetwork {
+	for i := range m.Scenario.Networks {
+		if m.Scenario.Networks[i].SSID == ssid {
+			return &m.Scenario.Networks[i]
+		}
+	}
+	return nil
+}
+
+func (m *SimulatedManager) signalOf(n *SimNetwork, t time.Duration) int {
+	v := int(math.Round(n.Signal.At(t)))
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+func (m *SimulatedManager) ListProfiles(ctx context.Context) ([]WifiProfile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var profiles []WifiProfile
+	for _, n := range m.Scenario.Networks {
+		if n.Saved != nil && !*n.Saved {
+			continue
+		}
+		profiles = append(profiles, WifiProfile{RawName: n.SSID, CleanName: strings.TrimSpace(n.SSID)})
+	}
+	return profiles, nil
+}
+
+func (m *SimulatedManager) GetCurrentStatus(ctx context.Context) (*WifiStatus, error) {
+	statuses, err := m.GetInterfaceStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range statuses {
+		if st.SSID != "" {
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("no active Wi-Fi interface found")
+}
+
+func (m *SimulatedManager) GetInterfaceStatuses(ctx context.Context) ([]*WifiStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.elapsed()
+	var statuses []*WifiStatus
+	for _, iface := range m.Scenario.Interfaces {
+		st := &WifiStatus{InterfaceName: iface, State: "disconnected"}
+		if until, ok := m.pending[iface]; ok {
+			if m.Now().Before(until) {
+				st.State = "associating"
+				statuses = append(statuses, st)
+				continue
+			}
+			delete(m.pending, iface)
+		}
+
+		if n := m.network(m.assoc[iface]); n != nil {
+			signal := m.signalOf(n, t)
+			if signal > 0 {
+				st.State = "connected"
+				st.SSID = n.SSID
+				st.ProfileName = n.SSID
+				st.BSSID = n.BSSID
+				st.Channel = n.Channel
+				st.RadioType = n.RadioType
+				st.Authentication = n.Authentication
+				st.Signal = signal
+			} else {
+				// Out of range: the link drops.
+				delete(m.assoc, iface)
+			}
+		}
+		st.fillDerived()
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+func (m *SimulatedManager) Connect(ctx context.Context, profile WifiProfile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	iface := profile.InterfaceName
+	if iface == "" {
+		iface = m.Scenario.Interfaces[0]
+	}
+	n := m.network(profile.RawName)
+	if n == nil {
+		return fmt.Errorf("simulated connect %s: unknown network", profile.RawName)
+	}
+
+	m.attempts[n.SSID]++
+	attempt := m.attempts[n.SSID]
+	fail := n.Connect.Fail || attempt <= n.Connect.FailFirst || m.signalOf(n, m.elapsed()) == 0
+	if fail {
+		m.connects = append(m.connects, iface+":"+n.SSID+":fail")
+		return fmt.Errorf("simulated connect %s on %s failed (attempt %d)", n.SSID, iface, attempt)
+	}
+
+	m.connects = append(m.connects, iface+":"+n.SSID+":ok")
+	m.assoc[iface] = n.SSID
+	if n.Connect.Latency > 0 {
+		m.pending[iface] = m.Now().Add(n.Connect.Latency)
+	}
+	return nil
+}
+
+func (m *SimulatedManager) ScanNetworks(ctx context.Context) ([]VisibleNetwork, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.elapsed()
+	var visible []VisibleNetwork
+	for _, iface := range m.Scenario.Interfaces {
+		for i := range m.Scenario.Networks {
+			n := &m.Scenario.Networks[i]
+			signal := m.signalOf(n, t)
+			if signal == 0 {
+				continue
+			}
+			visible = append(visible, VisibleNetwork{
+				InterfaceName:  iface,
+				SSID:           n.SSID,
+				NetworkType:    "Infrastructure",
+				Authentication: n.Authentication,
+				BSSIDs: []BSSIDInfo{{
+					BSSID:     n.BSSID,
+					Signal:    signal,
+					RadioType: n.RadioType,
+					Channel:   n.Channel,
+					Band:      BandFromChannel(n.Channel),
+				}},
+			})
+		}
+	}
+	return visible, nil
+}
+
+// ConnectLog returns every connect attempt as "iface:ssid:ok|fail", in order.
+func (m *SimulatedManager) ConnectLog() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.connects...)
+}
+
+// PingRTT returns one round trip to host following the ping curve of the
+// network the first associated interface is on; probe.Simulated wraps it.
+func (m *SimulatedManager) PingRTT(host string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.elapsed()
+	n := m.activeNetwork(t)
+	if n == nil {
+		return 0, fmt.Errorf("simulated ping %s: no network", host)
+	}
+	if len(n.Ping) == 0 {
+		return 20 * time.Millisecond, nil
+	}
+	ms := n.Ping.At(t)
+	if ms <= 0 || m.rng.Float64()*100 < n.Loss.At(t) {
+		return 0, fmt.Errorf("simulated ping %s: request timed out", host)
+	}
+	if j := n.Jitter.At(t); j > 0 {
+		ms = math.Max(1, ms+(m.rng.Float64()*2-1)*j)
+	}
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
+
+// CaptivePortal reports whether the active network sits behind a login
+// portal; probe.SimulatedCaptive wraps it.
+func (m *SimulatedManager) CaptivePortal() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := m.activeNetwork(m.elapsed())
+	return n != nil && n.Captive
+}
+
+// activeNetwork is the network of the first interface that is associated
+// and in range; traffic is taken to leave over it. Callers hold m.mu.
+func (m *SimulatedManager) activeNetwork(t time.Duration) *SimNetwork {
+	for _, iface := range m.Scenario.Interfaces {
+		if until, ok := m.pending[iface]; ok && m.Now().Before(until) {
+			continue
+		}
+		n := m.network(m.assoc[iface])
+		if n == nil || m.signalOf(n, t) == 0 {
+			continue
+		}
+		return n
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+package wifi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultWpaCtrlDir is where wpa_supplicant creates its control sockets.
+const DefaultWpaCtrlDir = "/var/run/wpa_supplicant"
+
+var wpaLocalSeq atomic.Uint64
+
+// WpaManager implements Manager by talking to the wpa_supplicant control
+// socket directly, for minimal Linux images without NetworkManager.
+type WpaManager struct {
+	CtrlPath string        // e.g. /var/run/wpa_supplicant/wlan0
+	Timeout  time.Duration // per-request reply timeout
+}
+
+func NewWpaManager(ctrlPath string) *WpaManager {
+	return &WpaManager{CtrlPath: ctrlPath, Timeout: 3 * time.Second}
+}
+
+// FindWpaCtrl returns the first station control socket in dir, if any.
+// The p2p-dev-* sockets belong to Wi-Fi Direct devices, which have no
+// association to report.
+func FindWpaCtrl(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.Type()&os.ModeSocket != 0 && !strings.HasPrefix(e.Name(), "p2p-dev-") {
+			return filepath.Join(dir, e.Name()), nil
+		}
+	}
+	return "", fmt.Errorf("no wpa_supplicant control socket in %s", dir)
+}
+
+// request sends one command over the unix datagram protocol and returns
+// the reply, waiting at most Timeout and never past ctx's deadline.
+func (w *WpaManager) request(ctx context.Context, cmd string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("wpa_ctrl %s: %w", cmd, err)
+	}
+	local := filepath.Join(os.TempDir(),
+		fmt.Sprintf("wpa_ctrl_%d-%d", os.Getpid(), wpaLocalSeq.Add(1)))
+	conn, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: local, Net: "unixgram"},
+		&net.UnixAddr{Name: w.CtrlPath, Net: "unixgram"})
+	if err != nil {
+		return "", fmt.Errorf("wpa_ctrl dial %s: %v", w.CtrlPath, err)
+	}
+	defer os.Remove(local)
+	defer conn.Close()
+
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+	// A cancel without a deadline unblocks the read too.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+	if _, err := conn.Write([]byte(cmd)); err != nil {
+		return "", fmt.Errorf("wpa_ctrl %s: %v", cmd, err)
+	}
+
+	buf := make([]byte, 8192)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return "", fmt.Errorf("wpa_ctrl %s: %v", cmd, err)
+		}
+		reply := string(buf[:n])
+		// Unsolicited events look like "<3>CTRL-EVENT-..."; skip them.
+		if strings.HasPrefix(reply, "<") {
+			continue
+		}
+		return reply, nil
+	}
+}
+
+func (w *WpaManager) ListProfiles(ctx context.Context) ([]WifiProfile, error) {
+	out, err := w.request(ctx, "LIST_NETWORKS")
+	if err != nil {
+		return nil, err
+	}
+	var profiles []WifiProfile
+	for _, n := range ParseWpaNetworks(out) {
+		profiles = append(profiles, WifiProfile{
+			RawName:   n.SSID,
+			CleanName: strings.TrimSpace(n.SSID),
+		})
+	}
+	return profiles, nil
+}
+
+func (w *WpaManager) GetCurrentStatus(ctx context.Context) (*WifiStatus, error) {
+	status, err := w.readStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.SSID == "" {
+		return nil, fmt.Errorf("no active Wi-Fi interface found")
+	}
+	return status, nil
+}
+
+// GetInterfaceStatuses returns the single interface this control socket manages.
+func (w *WpaManager) GetInterfaceStatuses(ctx context.Context) ([]*WifiStatus, error) {
+	status, err := w.readStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []*WifiStatus{status}, nil
+}
+
+// readStatus combines STATUS and SIGNAL_POLL. SSID is left empty unless
+// wpa_supplicant reports the association as COMPLETED.
+func (w *WpaManager) readStatus(ctx context.Context) (*WifiStatus, error) {
+	out, err := w.request(ctx, "STATUS")
+	if err != nil {
+		return nil, err
+	}
+	kv := parseWpaKeyValues(out)
+	if kv["wpa_state"] != "COMPLETED" || kv["ssid"] == "" {
+		return &WifiStatus{
+			InterfaceName: filepath.Base(w.CtrlPath),
+			State:         strings.ToLower(kv["wpa_state"]),
+		}, nil
+	}
+
+	freq, _ := strconv.Atoi(kv["freq"])
+	ssid := wpaUnescape(kv["ssid"])
+	status := &WifiStatus{
+		InterfaceName:  filepath.Base(w.CtrlPath),
+		State:          "connected",
+		SSID:           ssid,
+		BSSID:          kv["bssid"],
+		ProfileName:    ssid,
+		Authentication: kv["key_mgmt"],
+		Cipher:         kv["pairwise_cipher"],
+		Channel:        ChannelFromFrequency(freq),
+		Band:           BandFromFrequency(freq),
+	}
+
+	if poll, err := w.request(ctx, "SIGNAL_POLL"); err == nil {
+		pv := parseWpaKeyValues(poll)
+		if rssi, err := strconv.Atoi(pv["RSSI"]); err == nil {
+			status.RSSI = rssi
+			status.Signal = SignalFromRSSI(rssi)
+		}
+		// LINKSPEED is the current TX bitrate in Mbps.
+		if v, err := strconv.ParseFloat(pv["LINKSPEED"], 64); err == nil {
+			status.TransmitRateMbps = v
+		}
+	}
+	status.fillDerived()
+	return status, nil
+}
+
+func (w *WpaManager) Connect(ctx context.Context, profile WifiProfile) error {
+	out, err := w.request(ctx, "LIST_NETWORKS")
+	if err != nil {
+		return err
+	}
+	for _, n := range ParseWpaNetworks(out) {
+		if n.SSID != profile.RawName {
+			continue
+		}
+		reply, err := w.request(ctx, "SELECT_NETWORK "+n.ID)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(reply) != "OK" {
+			return fmt.Errorf("wpa_ctrl SELECT_NETWORK %s: %s", n.ID, strings.TrimSpace(reply))
+		}
+		return nil
+	}
+	return fmt.Errorf("no configured network for %q", profile.RawName)
+}
+
+// ScanResults returns the last scan wpa_supplicant has cached.
+func (w *WpaManager) ScanResults(ctx context.Context) ([]WpaScanResult, error) {
+	out, err := w.request(ctx, "SCAN_RESULTS")
+	if err != nil {
+		return nil, err
+	}
+	return ParseWpaScanResults(out), nil
+}
+
+// ScanNetworks groups the cached SCAN_RESULTS by SSID.
+func (w *WpaManager) ScanNetworks(ctx context.Context) ([]VisibleNetwork, error) {
+	results, err := w.ScanResults(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return WpaScanToNetworks(filepath.Base(w.CtrlPath), results), nil
+}
+
+// WpaNetwork is one LIST_NETWORKS row.
+type WpaNetwork struct {
+	ID    string
+	SSID  string
+	BSSID string
+	Flags string
+}
+
+// WpaScanResult is one SCAN_RESULTS row.
+type WpaScanResult struct {
+	BSSID     string
+	Frequency int // MHz
+	SignalDBm int
+	Flags     string
+	SSID      string
+}
+
+// ParseWpaNetworks parses LIST_NETWORKS output:
+//
+//	network id / ssid / bssid / flags
+//	0	HomeNet	any	[CURRENT]
+func ParseWpaNetworks(output string) []WpaNetwork {
+	var networks []WpaNetwork
+	for i, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if i == 0 || line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		n := WpaNetwork{ID: fields[0], SSID: wpaUnescape(fields[1])}
+		if len(fields) > 2 {
+			n.BSSID = fields[2]
+		}
+		if len(fields) > 3 {
+			n.Flags = fields[3]
+		}
+		networks = append(networks, n)
+	}
+	return networks
+}
+
+// ParseWpaScanResults parses SCAN_RESULTS output:
+//
+//	bssid / frequency / signal level / flags / ssid
+//	aa:bb:cc:dd:ee:ff	2437	-52	[WPA2-PSK-CCMP][ESS]	HomeNet
+func ParseWpaScanResults(output string) []WpaScanResult {
+	var results []WpaScanResult
+	for i, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if i == 0 || line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 {
+			continue
+		}
+		r := WpaScanResult{BSSID: fields[0], Flags: fields[3]}
+		r.Frequency, _ = strconv.Atoi(fields[1])
+		r.SignalDBm, _ = strconv.Atoi(fields[2])
+		if len(fields) > 4 {
+			// Some drivers report a hidden SSID as its length in NULs.
+			r.SSID = strings.Trim(wpaUnescape(fields[4]), "\x00")
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// WpaScanToNetworks converts SCAN_RESULTS rows to the shared scan model,
+// with each hidden BSSID as a network of its own.
+func WpaScanToNetworks(iface string, results []WpaScanResult) []VisibleNetwork {
+	var networks []VisibleNetwork
+	index := make(map[string]int)
+
+	for _, r := range results {
+		key := r.SSID
+		if key == "" {
+			key = "\x00" + r.BSSID
+		}
+		i, ok := index[key]
+		if !ok {
+			auth, enc := parseWpaFlags(r.Flags)
+			networks = append(networks, VisibleNetwork{
+				InterfaceName:  iface,
+				SSID:           r.SSID,
+				NetworkType:    "Infrastructure",
+				Authentication: auth,
+				Encryption:     enc,
+			})
+			i = len(networks) - 1
+			index[key] = i
+		}
+		networks[i].BSSIDs = append(networks[i].BSSIDs, BSSIDInfo{
+			BSSID:   r.BSSID,
+			Signal:  SignalFromRSSI(r.SignalDBm),
+			Channel: ChannelFromFrequency(r.Frequency),
+			Band:    BandFromFrequency(r.Frequency),
+		})
+	}
+	return networks
+}
+
+// parseWpaFlags splits the first security flag, e.g. "[WPA2-PSK-CCMP][ESS]"
+// becomes ("WPA2-PSK", "CCMP").
+func parseWpaFlags(flags string) (auth, enc string) {
+	for _, tok := range strings.Split(flags, "]") {
+		tok = strings.TrimPrefix(tok, "[")
+		if tok == "" || tok == "ESS" || tok == "IBSS" || tok == "P2P" || tok == "WPS" {
+			continue
+		}
+		parts := strings.Split(tok, "-")
+		if len(parts) < 3 {
+			return tok, ""
+		}
+		return strings.Join(parts[:len(parts)-1], "-"), parts[len(parts)-1]
+	}
+	return "Open", "None"
+}
+
+// wpaUnescape undoes the printf-style escaping wpa_supplicant applies to
+// SSIDs in its replies: \\, \", \e, \n, \r, \t and \xNN for any other
+// byte outside printable ASCII, so "Caf\xc3\xa9" is "Café".
+func wpaUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			out = append(out, s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'e':
+			out = append(out, 0x1b)
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'x':
+			if i+2 < len(s) {
+				if b, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+					out = append(out, byte(b))
+					i += 2
+					continue
+				}
+			}
+			out = append(out, '\\', 'x')
+		default:
+			out = append(out, s[i]) // \\ and \"
+		}
+	}
+	return string(out)
+}
+
+// parseWpaKeyValues parses the key=value replies of STATUS and SIGNAL_POLL.
+func parseWpaKeyValues(output string) map[string]string {
+	kv := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		k, v, ok := strings.Cut(strings.TrimRight(line, "\r"), "=")
+		if ok {
+			kv[k] = v
+		}
+	}
+	return kv
+}
+
+// SignalFromRSSI maps dBm to the 0-100 quality scale netsh uses
+// (-100 dBm -> 0%, -50 dBm -> 100%).
+func SignalFromRSSI(dBm int) int {
+	q := 2 * (dBm + 100)
+	if q < 0 {
+		return 0
+	}
+	if q > 100 {
+		return 100
+	}
+	return q
+}
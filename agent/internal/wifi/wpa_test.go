@@ -0,0 +1,274 @@
+package wifi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWpa is a wpa_supplicant control socket that answers from a table and
+// remembers the commands it was sent.
+type fakeWpa struct {
+	conn    *net.UnixConn
+	replies map[string]string
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func startFakeWpa(t *testing.T, dir, name string, replies map[string]string) *fakeWpa {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	f := &fakeWpa{conn: conn, replies: replies}
+	t.Cleanup(func() { conn.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeWpa) serve() {
+	buf := make([]byte, 4096)
+	for {
+		n, from, err := f.conn.ReadFromUnix(buf)
+		if err != nil {
+			return
+		}
+		cmd := string(buf[:n])
+		f.mu.Lock()
+		f.commands = append(f.commands, cmd)
+		f.mu.Unlock()
+
+		reply, ok := f.replies[cmd]
+		if !ok {
+			reply = "UNKNOWN COMMAND\n"
+		}
+		// A client attached for events would get these between replies.
+		f.conn.WriteToUnix([]byte("<3>CTRL-EVENT-SCAN-RESULTS "), from)
+		f.conn.WriteToUnix([]byte(reply), from)
+	}
+}
+
+func (f *fakeWpa) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+var wpaReplies = map[string]string{
+	"STATUS": "bssid=aa:bb:cc:dd:ee:02\nfreq=5180\nssid=Caf\\xc3\\xa9 \\\"Lab\\\"\nid=1\nmode=station\n" +
+		"pairwise_cipher=CCMP\ngroup_cipher=CCMP\nkey_mgmt=WPA2-PSK\nwpa_state=COMPLETED\nip_address=10.0.0.23\n",
+	"SIGNAL_POLL": "RSSI=-58\nLINKSPEED=433\nNOISE=9999\nFREQUENCY=5180\n",
+	"LIST_NETWORKS": "network id / ssid / bssid / flags\n" +
+		"0\tHomeNet\tany\t\n" +
+		"1\tCaf\\xc3\\xa9 \\\"Lab\\\"\tany\t[CURRENT]\n" +
+		"2\tBackup\tany\t[DISABLED]\n",
+	"SCAN_RESULTS": "bssid / frequency / signal level / flags / ssid\n" +
+		"aa:bb:cc:dd:ee:01\t2437\t-52\t[WPA2-PSK-CCMP][ESS]\tHomeNet\n" +
+		"aa:bb:cc:dd:ee:11\t5745\t-61\t[WPA2-PSK-CCMP][ESS]\tHomeNet\n" +
+		"aa:bb:cc:dd:ee:02\t5180\t-58\t[WPA2-PSK-CCMP][ESS]\tCaf\\xc3\\xa9 \\\"Lab\\\"\n" +
+		"aa:bb:cc:dd:ee:03\t2462\t-70\t[WPA2-PSK-CCMP][ESS]\t\n" +
+		"aa:bb:cc:dd:ee:04\t2412\t-80\t[WPA2-PSK-CCMP][ESS]\t\\x00\\x00\\x00\\x00\n" +
+		"aa:bb:cc:dd:ee:05\t2412\t-75\t[ESS]\tGuest\n",
+	"SELECT_NETWORK 0": "OK\n",
+	"SELECT_NETWORK 2": "FAIL\n",
+}
+
+const cafeLab = `Café "Lab"`
+
+func newFakeWpaManager(t *testing.T) (*WpaManager, *fakeWpa) {
+	dir := t.TempDir()
+	f := startFakeWpa(t, dir, "wlan0", wpaReplies)
+	w := NewWpaManager(filepath.Join(dir, "wlan0"))
+	w.Timeout = 2 * time.Second
+	return w, f
+}
+
+func TestWpaManagerStatus(t *testing.T) {
+	w, _ := newFakeWpaManager(t)
+	s, err := w.GetCurrentStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.InterfaceName != "wlan0" || s.State != "connected" {
+		t.Errorf("status %s %s", s.InterfaceName, s.State)
+	}
+	if s.SSID != cafeLab || s.ProfileName != cafeLab {
+		t.Errorf("SSID %q profile %q, want %q", s.SSID, s.ProfileName, cafeLab)
+	}
+	if s.BSSID != "aa:bb:cc:dd:ee:02" || s.Channel != 36 || s.Band != Band5GHz {
+		t.Errorf("BSSID %s channel %d band %s", s.BSSID, s.Channel, s.Band)
+	}
+	if s.RSSI != -58 || s.Signal != 84 || s.TransmitRateMbps != 433 {
+		t.Errorf("RSSI %d signal %d rate %v", s.RSSI, s.Signal, s.TransmitRateMbps)
+	}
+	if s.Authentication != "WPA2-PSK" || s.Cipher != "CCMP" {
+		t.Errorf("security %s/%s", s.Authentication, s.Cipher)
+	}
+}
+
+func TestWpaManagerNotAssociated(t *testing.T) {
+	dir := t.TempDir()
+	startFakeWpa(t, dir, "wlan0", map[string]string{"STATUS": "wpa_state=SCANNING\n"})
+	w := NewWpaManager(filepath.Join(dir, "wlan0"))
+
+	if _, err := w.GetCurrentStatus(context.Background()); err == nil {
+		t.Error("GetCurrentStatus succeeded while scanning")
+	}
+	all, err := w.GetInterfaceStatuses(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].State != "scanning" || all[0].SSID != "" {
+		t.Errorf("interfaces = %+v", all)
+	}
+}
+
+func TestWpaManagerListProfiles(t *testing.T) {
+	w, _ := newFakeWpaManager(t)
+	profiles, err := w.ListProfiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range profiles {
+		names = append(names, p.CleanName)
+	}
+	if got, want := strings.Join(names, "|"), "HomeNet|"+cafeLab+"|Backup"; got != want {
+		t.Errorf("profiles = %s, want %s", got, want)
+	}
+}
+
+func TestWpaManagerScanNetworks(t *testing.T) {
+	w, _ := newFakeWpaManager(t)
+	networks, err := w.ScanNetworks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySSID := make(map[string]VisibleNetwork)
+	hidden := 0
+	for _, n := range networks {
+		if n.InterfaceName != "wlan0" {
+			t.Errorf("%s on %q", n.SSID, n.InterfaceName)
+		}
+		if n.SSID == "" {
+			hidden++
+			continue
+		}
+		bySSID[n.SSID] = n
+	}
+	if hidden != 2 {
+		t.Errorf("got %d hidden networks, want one per hidden BSSID", hidden)
+	}
+	home := bySSID["HomeNet"]
+	if len(home.BSSIDs) != 2 || home.BestSignal() != 96 || home.Authentication != "WPA2-PSK" || home.Encryption != "CCMP" {
+		t.Errorf("HomeNet = %+v", home)
+	}
+	if home.BSSIDs[1].Band != Band5GHz || home.BSSIDs[1].Channel != 149 {
+		t.Errorf("HomeNet 5 GHz BSSID = %+v", home.BSSIDs[1])
+	}
+	if _, ok := bySSID[cafeLab]; !ok {
+		t.Errorf("%s missing from %+v", cafeLab, networks)
+	}
+	if g := bySSID["Guest"]; g.Authentication != "Open" || g.Encryption != "None" {
+		t.Errorf("Guest security %s/%s", g.Authentication, g.Encryption)
+	}
+}
+
+func TestWpaManagerConnect(t *testing.T) {
+	w, f := newFakeWpaManager(t)
+	if err := w.Connect(context.Background(), WifiProfile{RawName: "HomeNet"}); err != nil {
+		t.Fatal(err)
+	}
+	sent := f.sent()
+	if last := sent[len(sent)-1]; last != "SELECT_NETWORK 0" {
+		t.Errorf("last command %q, want SELECT_NETWORK 0", last)
+	}
+
+	if err := w.Connect(context.Background(), WifiProfile{RawName: "Backup"}); err == nil || !strings.Contains(err.Error(), "FAIL") {
+		t.Errorf("Connect(Backup) = %v, want the FAIL reply", err)
+	}
+	if err := w.Connect(context.Background(), WifiProfile{RawName: "Nowhere"}); err == nil {
+		t.Error("Connect to an unconfigured network succeeded")
+	}
+}
+
+func TestWpaManagerNoServer(t *testing.T) {
+	w := NewWpaManager(filepath.Join(t.TempDir(), "wlan0"))
+	if _, err := w.GetCurrentStatus(context.Background()); err == nil {
+		t.Error("GetCurrentStatus succeeded with no wpa_supplicant")
+	}
+}
+
+// A wpa_supplicant that never answers holds a call only until ctx is done,
+// however long Timeout is.
+func TestWpaManagerCancelled(t *testing.T) {
+	dir := t.TempDir()
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "wlan0"), Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+	w := NewWpaManager(filepath.Join(dir, "wlan0"))
+	w.Timeout = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := w.GetCurrentStatus(ctx); err == nil {
+		t.Error("GetCurrentStatus succeeded with no reply")
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("GetCurrentStatus returned after %s, want soon after the cancel", d)
+	}
+	if err := w.Connect(ctx, WifiProfile{RawName: "HomeNet"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Connect after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestFindWpaCtrl(t *testing.T) {
+	dir := t.TempDir()
+	startFakeWpa(t, dir, "p2p-dev-wlan0", nil)
+	if _, err := FindWpaCtrl(dir); err == nil {
+		t.Error("FindWpaCtrl chose the P2P device socket")
+	}
+
+	startFakeWpa(t, dir, "wlan0", nil)
+	if err := os.WriteFile(filepath.Join(dir, "README"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FindWpaCtrl(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(got) != "wlan0" {
+		t.Errorf("FindWpaCtrl = %s, want wlan0", got)
+	}
+}
+
+func TestWpaUnescape(t *testing.T) {
+	for in, want := range map[string]string{
+		"HomeNet":                  "HomeNet",
+		`Caf\xc3\xa9`:              "Café",
+		`a\\b`:                     `a\b`,
+		`say \"hi\"`:               `say "hi"`,
+		`tab\there`:                "tab\there",
+		`\x00\x00`:                 "\x00\x00",
+		`bad \xZZ`:                 `bad \xZZ`,
+		`trailing\`:                `trailing\`,
+		`\xe3\x83\x86\xe3`:         "\xe3\x83\x86\xe3",
+		`\xe6\x95\x99\xe5\xae\xa4`: "教室",
+	} {
+		if got := wpaUnescape(in); got != want {
+			t.Errorf("wpaUnescape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
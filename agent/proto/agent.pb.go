@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.5
+// 	protoc-gen-go v1.36.10
 // 	protoc        v6.30.0
 // source: agent/proto/agent.proto
 
@@ -36,25 +36,626 @@ type NetworkMetric struct {
 	DownMbps        float32                `protobuf:"fixed32,11,opt,name=down_mbps,json=downMbps,proto3" json:"down_mbps,omitempty"`
 	UpMbps          float32                `protobuf:"fixed32,12,opt,name=up_mbps,json=upMbps,proto3" json:"up_mbps,omitempty"`
 	ExperienceScore int32                  `protobuf:"varint,13,opt,name=experience_score,json=experienceScore,proto3" json:"experience_score,omitempty"` // 0-100 computed by agent
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// Link details from the Wi-Fi backend.
+	Bssid           string  `protobuf:"bytes,14,opt,name=bssid,proto3" json:"bssid,omitempty"`
+	ConnectionState string  `protobuf:"bytes,15,opt,name=connection_state,json=connectionState,proto3" json:"connection_state,omitempty"` // e.g. "connected"
+	RadioType       string  `protobuf:"bytes,16,opt,name=radio_type,json=radioType,proto3" json:"radio_type,omitempty"`                   // e.g. "802.11ax"
+	Channel         int32   `protobuf:"varint,17,opt,name=channel,proto3" json:"channel,omitempty"`
+	Band            string  `protobuf:"bytes,18,opt,name=band,proto3" json:"band,omitempty"` // "2.4 GHz", "5 GHz", "6 GHz"
+	RxRateMbps      float32 `protobuf:"fixed32,19,opt,name=rx_rate_mbps,json=rxRateMbps,proto3" json:"rx_rate_mbps,omitempty"`
+	TxRateMbps      float32 `protobuf:"fixed32,20,opt,name=tx_rate_mbps,json=txRateMbps,proto3" json:"tx_rate_mbps,omitempty"`
+	Authentication  string  `protobuf:"bytes,21,opt,name=authentication,proto3" json:"authentication,omitempty"`
+	Cipher          string  `protobuf:"bytes,22,opt,name=cipher,proto3" json:"cipher,omitempty"`
+	RssiDbm         int32   `protobuf:"varint,23,opt,name=rssi_dbm,json=rssiDbm,proto3" json:"rssi_dbm,omitempty"` // approximate when derived from signal_percent
+	// DNS probe over the configured names and servers.
+	DnsLatencyMs float32 `protobuf:"fixed32,24,opt,name=dns_latency_ms,json=dnsLatencyMs,proto3" json:"dns_latency_ms,omitempty"` // average over successful lookups
+	DnsQueries   int32   `protobuf:"varint,25,opt,name=dns_queries,json=dnsQueries,proto3" json:"dns_queries,omitempty"`
+	DnsFailures  int32   `protobuf:"varint,26,opt,name=dns_failures,json=dnsFailures,proto3" json:"dns_failures,omitempty"` // nxdomain + servfail + timeouts + other errors
+	DnsNxdomain  int32   `protobuf:"varint,27,opt,name=dns_nxdomain,json=dnsNxdomain,proto3" json:"dns_nxdomain,omitempty"`
+	DnsServfail  int32   `protobuf:"varint,28,opt,name=dns_servfail,json=dnsServfail,proto3" json:"dns_servfail,omitempty"`
+	DnsTimeouts  int32   `protobuf:"varint,29,opt,name=dns_timeouts,json=dnsTimeouts,proto3" json:"dns_timeouts,omitempty"`
+	// Application endpoints (exam portal, telehealth service, ...).
+	Endpoints []*EndpointResult `protobuf:"bytes,30,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+	// Internet reachability: "online", "captive_portal" or "offline";
+	// empty when the check did not run.
+	Connectivity string `protobuf:"bytes,31,opt,name=connectivity,proto3" json:"connectivity,omitempty"`
+	PortalUrl    string `protobuf:"bytes,32,opt,name=portal_url,json=portalUrl,proto3" json:"portal_url,omitempty"` // login page a captive portal redirected to
+	// Where a degraded link breaks: "none", "local_link", "lan", "upstream",
+	// or empty when the path could not be probed.
+	FaultLocation string  `protobuf:"bytes,33,opt,name=fault_location,json=faultLocation,proto3" json:"fault_location,omitempty"`
+	GatewayPingMs float32 `protobuf:"fixed32,34,opt,name=gateway_ping_ms,json=gatewayPingMs,proto3" json:"gateway_ping_ms,omitempty"` // 0 unless the gateway was probed and answered
+	// ITU-T G.107 E-model estimate of call quality for the codec profile.
+	RFactor float32 `protobuf:"fixed32,35,opt,name=r_factor,json=rFactor,proto3" json:"r_factor,omitempty"`
+	Mos     float32 `protobuf:"fixed32,36,opt,name=mos,proto3" json:"mos,omitempty"` // 1-4.5; 0 when the link was not probed
+	Codec   string  `protobuf:"bytes,37,opt,name=codec,proto3" json:"codec,omitempty"`
+	// Latest latency-under-load test on this link; 0/empty until one ran.
+	BufferbloatIdleMs   float32 `protobuf:"fixed32,38,opt,name=bufferbloat_idle_ms,json=bufferbloatIdleMs,proto3" json:"bufferbloat_idle_ms,omitempty"`
+	BufferbloatLoadedMs float32 `protobuf:"fixed32,39,opt,name=bufferbloat_loaded_ms,json=bufferbloatLoadedMs,proto3" json:"bufferbloat_loaded_ms,omitempty"` // worse of download and upload
+	BufferbloatDeltaMs  float32 `protobuf:"fixed32,40,opt,name=bufferbloat_delta_ms,json=bufferbloatDeltaMs,proto3" json:"bufferbloat_delta_ms,omitempty"`
+	BufferbloatGrade    string  `protobuf:"bytes,41,opt,name=bufferbloat_grade,json=bufferbloatGrade,proto3" json:"bufferbloat_grade,omitempty"` // "A+" to "F"
+	// Set on the metric of the check where this link turned degraded.
+	Degradation *DegradationEvent `protobuf:"bytes,42,opt,name=degradation,proto3" json:"degradation,omitempty"`
+	// What experience_score is made of, for the profile named.
+	ScoreProfile string         `protobuf:"bytes,43,opt,name=score_profile,json=scoreProfile,proto3" json:"score_profile,omitempty"`
+	ScoreFactors []*ScoreFactor `protobuf:"bytes,44,rep,name=score_factors,json=scoreFactors,proto3" json:"score_factors,omitempty"`
+	// Set while the trend of signal or RTT says this link will cross its
+	// threshold soon.
+	Prediction *DegradationPrediction `protobuf:"bytes,45,opt,name=prediction,proto3" json:"prediction,omitempty"`
+	// What the failover governor allows at the time of this check.
+	Governor      *FailoverGovernor `protobuf:"bytes,46,opt,name=governor,proto3" json:"governor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NetworkMetric) Reset() {
+	*x = NetworkMetric{}
+	mi := &file_agent_proto_agent_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NetworkMetric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetworkMetric) ProtoMessage() {}
+
+func (x *NetworkMetric) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_agent_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetworkMetric.ProtoReflect.Descriptor instead.
+func (*NetworkMetric) Descriptor() ([]byte, []int) {
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *NetworkMetric) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetTimestampUnix() int64 {
+	if x != nil {
+		return x.TimestampUnix
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetSsid() string {
+	if x != nil {
+		return x.Ssid
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetInterfaceName() string {
+	if x != nil {
+		return x.InterfaceName
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetSignalPercent() int32 {
+	if x != nil {
+		return x.SignalPercent
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetAvgPingMs() int32 {
+	if x != nil {
+		return x.AvgPingMs
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetJitterMs() int32 {
+	if x != nil {
+		return x.JitterMs
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetPacketLossPct() float32 {
+	if x != nil {
+		return x.PacketLossPct
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetDownMbps() float32 {
+	if x != nil {
+		return x.DownMbps
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetUpMbps() float32 {
+	if x != nil {
+		return x.UpMbps
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetExperienceScore() int32 {
+	if x != nil {
+		return x.ExperienceScore
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetBssid() string {
+	if x != nil {
+		return x.Bssid
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetConnectionState() string {
+	if x != nil {
+		return x.ConnectionState
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetRadioType() string {
+	if x != nil {
+		return x.RadioType
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetChannel() int32 {
+	if x != nil {
+		return x.Channel
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetBand() string {
+	if x != nil {
+		return x.Band
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetRxRateMbps() float32 {
+	if x != nil {
+		return x.RxRateMbps
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetTxRateMbps() float32 {
+	if x != nil {
+		return x.TxRateMbps
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetAuthentication() string {
+	if x != nil {
+		return x.Authentication
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetCipher() string {
+	if x != nil {
+		return x.Cipher
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetRssiDbm() int32 {
+	if x != nil {
+		return x.RssiDbm
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetDnsLatencyMs() float32 {
+	if x != nil {
+		return x.DnsLatencyMs
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetDnsQueries() int32 {
+	if x != nil {
+		return x.DnsQueries
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetDnsFailures() int32 {
+	if x != nil {
+		return x.DnsFailures
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetDnsNxdomain() int32 {
+	if x != nil {
+		return x.DnsNxdomain
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetDnsServfail() int32 {
+	if x != nil {
+		return x.DnsServfail
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetDnsTimeouts() int32 {
+	if x != nil {
+		return x.DnsTimeouts
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetEndpoints() []*EndpointResult {
+	if x != nil {
+		return x.Endpoints
+	}
+	return nil
+}
+
+func (x *NetworkMetric) GetConnectivity() string {
+	if x != nil {
+		return x.Connectivity
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetPortalUrl() string {
+	if x != nil {
+		return x.PortalUrl
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetFaultLocation() string {
+	if x != nil {
+		return x.FaultLocation
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetGatewayPingMs() float32 {
+	if x != nil {
+		return x.GatewayPingMs
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetRFactor() float32 {
+	if x != nil {
+		return x.RFactor
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetMos() float32 {
+	if x != nil {
+		return x.Mos
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetBufferbloatIdleMs() float32 {
+	if x != nil {
+		return x.BufferbloatIdleMs
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetBufferbloatLoadedMs() float32 {
+	if x != nil {
+		return x.BufferbloatLoadedMs
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetBufferbloatDeltaMs() float32 {
+	if x != nil {
+		return x.BufferbloatDeltaMs
+	}
+	return 0
+}
+
+func (x *NetworkMetric) GetBufferbloatGrade() string {
+	if x != nil {
+		return x.BufferbloatGrade
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetDegradation() *DegradationEvent {
+	if x != nil {
+		return x.Degradation
+	}
+	return nil
+}
+
+func (x *NetworkMetric) GetScoreProfile() string {
+	if x != nil {
+		return x.ScoreProfile
+	}
+	return ""
+}
+
+func (x *NetworkMetric) GetScoreFactors() []*ScoreFactor {
+	if x != nil {
+		return x.ScoreFactors
+	}
+	return nil
+}
+
+func (x *NetworkMetric) GetPrediction() *DegradationPrediction {
+	if x != nil {
+		return x.Prediction
+	}
+	return nil
+}
+
+func (x *NetworkMetric) GetGovernor() *FailoverGovernor {
+	if x != nil {
+		return x.Governor
+	}
+	return nil
+}
+
+// FailoverGovernor is the state of the limits on failover: the hourly
+// switch budget, the cooldown after failed failovers and the networks
+// whose circuit breaker is open.
+type FailoverGovernor struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	SwitchesLastHour    int32                  `protobuf:"varint,1,opt,name=switches_last_hour,json=switchesLastHour,proto3" json:"switches_last_hour,omitempty"` // to other networks; rollbacks are not counted
+	MaxSwitchesPerHour  int32                  `protobuf:"varint,2,opt,name=max_switches_per_hour,json=maxSwitchesPerHour,proto3" json:"max_switches_per_hour,omitempty"`
+	ConsecutiveFailures int32                  `protobuf:"varint,3,opt,name=consecutive_failures,json=consecutiveFailures,proto3" json:"consecutive_failures,omitempty"`
+	CooldownUntilUnix   int64                  `protobuf:"varint,4,opt,name=cooldown_until_unix,json=cooldownUntilUnix,proto3" json:"cooldown_until_unix,omitempty"` // 0 when not cooling down
+	StayPut             bool                   `protobuf:"varint,5,opt,name=stay_put,json=stayPut,proto3" json:"stay_put,omitempty"`
+	Reason              string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`                                 // why it stays put
+	OpenBreakers        []string               `protobuf:"bytes,7,rep,name=open_breakers,json=openBreakers,proto3" json:"open_breakers,omitempty"` // SSIDs left out of failover
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *FailoverGovernor) Reset() {
+	*x = FailoverGovernor{}
+	mi := &file_agent_proto_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FailoverGovernor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FailoverGovernor) ProtoMessage() {}
+
+func (x *FailoverGovernor) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FailoverGovernor.ProtoReflect.Descriptor instead.
+func (*FailoverGovernor) Descriptor() ([]byte, []int) {
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FailoverGovernor) GetSwitchesLastHour() int32 {
+	if x != nil {
+		return x.SwitchesLastHour
+	}
+	return 0
+}
+
+func (x *FailoverGovernor) GetMaxSwitchesPerHour() int32 {
+	if x != nil {
+		return x.MaxSwitchesPerHour
+	}
+	return 0
+}
+
+func (x *FailoverGovernor) GetConsecutiveFailures() int32 {
+	if x != nil {
+		return x.ConsecutiveFailures
+	}
+	return 0
+}
+
+func (x *FailoverGovernor) GetCooldownUntilUnix() int64 {
+	if x != nil {
+		return x.CooldownUntilUnix
+	}
+	return 0
+}
+
+func (x *FailoverGovernor) GetStayPut() bool {
+	if x != nil {
+		return x.StayPut
+	}
+	return false
+}
+
+func (x *FailoverGovernor) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *FailoverGovernor) GetOpenBreakers() []string {
+	if x != nil {
+		return x.OpenBreakers
+	}
+	return nil
+}
+
+// DegradationPrediction is a degradation_predicted event: a line fitted
+// through the recent signal or RTT of the primary link that reaches the
+// threshold within the horizon.
+type DegradationPrediction struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Metric             string                 `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`     // signal or latency
+	Current            float32                `protobuf:"fixed32,2,opt,name=current,proto3" json:"current,omitempty"` // fitted value now
+	Threshold          float32                `protobuf:"fixed32,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	SlopePerMin        float32                `protobuf:"fixed32,4,opt,name=slope_per_min,json=slopePerMin,proto3" json:"slope_per_min,omitempty"`
+	SecondsToThreshold float32                `protobuf:"fixed32,5,opt,name=seconds_to_threshold,json=secondsToThreshold,proto3" json:"seconds_to_threshold,omitempty"`
+	R2                 float32                `protobuf:"fixed32,6,opt,name=r2,proto3" json:"r2,omitempty"`
+	SinceUnix          int64                  `protobuf:"varint,7,opt,name=since_unix,json=sinceUnix,proto3" json:"since_unix,omitempty"` // when the trend was first seen
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *DegradationPrediction) Reset() {
+	*x = DegradationPrediction{}
+	mi := &file_agent_proto_agent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DegradationPrediction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DegradationPrediction) ProtoMessage() {}
+
+func (x *DegradationPrediction) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_agent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func (x *NetworkMetric) Reset() {
-	*x = NetworkMetric{}
-	mi := &file_agent_proto_agent_proto_msgTypes[0]
+// Deprecated: Use DegradationPrediction.ProtoReflect.Descriptor instead.
+func (*DegradationPrediction) Descriptor() ([]byte, []int) {
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DegradationPrediction) GetMetric() string {
+	if x != nil {
+		return x.Metric
+	}
+	return ""
+}
+
+func (x *DegradationPrediction) GetCurrent() float32 {
+	if x != nil {
+		return x.Current
+	}
+	return 0
+}
+
+func (x *DegradationPrediction) GetThreshold() float32 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *DegradationPrediction) GetSlopePerMin() float32 {
+	if x != nil {
+		return x.SlopePerMin
+	}
+	return 0
+}
+
+func (x *DegradationPrediction) GetSecondsToThreshold() float32 {
+	if x != nil {
+		return x.SecondsToThreshold
+	}
+	return 0
+}
+
+func (x *DegradationPrediction) GetR2() float32 {
+	if x != nil {
+		return x.R2
+	}
+	return 0
+}
+
+func (x *DegradationPrediction) GetSinceUnix() int64 {
+	if x != nil {
+		return x.SinceUnix
+	}
+	return 0
+}
+
+// ScoreFactor is one input's part in the experience score: its value,
+// its own 0-100 score and its share of the total.
+type ScoreFactor struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // signal, latency, jitter, loss, dns, download, upload
+	Value         float32                `protobuf:"fixed32,2,opt,name=value,proto3" json:"value,omitempty"`
+	Score         float32                `protobuf:"fixed32,3,opt,name=score,proto3" json:"score,omitempty"`
+	Weight        float32                `protobuf:"fixed32,4,opt,name=weight,proto3" json:"weight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScoreFactor) Reset() {
+	*x = ScoreFactor{}
+	mi := &file_agent_proto_agent_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *NetworkMetric) String() string {
+func (x *ScoreFactor) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*NetworkMetric) ProtoMessage() {}
+func (*ScoreFactor) ProtoMessage() {}
 
-func (x *NetworkMetric) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_agent_proto_msgTypes[0]
+func (x *ScoreFactor) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_agent_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -65,102 +666,360 @@ func (x *NetworkMetric) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use NetworkMetric.ProtoReflect.Descriptor instead.
-func (*NetworkMetric) Descriptor() ([]byte, []int) {
-	return file_agent_proto_agent_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use ScoreFactor.ProtoReflect.Descriptor instead.
+func (*ScoreFactor) Descriptor() ([]byte, []int) {
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *NetworkMetric) GetDeviceId() string {
+func (x *ScoreFactor) GetName() string {
 	if x != nil {
-		return x.DeviceId
+		return x.Name
 	}
 	return ""
 }
 
-func (x *NetworkMetric) GetUserId() string {
+func (x *ScoreFactor) GetValue() float32 {
 	if x != nil {
-		return x.UserId
+		return x.Value
+	}
+	return 0
+}
+
+func (x *ScoreFactor) GetScore() float32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *ScoreFactor) GetWeight() float32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+// DegradationEvent is the primary link turning degraded, with the path to
+// the first probe target traced at that moment.
+type DegradationEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StartedUnix   int64                  `protobuf:"varint,1,opt,name=started_unix,json=startedUnix,proto3" json:"started_unix,omitempty"`
+	Reasons       []string               `protobuf:"bytes,2,rep,name=reasons,proto3" json:"reasons,omitempty"` // weak_signal, high_ping, packet_loss, low_mos, dns, endpoint, captive_portal
+	FaultLocation string                 `protobuf:"bytes,3,opt,name=fault_location,json=faultLocation,proto3" json:"fault_location,omitempty"`
+	TraceTarget   string                 `protobuf:"bytes,4,opt,name=trace_target,json=traceTarget,proto3" json:"trace_target,omitempty"` // empty when no trace could run
+	TraceMethod   string                 `protobuf:"bytes,5,opt,name=trace_method,json=traceMethod,proto3" json:"trace_method,omitempty"`
+	TraceReached  bool                   `protobuf:"varint,6,opt,name=trace_reached,json=traceReached,proto3" json:"trace_reached,omitempty"`
+	Hops          []*TraceHop            `protobuf:"bytes,7,rep,name=hops,proto3" json:"hops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DegradationEvent) Reset() {
+	*x = DegradationEvent{}
+	mi := &file_agent_proto_agent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DegradationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DegradationEvent) ProtoMessage() {}
+
+func (x *DegradationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DegradationEvent.ProtoReflect.Descriptor instead.
+func (*DegradationEvent) Descriptor() ([]byte, []int) {
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DegradationEvent) GetStartedUnix() int64 {
+	if x != nil {
+		return x.StartedUnix
+	}
+	return 0
+}
+
+func (x *DegradationEvent) GetReasons() []string {
+	if x != nil {
+		return x.Reasons
+	}
+	return nil
+}
+
+func (x *DegradationEvent) GetFaultLocation() string {
+	if x != nil {
+		return x.FaultLocation
 	}
 	return ""
 }
 
-func (x *NetworkMetric) GetDomain() string {
+func (x *DegradationEvent) GetTraceTarget() string {
 	if x != nil {
-		return x.Domain
+		return x.TraceTarget
 	}
 	return ""
 }
 
-func (x *NetworkMetric) GetTimestampUnix() int64 {
+func (x *DegradationEvent) GetTraceMethod() string {
 	if x != nil {
-		return x.TimestampUnix
+		return x.TraceMethod
+	}
+	return ""
+}
+
+func (x *DegradationEvent) GetTraceReached() bool {
+	if x != nil {
+		return x.TraceReached
+	}
+	return false
+}
+
+func (x *DegradationEvent) GetHops() []*TraceHop {
+	if x != nil {
+		return x.Hops
+	}
+	return nil
+}
+
+// TraceHop is one TTL of a traceroute; addr is empty when nothing answered.
+type TraceHop struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ttl           int32                  `protobuf:"varint,1,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Addr          string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	Sent          int32                  `protobuf:"varint,3,opt,name=sent,proto3" json:"sent,omitempty"`
+	Received      int32                  `protobuf:"varint,4,opt,name=received,proto3" json:"received,omitempty"`
+	LossPct       float32                `protobuf:"fixed32,5,opt,name=loss_pct,json=lossPct,proto3" json:"loss_pct,omitempty"`
+	AvgMs         float32                `protobuf:"fixed32,6,opt,name=avg_ms,json=avgMs,proto3" json:"avg_ms,omitempty"`
+	BestMs        float32                `protobuf:"fixed32,7,opt,name=best_ms,json=bestMs,proto3" json:"best_ms,omitempty"`
+	WorstMs       float32                `protobuf:"fixed32,8,opt,name=worst_ms,json=worstMs,proto3" json:"worst_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TraceHop) Reset() {
+	*x = TraceHop{}
+	mi := &file_agent_proto_agent_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TraceHop) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TraceHop) ProtoMessage() {}
+
+func (x *TraceHop) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_agent_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TraceHop.ProtoReflect.Descriptor instead.
+func (*TraceHop) Descriptor() ([]byte, []int) {
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TraceHop) GetTtl() int32 {
+	if x != nil {
+		return x.Ttl
 	}
 	return 0
 }
 
-func (x *NetworkMetric) GetSsid() string {
+func (x *TraceHop) GetAddr() string {
 	if x != nil {
-		return x.Ssid
+		return x.Addr
 	}
 	return ""
 }
 
-func (x *NetworkMetric) GetInterfaceName() string {
+func (x *TraceHop) GetSent() int32 {
 	if x != nil {
-		return x.InterfaceName
+		return x.Sent
 	}
-	return ""
+	return 0
 }
 
-func (x *NetworkMetric) GetSignalPercent() int32 {
+func (x *TraceHop) GetReceived() int32 {
 	if x != nil {
-		return x.SignalPercent
+		return x.Received
 	}
 	return 0
 }
 
-func (x *NetworkMetric) GetAvgPingMs() int32 {
+func (x *TraceHop) GetLossPct() float32 {
 	if x != nil {
-		return x.AvgPingMs
+		return x.LossPct
 	}
 	return 0
 }
 
-func (x *NetworkMetric) GetJitterMs() int32 {
+func (x *TraceHop) GetAvgMs() float32 {
 	if x != nil {
-		return x.JitterMs
+		return x.AvgMs
 	}
 	return 0
 }
 
-func (x *NetworkMetric) GetPacketLossPct() float32 {
+func (x *TraceHop) GetBestMs() float32 {
 	if x != nil {
-		return x.PacketLossPct
+		return x.BestMs
 	}
 	return 0
 }
 
-func (x *NetworkMetric) GetDownMbps() float32 {
+func (x *TraceHop) GetWorstMs() float32 {
 	if x != nil {
-		return x.DownMbps
+		return x.WorstMs
 	}
 	return 0
 }
 
-func (x *NetworkMetric) GetUpMbps() float32 {
+// EndpointResult is one HTTP(S) request to an application endpoint,
+// broken down into phases; phases that did not happen are 0.
+type EndpointResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Status        int32                  `protobuf:"varint,3,opt,name=status,proto3" json:"status,omitempty"`
+	DnsMs         float32                `protobuf:"fixed32,4,opt,name=dns_ms,json=dnsMs,proto3" json:"dns_ms,omitempty"`
+	ConnectMs     float32                `protobuf:"fixed32,5,opt,name=connect_ms,json=connectMs,proto3" json:"connect_ms,omitempty"`
+	TlsMs         float32                `protobuf:"fixed32,6,opt,name=tls_ms,json=tlsMs,proto3" json:"tls_ms,omitempty"`
+	TtfbMs        float32                `protobuf:"fixed32,7,opt,name=ttfb_ms,json=ttfbMs,proto3" json:"ttfb_ms,omitempty"`
+	TotalMs       float32                `protobuf:"fixed32,8,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`
+	Ok            bool                   `protobuf:"varint,9,opt,name=ok,proto3" json:"ok,omitempty"`
+	Critical      bool                   `protobuf:"varint,10,opt,name=critical,proto3" json:"critical,omitempty"`
+	Error         string                 `protobuf:"bytes,11,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EndpointResult) Reset() {
+	*x = EndpointResult{}
+	mi := &file_agent_proto_agent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EndpointResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndpointResult) ProtoMessage() {}
+
+func (x *EndpointResult) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_agent_proto_msgTypes[6]
 	if x != nil {
-		return x.UpMbps
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndpointResult.ProtoReflect.Descriptor instead.
+func (*EndpointResult) Descriptor() ([]byte, []int) {
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EndpointResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *EndpointResult) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *EndpointResult) GetStatus() int32 {
+	if x != nil {
+		return x.Status
 	}
 	return 0
 }
 
-func (x *NetworkMetric) GetExperienceScore() int32 {
+func (x *EndpointResult) GetDnsMs() float32 {
 	if x != nil {
-		return x.ExperienceScore
+		return x.DnsMs
+	}
+	return 0
+}
+
+func (x *EndpointResult) GetConnectMs() float32 {
+	if x != nil {
+		return x.ConnectMs
+	}
+	return 0
+}
+
+func (x *EndpointResult) GetTlsMs() float32 {
+	if x != nil {
+		return x.TlsMs
 	}
 	return 0
 }
 
+func (x *EndpointResult) GetTtfbMs() float32 {
+	if x != nil {
+		return x.TtfbMs
+	}
+	return 0
+}
+
+func (x *EndpointResult) GetTotalMs() float32 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+func (x *EndpointResult) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *EndpointResult) GetCritical() bool {
+	if x != nil {
+		return x.Critical
+	}
+	return false
+}
+
+func (x *EndpointResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 type AgentHello struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	DeviceId      string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
@@ -173,7 +1032,7 @@ type AgentHello struct {
 
 func (x *AgentHello) Reset() {
 	*x = AgentHello{}
-	mi := &file_agent_proto_agent_proto_msgTypes[1]
+	mi := &file_agent_proto_agent_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -185,7 +1044,7 @@ func (x *AgentHello) String() string {
 func (*AgentHello) ProtoMessage() {}
 
 func (x *AgentHello) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_agent_proto_msgTypes[1]
+	mi := &file_agent_proto_agent_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -198,7 +1057,7 @@ func (x *AgentHello) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AgentHello.ProtoReflect.Descriptor instead.
 func (*AgentHello) Descriptor() ([]byte, []int) {
-	return file_agent_proto_agent_proto_rawDescGZIP(), []int{1}
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *AgentHello) GetDeviceId() string {
@@ -241,7 +1100,7 @@ type ServerConfig struct {
 
 func (x *ServerConfig) Reset() {
 	*x = ServerConfig{}
-	mi := &file_agent_proto_agent_proto_msgTypes[2]
+	mi := &file_agent_proto_agent_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -253,7 +1112,7 @@ func (x *ServerConfig) String() string {
 func (*ServerConfig) ProtoMessage() {}
 
 func (x *ServerConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_agent_proto_msgTypes[2]
+	mi := &file_agent_proto_agent_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -266,7 +1125,7 @@ func (x *ServerConfig) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ServerConfig.ProtoReflect.Descriptor instead.
 func (*ServerConfig) Descriptor() ([]byte, []int) {
-	return file_agent_proto_agent_proto_rawDescGZIP(), []int{2}
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ServerConfig) GetMinScoreForOk() int32 {
@@ -307,7 +1166,7 @@ type ControlMessage struct {
 
 func (x *ControlMessage) Reset() {
 	*x = ControlMessage{}
-	mi := &file_agent_proto_agent_proto_msgTypes[3]
+	mi := &file_agent_proto_agent_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -319,7 +1178,7 @@ func (x *ControlMessage) String() string {
 func (*ControlMessage) ProtoMessage() {}
 
 func (x *ControlMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_agent_proto_agent_proto_msgTypes[3]
+	mi := &file_agent_proto_agent_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -332,7 +1191,7 @@ func (x *ControlMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ControlMessage.ProtoReflect.Descriptor instead.
 func (*ControlMessage) Descriptor() ([]byte, []int) {
-	return file_agent_proto_agent_proto_rawDescGZIP(), []int{3}
+	return file_agent_proto_agent_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ControlMessage) GetType() string {
@@ -351,72 +1210,135 @@ func (x *ControlMessage) GetData() string {
 
 var File_agent_proto_agent_proto protoreflect.FileDescriptor
 
-var file_agent_proto_agent_proto_rawDesc = string([]byte{
-	0x0a, 0x17, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x61, 0x67,
-	0x65, 0x6e, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0f, 0x6e, 0x65, 0x74, 0x73, 0x68,
-	0x69, 0x65, 0x6c, 0x64, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x22, 0xac, 0x03, 0x0a, 0x0d, 0x4e,
-	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x12, 0x1b, 0x0a, 0x09,
-	0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x08, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65,
-	0x72, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72,
-	0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x18, 0x03, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x12, 0x25, 0x0a, 0x0e, 0x74, 0x69,
-	0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x5f, 0x75, 0x6e, 0x69, 0x78, 0x18, 0x04, 0x20, 0x01,
-	0x28, 0x03, 0x52, 0x0d, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x55, 0x6e, 0x69,
-	0x78, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x73, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x04, 0x73, 0x73, 0x69, 0x64, 0x12, 0x25, 0x0a, 0x0e, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x66, 0x61,
-	0x63, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x69,
-	0x6e, 0x74, 0x65, 0x72, 0x66, 0x61, 0x63, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x25, 0x0a, 0x0e,
-	0x73, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x5f, 0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x18, 0x07,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x50, 0x65, 0x72, 0x63,
-	0x65, 0x6e, 0x74, 0x12, 0x1e, 0x0a, 0x0b, 0x61, 0x76, 0x67, 0x5f, 0x70, 0x69, 0x6e, 0x67, 0x5f,
-	0x6d, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x61, 0x76, 0x67, 0x50, 0x69, 0x6e,
-	0x67, 0x4d, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x5f, 0x6d, 0x73,
-	0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x6a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x4d, 0x73,
-	0x12, 0x26, 0x0a, 0x0f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x5f, 0x6c, 0x6f, 0x73, 0x73, 0x5f,
-	0x70, 0x63, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0d, 0x70, 0x61, 0x63, 0x6b, 0x65,
-	0x74, 0x4c, 0x6f, 0x73, 0x73, 0x50, 0x63, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x6f, 0x77, 0x6e,
-	0x5f, 0x6d, 0x62, 0x70, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x64, 0x6f, 0x77,
-	0x6e, 0x4d, 0x62, 0x70, 0x73, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x70, 0x5f, 0x6d, 0x62, 0x70, 0x73,
-	0x18, 0x0c, 0x20, 0x01, 0x28, 0x02, 0x52, 0x06, 0x75, 0x70, 0x4d, 0x62, 0x70, 0x73, 0x12, 0x29,
-	0x0a, 0x10, 0x65, 0x78, 0x70, 0x65, 0x72, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x5f, 0x73, 0x63, 0x6f,
-	0x72, 0x65, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x65, 0x78, 0x70, 0x65, 0x72, 0x69,
-	0x65, 0x6e, 0x63, 0x65, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x22, 0x74, 0x0a, 0x0a, 0x41, 0x67, 0x65,
-	0x6e, 0x74, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x65, 0x76, 0x69, 0x63,
-	0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x65, 0x76, 0x69,
-	0x63, 0x65, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x75, 0x73, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x75, 0x73, 0x65, 0x72, 0x49, 0x64, 0x12, 0x16, 0x0a,
-	0x06, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x64,
-	0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22,
-	0x9a, 0x01, 0x0a, 0x0c, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
-	0x12, 0x27, 0x0a, 0x10, 0x6d, 0x69, 0x6e, 0x5f, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x5f, 0x66, 0x6f,
-	0x72, 0x5f, 0x6f, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x6d, 0x69, 0x6e, 0x53,
-	0x63, 0x6f, 0x72, 0x65, 0x46, 0x6f, 0x72, 0x4f, 0x6b, 0x12, 0x1d, 0x0a, 0x0a, 0x6d, 0x69, 0x6e,
-	0x5f, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x6d,
-	0x69, 0x6e, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x12, 0x1e, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x5f,
-	0x70, 0x69, 0x6e, 0x67, 0x5f, 0x6d, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x6d,
-	0x61, 0x78, 0x50, 0x69, 0x6e, 0x67, 0x4d, 0x73, 0x12, 0x22, 0x0a, 0x0d, 0x6d, 0x61, 0x78, 0x5f,
-	0x6a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x5f, 0x6d, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x0b, 0x6d, 0x61, 0x78, 0x4a, 0x69, 0x74, 0x74, 0x65, 0x72, 0x4d, 0x73, 0x22, 0x38, 0x0a, 0x0e,
-	0x43, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x12,
-	0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79,
-	0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x32, 0xad, 0x01, 0x0a, 0x0c, 0x41, 0x67, 0x65, 0x6e, 0x74,
-	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x54, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x1e, 0x2e, 0x6e, 0x65, 0x74, 0x73, 0x68,
-	0x69, 0x65, 0x6c, 0x64, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x4e, 0x65, 0x74, 0x77, 0x6f,
-	0x72, 0x6b, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x1a, 0x1f, 0x2e, 0x6e, 0x65, 0x74, 0x73, 0x68,
-	0x69, 0x65, 0x6c, 0x64, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72,
-	0x6f, 0x6c, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x28, 0x01, 0x30, 0x01, 0x12, 0x47, 0x0a,
-	0x09, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x1b, 0x2e, 0x6e, 0x65, 0x74,
-	0x73, 0x68, 0x69, 0x65, 0x6c, 0x64, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x41, 0x67, 0x65,
-	0x6e, 0x74, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x1a, 0x1d, 0x2e, 0x6e, 0x65, 0x74, 0x73, 0x68, 0x69,
-	0x65, 0x6c, 0x64, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72,
-	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x42, 0x1f, 0x5a, 0x1d, 0x6e, 0x65, 0x74, 0x73, 0x68, 0x69,
-	0x65, 0x6c, 0x64, 0x2f, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b,
-	0x61, 0x67, 0x65, 0x6e, 0x74, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
-})
+const file_agent_proto_agent_proto_rawDesc = "" +
+	"\n" +
+	"\x17agent/proto/agent.proto\x12\x0fnetshield.agent\"\xb7\r\n" +
+	"\rNetworkMetric\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06domain\x18\x03 \x01(\tR\x06domain\x12%\n" +
+	"\x0etimestamp_unix\x18\x04 \x01(\x03R\rtimestampUnix\x12\x12\n" +
+	"\x04ssid\x18\x05 \x01(\tR\x04ssid\x12%\n" +
+	"\x0einterface_name\x18\x06 \x01(\tR\rinterfaceName\x12%\n" +
+	"\x0esignal_percent\x18\a \x01(\x05R\rsignalPercent\x12\x1e\n" +
+	"\vavg_ping_ms\x18\b \x01(\x05R\tavgPingMs\x12\x1b\n" +
+	"\tjitter_ms\x18\t \x01(\x05R\bjitterMs\x12&\n" +
+	"\x0fpacket_loss_pct\x18\n" +
+	" \x01(\x02R\rpacketLossPct\x12\x1b\n" +
+	"\tdown_mbps\x18\v \x01(\x02R\bdownMbps\x12\x17\n" +
+	"\aup_mbps\x18\f \x01(\x02R\x06upMbps\x12)\n" +
+	"\x10experience_score\x18\r \x01(\x05R\x0fexperienceScore\x12\x14\n" +
+	"\x05bssid\x18\x0e \x01(\tR\x05bssid\x12)\n" +
+	"\x10connection_state\x18\x0f \x01(\tR\x0fconnectionState\x12\x1d\n" +
+	"\n" +
+	"radio_type\x18\x10 \x01(\tR\tradioType\x12\x18\n" +
+	"\achannel\x18\x11 \x01(\x05R\achannel\x12\x12\n" +
+	"\x04band\x18\x12 \x01(\tR\x04band\x12 \n" +
+	"\frx_rate_mbps\x18\x13 \x01(\x02R\n" +
+	"rxRateMbps\x12 \n" +
+	"\ftx_rate_mbps\x18\x14 \x01(\x02R\n" +
+	"txRateMbps\x12&\n" +
+	"\x0eauthentication\x18\x15 \x01(\tR\x0eauthentication\x12\x16\n" +
+	"\x06cipher\x18\x16 \x01(\tR\x06cipher\x12\x19\n" +
+	"\brssi_dbm\x18\x17 \x01(\x05R\arssiDbm\x12$\n" +
+	"\x0edns_latency_ms\x18\x18 \x01(\x02R\fdnsLatencyMs\x12\x1f\n" +
+	"\vdns_queries\x18\x19 \x01(\x05R\n" +
+	"dnsQueries\x12!\n" +
+	"\fdns_failures\x18\x1a \x01(\x05R\vdnsFailures\x12!\n" +
+	"\fdns_nxdomain\x18\x1b \x01(\x05R\vdnsNxdomain\x12!\n" +
+	"\fdns_servfail\x18\x1c \x01(\x05R\vdnsServfail\x12!\n" +
+	"\fdns_timeouts\x18\x1d \x01(\x05R\vdnsTimeouts\x12=\n" +
+	"\tendpoints\x18\x1e \x03(\v2\x1f.netshield.agent.EndpointResultR\tendpoints\x12\"\n" +
+	"\fconnectivity\x18\x1f \x01(\tR\fconnectivity\x12\x1d\n" +
+	"\n" +
+	"portal_url\x18  \x01(\tR\tportalUrl\x12%\n" +
+	"\x0efault_location\x18! \x01(\tR\rfaultLocation\x12&\n" +
+	"\x0fgateway_ping_ms\x18\" \x01(\x02R\rgatewayPingMs\x12\x19\n" +
+	"\br_factor\x18# \x01(\x02R\arFactor\x12\x10\n" +
+	"\x03mos\x18$ \x01(\x02R\x03mos\x12\x14\n" +
+	"\x05codec\x18% \x01(\tR\x05codec\x12.\n" +
+	"\x13bufferbloat_idle_ms\x18& \x01(\x02R\x11bufferbloatIdleMs\x122\n" +
+	"\x15bufferbloat_loaded_ms\x18' \x01(\x02R\x13bufferbloatLoadedMs\x120\n" +
+	"\x14bufferbloat_delta_ms\x18( \x01(\x02R\x12bufferbloatDeltaMs\x12+\n" +
+	"\x11bufferbloat_grade\x18) \x01(\tR\x10bufferbloatGrade\x12C\n" +
+	"\vdegradation\x18* \x01(\v2!.netshield.agent.DegradationEventR\vdegradation\x12#\n" +
+	"\rscore_profile\x18+ \x01(\tR\fscoreProfile\x12A\n" +
+	"\rscore_factors\x18, \x03(\v2\x1c.netshield.agent.ScoreFactorR\fscoreFactors\x12F\n" +
+	"\n" +
+	"prediction\x18- \x01(\v2&.netshield.agent.DegradationPredictionR\n" +
+	"prediction\x12=\n" +
+	"\bgovernor\x18. \x01(\v2!.netshield.agent.FailoverGovernorR\bgovernor\"\xae\x02\n" +
+	"\x10FailoverGovernor\x12,\n" +
+	"\x12switches_last_hour\x18\x01 \x01(\x05R\x10switchesLastHour\x121\n" +
+	"\x15max_switches_per_hour\x18\x02 \x01(\x05R\x12maxSwitchesPerHour\x121\n" +
+	"\x14consecutive_failures\x18\x03 \x01(\x05R\x13consecutiveFailures\x12.\n" +
+	"\x13cooldown_until_unix\x18\x04 \x01(\x03R\x11cooldownUntilUnix\x12\x19\n" +
+	"\bstay_put\x18\x05 \x01(\bR\astayPut\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12#\n" +
+	"\ropen_breakers\x18\a \x03(\tR\fopenBreakers\"\xec\x01\n" +
+	"\x15DegradationPrediction\x12\x16\n" +
+	"\x06metric\x18\x01 \x01(\tR\x06metric\x12\x18\n" +
+	"\acurrent\x18\x02 \x01(\x02R\acurrent\x12\x1c\n" +
+	"\tthreshold\x18\x03 \x01(\x02R\tthreshold\x12\"\n" +
+	"\rslope_per_min\x18\x04 \x01(\x02R\vslopePerMin\x120\n" +
+	"\x14seconds_to_threshold\x18\x05 \x01(\x02R\x12secondsToThreshold\x12\x0e\n" +
+	"\x02r2\x18\x06 \x01(\x02R\x02r2\x12\x1d\n" +
+	"\n" +
+	"since_unix\x18\a \x01(\x03R\tsinceUnix\"e\n" +
+	"\vScoreFactor\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x02R\x05value\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x02R\x05score\x12\x16\n" +
+	"\x06weight\x18\x04 \x01(\x02R\x06weight\"\x90\x02\n" +
+	"\x10DegradationEvent\x12!\n" +
+	"\fstarted_unix\x18\x01 \x01(\x03R\vstartedUnix\x12\x18\n" +
+	"\areasons\x18\x02 \x03(\tR\areasons\x12%\n" +
+	"\x0efault_location\x18\x03 \x01(\tR\rfaultLocation\x12!\n" +
+	"\ftrace_target\x18\x04 \x01(\tR\vtraceTarget\x12!\n" +
+	"\ftrace_method\x18\x05 \x01(\tR\vtraceMethod\x12#\n" +
+	"\rtrace_reached\x18\x06 \x01(\bR\ftraceReached\x12-\n" +
+	"\x04hops\x18\a \x03(\v2\x19.netshield.agent.TraceHopR\x04hops\"\xc6\x01\n" +
+	"\bTraceHop\x12\x10\n" +
+	"\x03ttl\x18\x01 \x01(\x05R\x03ttl\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x12\x12\n" +
+	"\x04sent\x18\x03 \x01(\x05R\x04sent\x12\x1a\n" +
+	"\breceived\x18\x04 \x01(\x05R\breceived\x12\x19\n" +
+	"\bloss_pct\x18\x05 \x01(\x02R\alossPct\x12\x15\n" +
+	"\x06avg_ms\x18\x06 \x01(\x02R\x05avgMs\x12\x17\n" +
+	"\abest_ms\x18\a \x01(\x02R\x06bestMs\x12\x19\n" +
+	"\bworst_ms\x18\b \x01(\x02R\aworstMs\"\x91\x02\n" +
+	"\x0eEndpointResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\x05R\x06status\x12\x15\n" +
+	"\x06dns_ms\x18\x04 \x01(\x02R\x05dnsMs\x12\x1d\n" +
+	"\n" +
+	"connect_ms\x18\x05 \x01(\x02R\tconnectMs\x12\x15\n" +
+	"\x06tls_ms\x18\x06 \x01(\x02R\x05tlsMs\x12\x17\n" +
+	"\attfb_ms\x18\a \x01(\x02R\x06ttfbMs\x12\x19\n" +
+	"\btotal_ms\x18\b \x01(\x02R\atotalMs\x12\x0e\n" +
+	"\x02ok\x18\t \x01(\bR\x02ok\x12\x1a\n" +
+	"\bcritical\x18\n" +
+	" \x01(\bR\bcritical\x12\x14\n" +
+	"\x05error\x18\v \x01(\tR\x05error\"t\n" +
+	"\n" +
+	"AgentHello\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06domain\x18\x03 \x01(\tR\x06domain\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\tR\aversion\"\x9a\x01\n" +
+	"\fServerConfig\x12'\n" +
+	"\x10min_score_for_ok\x18\x01 \x01(\x05R\rminScoreForOk\x12\x1d\n" +
+	"\n" +
+	"min_signal\x18\x02 \x01(\x05R\tminSignal\x12\x1e\n" +
+	"\vmax_ping_ms\x18\x03 \x01(\x05R\tmaxPingMs\x12\"\n" +
+	"\rmax_jitter_ms\x18\x04 \x01(\x05R\vmaxJitterMs\"8\n" +
+	"\x0eControlMessage\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\tR\x04data2\xad\x01\n" +
+	"\fAgentService\x12T\n" +
+	"\rStreamMetrics\x12\x1e.netshield.agent.NetworkMetric\x1a\x1f.netshield.agent.ControlMessage(\x010\x01\x12G\n" +
+	"\tGetConfig\x12\x1b.netshield.agent.AgentHello\x1a\x1d.netshield.agent.ServerConfigB\x1fZ\x1dnetshield/agent/proto;agentpbb\x06proto3"
 
 var (
 	file_agent_proto_agent_proto_rawDescOnce sync.Once
@@ -430,23 +1352,35 @@ func file_agent_proto_agent_proto_rawDescGZIP() []byte {
 	return file_agent_proto_agent_proto_rawDescData
 }
 
-var file_agent_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_agent_proto_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_agent_proto_agent_proto_goTypes = []any{
-	(*NetworkMetric)(nil),  // 0: netshield.agent.NetworkMetric
-	(*AgentHello)(nil),     // 1: netshield.agent.AgentHello
-	(*ServerConfig)(nil),   // 2: netshield.agent.ServerConfig
-	(*ControlMessage)(nil), // 3: netshield.agent.ControlMessage
+	(*NetworkMetric)(nil),         // 0: netshield.agent.NetworkMetric
+	(*FailoverGovernor)(nil),      // 1: netshield.agent.FailoverGovernor
+	(*DegradationPrediction)(nil), // 2: netshield.agent.DegradationPrediction
+	(*ScoreFactor)(nil),           // 3: netshield.agent.ScoreFactor
+	(*DegradationEvent)(nil),      // 4: netshield.agent.DegradationEvent
+	(*TraceHop)(nil),              // 5: netshield.agent.TraceHop
+	(*EndpointResult)(nil),        // 6: netshield.agent.EndpointResult
+	(*AgentHello)(nil),            // 7: netshield.agent.AgentHello
+	(*ServerConfig)(nil),          // 8: netshield.agent.ServerConfig
+	(*ControlMessage)(nil),        // 9: netshield.agent.ControlMessage
 }
 var file_agent_proto_agent_proto_depIdxs = []int32{
-	0, // 0: netshield.agent.AgentService.StreamMetrics:input_type -> netshield.agent.NetworkMetric
-	1, // 1: netshield.agent.AgentService.GetConfig:input_type -> netshield.agent.AgentHello
-	3, // 2: netshield.agent.AgentService.StreamMetrics:output_type -> netshield.agent.ControlMessage
-	2, // 3: netshield.agent.AgentService.GetConfig:output_type -> netshield.agent.ServerConfig
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	6, // 0: netshield.agent.NetworkMetric.endpoints:type_name -> netshield.agent.EndpointResult
+	4, // 1: netshield.agent.NetworkMetric.degradation:type_name -> netshield.agent.DegradationEvent
+	3, // 2: netshield.agent.NetworkMetric.score_factors:type_name -> netshield.agent.ScoreFactor
+	2, // 3: netshield.agent.NetworkMetric.prediction:type_name -> netshield.agent.DegradationPrediction
+	1, // 4: netshield.agent.NetworkMetric.governor:type_name -> netshield.agent.FailoverGovernor
+	5, // 5: netshield.agent.DegradationEvent.hops:type_name -> netshield.agent.TraceHop
+	0, // 6: netshield.agent.AgentService.StreamMetrics:input_type -> netshield.agent.NetworkMetric
+	7, // 7: netshield.agent.AgentService.GetConfig:input_type -> netshield.agent.AgentHello
+	9, // 8: netshield.agent.AgentService.StreamMetrics:output_type -> netshield.agent.ControlMessage
+	8, // 9: netshield.agent.AgentService.GetConfig:output_type -> netshield.agent.ServerConfig
+	8, // [8:10] is the sub-list for method output_type
+	6, // [6:8] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_agent_proto_agent_proto_init() }
@@ -460,7 +1394,7 @@ func file_agent_proto_agent_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_agent_proto_agent_proto_rawDesc), len(file_agent_proto_agent_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
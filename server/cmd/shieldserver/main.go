@@ -12,6 +12,7 @@ import (
 	agentpb "netshield/agent/proto"
 	"netshield/server/internal/db"
 	grpcserver "netshield/server/internal/grpc"
+	"netshield/server/internal/speedtest"
 
 	"google.golang.org/grpc"
 )
@@ -96,6 +97,10 @@ func startHTTPServer(store *db.Store) {
 		w.Write([]byte("ok"))
 	})
 
+	// Throughput test payloads for agents
+	http.HandleFunc("/speedtest/download", speedtest.Download)
+	http.HandleFunc("/speedtest/upload", speedtest.Upload)
+
 	http.HandleFunc("/api/admin/links/devices", func(w http.ResponseWriter, r *http.Request) {
 		// CORS (same style as your /current)
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
@@ -136,6 +141,49 @@ func startHTTPServer(store *db.Store) {
 		})
 	})
 
+	// GET /api/admin/events?device_id=... -> latest degradation events with traceroutes
+	http.HandleFunc("/api/admin/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		deviceID := r.URL.Query().Get("device_id")
+		if deviceID == "" {
+			http.Error(w, "device_id is required", http.StatusBadRequest)
+			return
+		}
+		if store == nil {
+			http.Error(w, "no database (demo mode)", http.StatusServiceUnavailable)
+			return
+		}
+
+		events, err := store.GetDegradationEvents(r.Context(), deviceID, 50)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if events == nil {
+			events = []db.DegradationEventRow{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_id": deviceID,
+			"count":     len(events),
+			"events":    events,
+		})
+	})
+
 	addr := httpPort
 	log.Println("[server] HTTP status endpoint on", addr, "GET /status")
 	if err := http.ListenAndServe(addr, nil); err != nil {
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	agentpb "netshield/agent/proto"
@@ -35,17 +36,50 @@ func (s *Store) SaveMetric(ctx context.Context, m *agentpb.NetworkMetric) error
 
 	ts := time.Unix(m.TimestampUnix, 0)
 
+	// The score breakdown is kept as JSON; it is read whole, not queried.
+	factors := make([]ScoreFactorRow, 0, len(m.ScoreFactors))
+	for _, f := range m.ScoreFactors {
+		factors = append(factors, ScoreFactorRow{Name: f.Name, Value: f.Value, Score: f.Score, Weight: f.Weight})
+	}
+	pred := m.GetPrediction() // nil unless a degradation is predicted
+	gov := m.GetGovernor()
+	breakers := gov.GetOpenBreakers()
+	if breakers == nil {
+		breakers = []string{} // stored as [] rather than null
+	}
+
 	// Insert raw metric
 	_, err = tx.Exec(ctx, `
 		INSERT INTO metrics_raw (
 			device_id, user_id, domain, ts,
 			ssid, interface_name,
-			signal_percent, avg_ping_ms, experience_score
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+			signal_percent, avg_ping_ms, experience_score,
+			bssid, connection_state, radio_type, channel, band,
+			rx_rate_mbps, tx_rate_mbps, authentication, cipher, rssi_dbm,
+			jitter_ms, packet_loss_pct, down_mbps, up_mbps,
+			dns_latency_ms, dns_queries, dns_failures, dns_nxdomain, dns_servfail, dns_timeouts,
+			connectivity, portal_url, fault_location, gateway_ping_ms,
+			r_factor, mos, codec,
+			bufferbloat_idle_ms, bufferbloat_loaded_ms, bufferbloat_delta_ms, bufferbloat_grade,
+			score_profile, score_factors,
+			predicted_metric, predicted_in_s, predicted_slope,
+			governor_stay_put, governor_reason, governor_switches, governor_breakers
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,
+			$24,$25,$26,$27,$28,$29,$30,$31,$32,$33,$34,$35,$36,$37,$38,$39,$40,$41,$42,$43,$44,$45,$46,$47,$48,$49)
 	`,
 		m.DeviceId, m.UserId, m.Domain, ts,
 		m.Ssid, m.InterfaceName,
 		m.SignalPercent, m.AvgPingMs, m.ExperienceScore,
+		m.Bssid, m.ConnectionState, m.RadioType, m.Channel, m.Band,
+		m.RxRateMbps, m.TxRateMbps, m.Authentication, m.Cipher, m.RssiDbm,
+		m.JitterMs, m.PacketLossPct, m.DownMbps, m.UpMbps,
+		m.DnsLatencyMs, m.DnsQueries, m.DnsFailures, m.DnsNxdomain, m.DnsServfail, m.DnsTimeouts,
+		m.Connectivity, m.PortalUrl, m.FaultLocation, m.GatewayPingMs,
+		m.RFactor, m.Mos, m.Codec,
+		m.BufferbloatIdleMs, m.BufferbloatLoadedMs, m.BufferbloatDeltaMs, m.BufferbloatGrade,
+		m.ScoreProfile, factors,
+		pred.GetMetric(), pred.GetSecondsToThreshold(), pred.GetSlopePerMin(),
+		gov.GetStayPut(), gov.GetReason(), gov.GetSwitchesLastHour(), breakers,
 	)
 	if err != nil {
 		return err
@@ -55,48 +89,215 @@ func (s *Store) SaveMetric(ctx context.Context, m *agentpb.NetworkMetric) error
 	_, err = tx.Exec(ctx, `
 		INSERT INTO device_status (
 			device_id, user_id, domain, last_seen,
-			ssid, interface_name, signal_percent, avg_ping_ms, experience_score
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
-		ON CONFLICT (device_id) DO UPDATE
+			ssid, interface_name, signal_percent, avg_ping_ms, experience_score,
+			bssid, connection_state, radio_type, channel, band,
+			rx_rate_mbps, tx_rate_mbps, authentication, cipher, rssi_dbm,
+			jitter_ms, packet_loss_pct, down_mbps, up_mbps,
+			dns_latency_ms, dns_queries, dns_failures, dns_nxdomain, dns_servfail, dns_timeouts,
+			connectivity, portal_url, fault_location, gateway_ping_ms,
+			r_factor, mos, codec,
+			bufferbloat_idle_ms, bufferbloat_loaded_ms, bufferbloat_delta_ms, bufferbloat_grade,
+			score_profile, score_factors,
+			predicted_metric, predicted_in_s, predicted_slope,
+			governor_stay_put, governor_reason, governor_switches, governor_breakers
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,
+			$24,$25,$26,$27,$28,$29,$30,$31,$32,$33,$34,$35,$36,$37,$38,$39,$40,$41,$42,$43,$44,$45,$46,$47,$48,$49)
+		ON CONFLICT (device_id, interface_name) DO UPDATE
 		SET
 			user_id          = EXCLUDED.user_id,
 			domain           = EXCLUDED.domain,
 			last_seen        = EXCLUDED.last_seen,
 			ssid             = EXCLUDED.ssid,
-			interface_name   = EXCLUDED.interface_name,
 			signal_percent   = EXCLUDED.signal_percent,
 			avg_ping_ms      = EXCLUDED.avg_ping_ms,
-			experience_score = EXCLUDED.experience_score
+			experience_score = EXCLUDED.experience_score,
+			bssid            = EXCLUDED.bssid,
+			connection_state = EXCLUDED.connection_state,
+			radio_type       = EXCLUDED.radio_type,
+			channel          = EXCLUDED.channel,
+			band             = EXCLUDED.band,
+			rx_rate_mbps     = EXCLUDED.rx_rate_mbps,
+			tx_rate_mbps     = EXCLUDED.tx_rate_mbps,
+			authentication   = EXCLUDED.authentication,
+			cipher           = EXCLUDED.cipher,
+			rssi_dbm         = EXCLUDED.rssi_dbm,
+			jitter_ms        = EXCLUDED.jitter_ms,
+			packet_loss_pct  = EXCLUDED.packet_loss_pct,
+			down_mbps        = EXCLUDED.down_mbps,
+			up_mbps          = EXCLUDED.up_mbps,
+			dns_latency_ms   = EXCLUDED.dns_latency_ms,
+			dns_queries      = EXCLUDED.dns_queries,
+			dns_failures     = EXCLUDED.dns_failures,
+			dns_nxdomain     = EXCLUDED.dns_nxdomain,
+			dns_servfail     = EXCLUDED.dns_servfail,
+			dns_timeouts     = EXCLUDED.dns_timeouts,
+			connectivity     = EXCLUDED.connectivity,
+			portal_url       = EXCLUDED.portal_url,
+			fault_location   = EXCLUDED.fault_location,
+			gateway_ping_ms  = EXCLUDED.gateway_ping_ms,
+			r_factor         = EXCLUDED.r_factor,
+			mos              = EXCLUDED.mos,
+			codec            = EXCLUDED.codec,
+			bufferbloat_idle_ms   = EXCLUDED.bufferbloat_idle_ms,
+			bufferbloat_loaded_ms = EXCLUDED.bufferbloat_loaded_ms,
+			bufferbloat_delta_ms  = EXCLUDED.bufferbloat_delta_ms,
+			bufferbloat_grade     = EXCLUDED.bufferbloat_grade,
+			score_profile         = EXCLUDED.score_profile,
+			score_factors         = EXCLUDED.score_factors,
+			predicted_metric      = EXCLUDED.predicted_metric,
+			predicted_in_s        = EXCLUDED.predicted_in_s,
+			predicted_slope       = EXCLUDED.predicted_slope,
+			governor_stay_put     = EXCLUDED.governor_stay_put,
+			governor_reason       = EXCLUDED.governor_reason,
+			governor_switches     = EXCLUDED.governor_switches,
+			governor_breakers     = EXCLUDED.governor_breakers
 	`,
 		m.DeviceId, m.UserId, m.Domain, ts,
 		m.Ssid, m.InterfaceName,
 		m.SignalPercent, m.AvgPingMs, m.ExperienceScore,
+		m.Bssid, m.ConnectionState, m.RadioType, m.Channel, m.Band,
+		m.RxRateMbps, m.TxRateMbps, m.Authentication, m.Cipher, m.RssiDbm,
+		m.JitterMs, m.PacketLossPct, m.DownMbps, m.UpMbps,
+		m.DnsLatencyMs, m.DnsQueries, m.DnsFailures, m.DnsNxdomain, m.DnsServfail, m.DnsTimeouts,
+		m.Connectivity, m.PortalUrl, m.FaultLocation, m.GatewayPingMs,
+		m.RFactor, m.Mos, m.Codec,
+		m.BufferbloatIdleMs, m.BufferbloatLoadedMs, m.BufferbloatDeltaMs, m.BufferbloatGrade,
+		m.ScoreProfile, factors,
+		pred.GetMetric(), pred.GetSecondsToThreshold(), pred.GetSlopePerMin(),
+		gov.GetStayPut(), gov.GetReason(), gov.GetSwitchesLastHour(), breakers,
 	)
 	if err != nil {
 		return err
 	}
 
+	for _, e := range m.Endpoints {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO endpoint_results (
+				device_id, ts, name, url, status,
+				dns_ms, connect_ms, tls_ms, ttfb_ms, total_ms,
+				ok, critical, error
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		`,
+			m.DeviceId, ts, e.Name, e.Url, e.Status,
+			e.DnsMs, e.ConnectMs, e.TlsMs, e.TtfbMs, e.TotalMs,
+			e.Ok, e.Critical, e.Error,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ev := m.Degradation; ev != nil {
+		var id int64
+		err = tx.QueryRow(ctx, `
+			INSERT INTO degradation_events (
+				device_id, ts, ssid, bssid, reasons, fault_location,
+				trace_target, trace_method, trace_reached
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+			RETURNING id
+		`,
+			m.DeviceId, time.Unix(ev.StartedUnix, 0), m.Ssid, m.Bssid,
+			strings.Join(ev.Reasons, ","), ev.FaultLocation,
+			ev.TraceTarget, ev.TraceMethod, ev.TraceReached,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		for _, h := range ev.Hops {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO trace_hops (
+					event_id, ttl, addr, sent, received,
+					loss_pct, avg_ms, best_ms, worst_ms
+				) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+			`,
+				id, h.Ttl, h.Addr, h.Sent, h.Received,
+				h.LossPct, h.AvgMs, h.BestMs, h.WorstMs,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	return tx.Commit(ctx)
 }
 
 // DeviceStatusRow maps to JSON for /status.
 type DeviceStatusRow struct {
-	DeviceID        string    `json:"device_id"`
-	UserID          string    `json:"user_id"`
-	Domain          string    `json:"domain"`
-	LastSeen        time.Time `json:"last_seen"`
-	SSID            string    `json:"ssid"`
-	InterfaceName   string    `json:"interface_name"`
-	SignalPercent   int32     `json:"signal_percent"`
-	AvgPingMs       int32     `json:"avg_ping_ms"`
-	ExperienceScore int32     `json:"experience_score"`
+	DeviceID        string           `json:"device_id"`
+	UserID          string           `json:"user_id"`
+	Domain          string           `json:"domain"`
+	LastSeen        time.Time        `json:"last_seen"`
+	SSID            string           `json:"ssid"`
+	InterfaceName   string           `json:"interface_name"`
+	SignalPercent   int32            `json:"signal_percent"`
+	AvgPingMs       int32            `json:"avg_ping_ms"`
+	ExperienceScore int32            `json:"experience_score"`
+	BSSID           string           `json:"bssid"`
+	ConnectionState string           `json:"connection_state"`
+	RadioType       string           `json:"radio_type"`
+	Channel         int32            `json:"channel"`
+	Band            string           `json:"band"`
+	RxRateMbps      float32          `json:"rx_rate_mbps"`
+	TxRateMbps      float32          `json:"tx_rate_mbps"`
+	Authentication  string           `json:"authentication"`
+	Cipher          string           `json:"cipher"`
+	RSSIDbm         int32            `json:"rssi_dbm"`
+	JitterMs        int32            `json:"jitter_ms"`
+	PacketLossPct   float32          `json:"packet_loss_pct"`
+	DownMbps        float32          `json:"down_mbps"`
+	UpMbps          float32          `json:"up_mbps"`
+	DNSLatencyMs    float32          `json:"dns_latency_ms"`
+	DNSQueries      int32            `json:"dns_queries"`
+	DNSFailures     int32            `json:"dns_failures"`
+	DNSNXDomain     int32            `json:"dns_nxdomain"`
+	DNSServFail     int32            `json:"dns_servfail"`
+	DNSTimeouts     int32            `json:"dns_timeouts"`
+	Connectivity    string           `json:"connectivity"`
+	PortalURL       string           `json:"portal_url"`
+	FaultLocation   string           `json:"fault_location"`
+	GatewayPingMs   float32          `json:"gateway_ping_ms"`
+	RFactor         float32          `json:"r_factor"`
+	MOS             float32          `json:"mos"`
+	Codec           string           `json:"codec"`
+	BloatIdleMs     float32          `json:"bufferbloat_idle_ms"`
+	BloatLoadedMs   float32          `json:"bufferbloat_loaded_ms"`
+	BloatDeltaMs    float32          `json:"bufferbloat_delta_ms"`
+	BloatGrade      string           `json:"bufferbloat_grade"`
+	ScoreProfile    string           `json:"score_profile"`
+	ScoreFactors    []ScoreFactorRow `json:"score_factors"`
+	PredictedMetric string           `json:"predicted_metric"`
+	PredictedInS    float32          `json:"predicted_in_s"`
+	PredictedSlope  float32          `json:"predicted_slope_per_min"`
+	StayPut         bool             `json:"governor_stay_put"`
+	StayPutReason   string           `json:"governor_reason"`
+	HourlySwitches  int32            `json:"governor_switches_last_hour"`
+	OpenBreakers    []string         `json:"governor_open_breakers"`
 }
 
-// GetAllDeviceStatus returns one row per device.
+// ScoreFactorRow is one input's part in a device's experience score.
+type ScoreFactorRow struct {
+	Name   string  `json:"name"`
+	Value  float32 `json:"value"`
+	Score  float32 `json:"score"`
+	Weight float32 `json:"weight"`
+}
+
+// GetAllDeviceStatus returns one row per device and adapter.
 func (s *Store) GetAllDeviceStatus(ctx context.Context) ([]DeviceStatusRow, error) {
 	rows, err := s.Pool.Query(ctx, `
 		SELECT device_id, user_id, domain, last_seen,
-		       ssid, interface_name, signal_percent, avg_ping_ms, experience_score
+		       ssid, interface_name, signal_percent, avg_ping_ms, experience_score,
+		       bssid, connection_state, radio_type, channel, band,
+		       rx_rate_mbps, tx_rate_mbps, authentication, cipher, rssi_dbm,
+		       jitter_ms, packet_loss_pct, down_mbps, up_mbps,
+		       dns_latency_ms, dns_queries, dns_failures, dns_nxdomain, dns_servfail, dns_timeouts,
+		       connectivity, portal_url, fault_location, gateway_ping_ms,
+		       r_factor, mos, codec,
+		       bufferbloat_idle_ms, bufferbloat_loaded_ms, bufferbloat_delta_ms, bufferbloat_grade,
+		       score_profile, score_factors,
+		       predicted_metric, predicted_in_s, predicted_slope,
+		       governor_stay_put, governor_reason, governor_switches, governor_breakers
 		FROM device_status
 		ORDER BY last_seen DESC
 	`)
@@ -111,6 +312,16 @@ func (s *Store) GetAllDeviceStatus(ctx context.Context) ([]DeviceStatusRow, erro
 		if err := rows.Scan(
 			&r.DeviceID, &r.UserID, &r.Domain, &r.LastSeen,
 			&r.SSID, &r.InterfaceName, &r.SignalPercent, &r.AvgPingMs, &r.ExperienceScore,
+			&r.BSSID, &r.ConnectionState, &r.RadioType, &r.Channel, &r.Band,
+			&r.RxRateMbps, &r.TxRateMbps, &r.Authentication, &r.Cipher, &r.RSSIDbm,
+			&r.JitterMs, &r.PacketLossPct, &r.DownMbps, &r.UpMbps,
+			&r.DNSLatencyMs, &r.DNSQueries, &r.DNSFailures, &r.DNSNXDomain, &r.DNSServFail, &r.DNSTimeouts,
+			&r.Connectivity, &r.PortalURL, &r.FaultLocation, &r.GatewayPingMs,
+			&r.RFactor, &r.MOS, &r.Codec,
+			&r.BloatIdleMs, &r.BloatLoadedMs, &r.BloatDeltaMs, &r.BloatGrade,
+			&r.ScoreProfile, &r.ScoreFactors,
+			&r.PredictedMetric, &r.PredictedInS, &r.PredictedSlope,
+			&r.StayPut, &r.StayPutReason, &r.HourlySwitches, &r.OpenBreakers,
 		); err != nil {
 			return nil, err
 		}
@@ -122,10 +333,104 @@ func (s *Store) GetAllDeviceStatus(ctx context.Context) ([]DeviceStatusRow, erro
 // DeleteOldMetrics deletes raw metrics older than the given retention.
 func (s *Store) DeleteOldMetrics(ctx context.Context, olderThan time.Duration) error {
 	cutoff := time.Now().Add(-olderThan)
-	_, err := s.Pool.Exec(ctx, `DELETE FROM metrics_raw WHERE ts < $1`, cutoff)
+	if _, err := s.Pool.Exec(ctx, `DELETE FROM metrics_raw WHERE ts < $1`, cutoff); err != nil {
+		return err
+	}
+	if _, err := s.Pool.Exec(ctx, `DELETE FROM endpoint_results WHERE ts < $1`, cutoff); err != nil {
+		return err
+	}
+	// trace_hops go with their event (ON DELETE CASCADE).
+	_, err := s.Pool.Exec(ctx, `DELETE FROM degradation_events WHERE ts < $1`, cutoff)
 	return err
 }
 
+// DegradationEventRow is one degradation event with its traceroute.
+type DegradationEventRow struct {
+	ID            int64         `json:"id"`
+	DeviceID      string        `json:"device_id"`
+	Start         time.Time     `json:"start"`
+	SSID          string        `json:"ssid"`
+	BSSID         string        `json:"bssid"`
+	Reasons       []string      `json:"reasons"`
+	FaultLocation string        `json:"fault_location"`
+	TraceTarget   string        `json:"trace_target"`
+	TraceMethod   string        `json:"trace_method"`
+	TraceReached  bool          `json:"trace_reached"`
+	Hops          []TraceHopRow `json:"hops"`
+}
+
+// TraceHopRow is one TTL of an event's traceroute.
+type TraceHopRow struct {
+	TTL      int32   `json:"ttl"`
+	Addr     string  `json:"addr"`
+	Sent     int32   `json:"sent"`
+	Received int32   `json:"received"`
+	LossPct  float32 `json:"loss_pct"`
+	AvgMs    float32 `json:"avg_ms"`
+	BestMs   float32 `json:"best_ms"`
+	WorstMs  float32 `json:"worst_ms"`
+}
+
+// GetDegradationEvents returns a device's latest events, newest first.
+func (s *Store) GetDegradationEvents(ctx context.Context, deviceID string, limit int) ([]DegradationEventRow, error) {
+	rows, err := s.Pool.Query(ctx, `
+		SELECT id, device_id, ts, ssid, bssid, reasons, fault_location,
+		       trace_target, trace_method, trace_reached
+		FROM degradation_events
+		WHERE device_id = $1
+		ORDER BY ts DESC
+		LIMIT $2
+	`, deviceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	var events []DegradationEventRow
+	byID := map[int64]int{}
+	var ids []int64
+	for rows.Next() {
+		var e DegradationEventRow
+		var reasons string
+		if err := rows.Scan(
+			&e.ID, &e.DeviceID, &e.Start, &e.SSID, &e.BSSID, &reasons, &e.FaultLocation,
+			&e.TraceTarget, &e.TraceMethod, &e.TraceReached,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if reasons != "" {
+			e.Reasons = strings.Split(reasons, ",")
+		}
+		byID[e.ID] = len(events)
+		ids = append(ids, e.ID)
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return events, err
+	}
+
+	hops, err := s.Pool.Query(ctx, `
+		SELECT event_id, ttl, addr, sent, received, loss_pct, avg_ms, best_ms, worst_ms
+		FROM trace_hops
+		WHERE event_id = ANY($1)
+		ORDER BY event_id, ttl
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer hops.Close()
+	for hops.Next() {
+		var id int64
+		var h TraceHopRow
+		if err := hops.Scan(&id, &h.TTL, &h.Addr, &h.Sent, &h.Received, &h.LossPct, &h.AvgMs, &h.BestMs, &h.WorstMs); err != nil {
+			return nil, err
+		}
+		e := &events[byID[id]]
+		e.Hops = append(e.Hops, h)
+	}
+	return events, hops.Err()
+}
+
 func (s *Store) GetDevicesByLink(
 	ctx context.Context,
 	ssid string, //network identifier
@@ -141,7 +446,10 @@ func (s *Store) GetDevicesByLink(
 			signal_percent,
 			avg_ping_ms,
 			experience_score,
-			last_seen
+			last_seen,
+			bssid,
+			band,
+			channel
 		FROM device_status
 		WHERE ssid = $1
 		ORDER BY last_seen DESC
@@ -164,6 +472,9 @@ func (s *Store) GetDevicesByLink(
 			&d.AvgPingMs,
 			&d.ExperienceScore,
 			&d.LastSeen,
+			&d.BSSID,
+			&d.Band,
+			&d.Channel,
 		); err != nil {
 			return nil, err
 		}
@@ -1,18 +1,59 @@
 -- Current status 
-CREATE TABLE device_status (
-    device_id        text PRIMARY KEY,
+CREATE TABLE IF NOT EXISTS device_status (
+    device_id        text NOT NULL,
     user_id          text,
     domain           text,
     last_seen        timestamptz NOT NULL,
     ssid             text,
-    interface_name   text,
+    interface_name   text NOT NULL DEFAULT '',
     signal_percent   int,
     avg_ping_ms      int,
-    experience_score int
+    experience_score int,
+    bssid            text NOT NULL DEFAULT '',
+    connection_state text NOT NULL DEFAULT '',
+    radio_type       text NOT NULL DEFAULT '',
+    channel          int  NOT NULL DEFAULT 0,
+    band             text NOT NULL DEFAULT '',
+    rx_rate_mbps     real NOT NULL DEFAULT 0,
+    tx_rate_mbps     real NOT NULL DEFAULT 0,
+    authentication   text NOT NULL DEFAULT '',
+    cipher           text NOT NULL DEFAULT '',
+    rssi_dbm         int  NOT NULL DEFAULT 0,
+    jitter_ms        int  NOT NULL DEFAULT 0,
+    packet_loss_pct  real NOT NULL DEFAULT 0,
+    down_mbps        real NOT NULL DEFAULT 0,
+    up_mbps          real NOT NULL DEFAULT 0,
+    dns_latency_ms   real NOT NULL DEFAULT 0,
+    dns_queries      int  NOT NULL DEFAULT 0,
+    dns_failures     int  NOT NULL DEFAULT 0,
+    dns_nxdomain     int  NOT NULL DEFAULT 0,
+    dns_servfail     int  NOT NULL DEFAULT 0,
+    dns_timeouts     int  NOT NULL DEFAULT 0,
+    connectivity     text NOT NULL DEFAULT '',
+    portal_url       text NOT NULL DEFAULT '',
+    fault_location   text NOT NULL DEFAULT '',
+    gateway_ping_ms  real NOT NULL DEFAULT 0,
+    r_factor         real NOT NULL DEFAULT 0,
+    mos              real NOT NULL DEFAULT 0,
+    codec            text NOT NULL DEFAULT '',
+    bufferbloat_idle_ms   real NOT NULL DEFAULT 0,
+    bufferbloat_loaded_ms real NOT NULL DEFAULT 0,
+    bufferbloat_delta_ms  real NOT NULL DEFAULT 0,
+    bufferbloat_grade     text NOT NULL DEFAULT '',
+    score_profile         text NOT NULL DEFAULT '',
+    score_factors         jsonb NOT NULL DEFAULT '[]',
+    predicted_metric      text NOT NULL DEFAULT '',
+    predicted_in_s        real NOT NULL DEFAULT 0,
+    predicted_slope       real NOT NULL DEFAULT 0,
+    governor_stay_put     boolean NOT NULL DEFAULT false,
+    governor_reason       text NOT NULL DEFAULT '',
+    governor_switches     integer NOT NULL DEFAULT 0,
+    governor_breakers     jsonb NOT NULL DEFAULT '[]',
+    PRIMARY KEY (device_id, interface_name)
 );
 
 -- Raw time-series metrics
-CREATE TABLE metrics_raw (
+CREATE TABLE IF NOT EXISTS metrics_raw (
     id               bigserial PRIMARY KEY,
     device_id        text NOT NULL,
     user_id          text,
@@ -22,9 +63,201 @@ CREATE TABLE metrics_raw (
     interface_name   text,
     signal_percent   int,
     avg_ping_ms      int,
-    experience_score int
+    experience_score int,
+    bssid            text NOT NULL DEFAULT '',
+    connection_state text NOT NULL DEFAULT '',
+    radio_type       text NOT NULL DEFAULT '',
+    channel          int  NOT NULL DEFAULT 0,
+    band             text NOT NULL DEFAULT '',
+    rx_rate_mbps     real NOT NULL DEFAULT 0,
+    tx_rate_mbps     real NOT NULL DEFAULT 0,
+    authentication   text NOT NULL DEFAULT '',
+    cipher           text NOT NULL DEFAULT '',
+    rssi_dbm         int  NOT NULL DEFAULT 0,
+    jitter_ms        int  NOT NULL DEFAULT 0,
+    packet_loss_pct  real NOT NULL DEFAULT 0,
+    down_mbps        real NOT NULL DEFAULT 0,
+    up_mbps          real NOT NULL DEFAULT 0,
+    dns_latency_ms   real NOT NULL DEFAULT 0,
+    dns_queries      int  NOT NULL DEFAULT 0,
+    dns_failures     int  NOT NULL DEFAULT 0,
+    dns_nxdomain     int  NOT NULL DEFAULT 0,
+    dns_servfail     int  NOT NULL DEFAULT 0,
+    dns_timeouts     int  NOT NULL DEFAULT 0,
+    connectivity     text NOT NULL DEFAULT '',
+    portal_url       text NOT NULL DEFAULT '',
+    fault_location   text NOT NULL DEFAULT '',
+    gateway_ping_ms  real NOT NULL DEFAULT 0,
+    r_factor         real NOT NULL DEFAULT 0,
+    mos              real NOT NULL DEFAULT 0,
+    codec            text NOT NULL DEFAULT '',
+    bufferbloat_idle_ms   real NOT NULL DEFAULT 0,
+    bufferbloat_loaded_ms real NOT NULL DEFAULT 0,
+    bufferbloat_delta_ms  real NOT NULL DEFAULT 0,
+    bufferbloat_grade     text NOT NULL DEFAULT '',
+    score_profile         text NOT NULL DEFAULT '',
+    score_factors         jsonb NOT NULL DEFAULT '[]',
+    predicted_metric      text NOT NULL DEFAULT '',
+    predicted_in_s        real NOT NULL DEFAULT 0,
+    predicted_slope       real NOT NULL DEFAULT 0,
+    governor_stay_put     boolean NOT NULL DEFAULT false,
+    governor_reason       text NOT NULL DEFAULT '',
+    governor_switches     integer NOT NULL DEFAULT 0,
+    governor_breakers     jsonb NOT NULL DEFAULT '[]'
+);
+
+-- Application endpoint checks reported with each metric
+CREATE TABLE IF NOT EXISTS endpoint_results (
+    id               bigserial PRIMARY KEY,
+    device_id        text NOT NULL,
+    ts               timestamptz NOT NULL,
+    name             text NOT NULL,
+    url              text NOT NULL,
+    status           int  NOT NULL DEFAULT 0,
+    dns_ms           real NOT NULL DEFAULT 0,
+    connect_ms       real NOT NULL DEFAULT 0,
+    tls_ms           real NOT NULL DEFAULT 0,
+    ttfb_ms          real NOT NULL DEFAULT 0,
+    total_ms         real NOT NULL DEFAULT 0,
+    ok               boolean NOT NULL,
+    critical         boolean NOT NULL DEFAULT false,
+    error            text NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS degradation_events (
+    id               bigserial PRIMARY KEY,
+    device_id        text NOT NULL,
+    ts               timestamptz NOT NULL,
+    ssid             text NOT NULL DEFAULT '',
+    bssid            text NOT NULL DEFAULT '',
+    reasons          text NOT NULL DEFAULT '', -- comma-separated
+    fault_location   text NOT NULL DEFAULT '',
+    trace_target     text NOT NULL DEFAULT '',
+    trace_method     text NOT NULL DEFAULT '',
+    trace_reached    boolean NOT NULL DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS trace_hops (
+    event_id         bigint NOT NULL REFERENCES degradation_events(id) ON DELETE CASCADE,
+    ttl              int  NOT NULL,
+    addr             text NOT NULL DEFAULT '',
+    sent             int  NOT NULL DEFAULT 0,
+    received         int  NOT NULL DEFAULT 0,
+    loss_pct         real NOT NULL DEFAULT 0,
+    avg_ms           real NOT NULL DEFAULT 0,
+    best_ms          real NOT NULL DEFAULT 0,
+    worst_ms         real NOT NULL DEFAULT 0,
+    PRIMARY KEY (event_id, ttl)
 );
 
 -- Indexes
-CREATE INDEX ON metrics_raw(device_id, ts DESC);
-CREATE INDEX ON metrics_raw(domain, ts DESC);
+CREATE INDEX IF NOT EXISTS metrics_raw_device_id_ts_idx ON metrics_raw(device_id, ts DESC);
+CREATE INDEX IF NOT EXISTS metrics_raw_domain_ts_idx ON metrics_raw(domain, ts DESC);
+CREATE INDEX IF NOT EXISTS endpoint_results_device_id_ts_idx ON endpoint_results(device_id, ts DESC);
+CREATE INDEX IF NOT EXISTS degradation_events_device_id_ts_idx ON degradation_events(device_id, ts DESC);
+
+-- Columns added after the first release (safe to re-run on existing databases)
+ALTER TABLE device_status
+    ADD COLUMN IF NOT EXISTS bssid            text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS connection_state text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS radio_type       text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS channel          int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS band             text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS rx_rate_mbps     real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tx_rate_mbps     real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS authentication   text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS cipher           text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS rssi_dbm         int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS jitter_ms        int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS packet_loss_pct  real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS down_mbps        real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS up_mbps          real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_latency_ms   real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_queries      int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_failures     int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_nxdomain     int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_servfail     int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_timeouts     int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS connectivity     text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS portal_url       text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS fault_location   text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS gateway_ping_ms  real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS r_factor         real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS mos              real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS codec            text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS bufferbloat_idle_ms   real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS bufferbloat_loaded_ms real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS bufferbloat_delta_ms  real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS bufferbloat_grade     text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS score_profile         text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS score_factors         jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS predicted_metric      text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS predicted_in_s        real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS predicted_slope       real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS governor_stay_put     boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS governor_reason       text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS governor_switches     integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS governor_breakers     jsonb NOT NULL DEFAULT '[]';
+
+ALTER TABLE metrics_raw
+    ADD COLUMN IF NOT EXISTS bssid            text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS connection_state text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS radio_type       text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS channel          int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS band             text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS rx_rate_mbps     real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tx_rate_mbps     real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS authentication   text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS cipher           text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS rssi_dbm         int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS jitter_ms        int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS packet_loss_pct  real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS down_mbps        real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS up_mbps          real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_latency_ms   real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_queries      int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_failures     int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_nxdomain     int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_servfail     int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_timeouts     int  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS connectivity     text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS portal_url       text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS fault_location   text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS gateway_ping_ms  real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS r_factor         real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS mos              real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS codec            text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS bufferbloat_idle_ms   real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS bufferbloat_loaded_ms real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS bufferbloat_delta_ms  real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS bufferbloat_grade     text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS score_profile         text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS score_factors         jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS predicted_metric      text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS predicted_in_s        real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS predicted_slope       real NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS governor_stay_put     boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS governor_reason       text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS governor_switches     integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS governor_breakers     jsonb NOT NULL DEFAULT '[]';
+
+-- device_status used to be keyed on device_id alone, which agents filled
+-- with the SSID; it is now one row per device and adapter. The old rows
+-- are dropped rather than guessed at, and the next metric from each agent
+-- fills the table again.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_index i
+        JOIN pg_class c ON c.oid = i.indrelid
+        WHERE c.relname = 'device_status' AND i.indisprimary AND i.indnatts = 1
+    ) THEN
+        TRUNCATE device_status;
+        ALTER TABLE device_status DROP CONSTRAINT device_status_pkey;
+        ALTER TABLE device_status
+            ALTER COLUMN interface_name SET DEFAULT '',
+            ALTER COLUMN interface_name SET NOT NULL,
+            ADD PRIMARY KEY (device_id, interface_name);
+    END IF;
+END
+$$;
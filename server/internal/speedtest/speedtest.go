@@ -0,0 +1,81 @@
+// Package speedtest serves the payloads agents use to measure throughput.
+package speedtest
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxBytes caps a single download or upload so one agent cannot tie up
+// the server's uplink.
+const MaxBytes = 100 << 20
+
+// chunk is random so compression anywhere on the path cannot inflate results.
+var chunk = func() []byte {
+	b := make([]byte, 64<<10)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(b)
+	return b
+}()
+
+// Download streams ?bytes=N (default 10 MB, at most MaxBytes) of payload.
+func Download(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n := int64(10 << 20)
+	if v := r.URL.Query().Get("bytes"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "bytes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		n = parsed
+	}
+	if n > MaxBytes {
+		n = MaxBytes
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(n, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	for n > 0 {
+		size := int64(len(chunk))
+		if n < size {
+			size = n
+		}
+		if _, err := w.Write(chunk[:size]); err != nil {
+			return // client stopped early; that is expected
+		}
+		n -= size
+	}
+}
+
+// Upload discards the request body (at most MaxBytes) and reports how much
+// arrived and how long it took to read.
+func Upload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, http.MaxBytesReader(w, r.Body, MaxBytes))
+	elapsed := time.Since(start)
+	if err != nil && n == 0 {
+		http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]int64{
+		"bytes":       n,
+		"duration_ms": elapsed.Milliseconds(),
+	})
+}
@@ -17,7 +17,7 @@ type DeviceStatus struct {
 
 type State struct {
 	mu      sync.RWMutex
-	byDevID map[string]*DeviceStatus
+	byDevID map[string]*DeviceStatus // by device ID and interface name
 }
 
 func NewState() *State {
@@ -29,7 +29,7 @@ func NewState() *State {
 func (s *State) Update(m *agentpb.NetworkMetric) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.byDevID[m.DeviceId] = &DeviceStatus{
+	s.byDevID[m.DeviceId+"/"+m.InterfaceName] = &DeviceStatus{
 		DeviceID: m.DeviceId,
 		UserID:   m.UserId,
 		Domain:   m.Domain,